@@ -0,0 +1,138 @@
+package api
+
+import (
+	"car-rental-lite/src/models"
+	"net/http"
+	"time"
+)
+
+type bookingRequest struct {
+	UserID    string    `json:"user_id"`
+	CarID     string    `json:"car_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+func (s *Server) quote(w http.ResponseWriter, r *http.Request) error {
+	var req bookingRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	var v validator
+	v.check(req.UserID == "", "user_id", "is not used for quotes")
+	v.check(req.CarID != "", "car_id", "is required")
+	checkWindow(&v, req.StartTime, req.EndTime)
+	if err := v.err(); err != nil {
+		return err
+	}
+	quote, err := s.cfg.BookingService.QuotePrice(r.Context(), req.CarID, req.StartTime, req.EndTime)
+	if err != nil {
+		return mustExist(err, "car_id")
+	}
+	writeJSON(w, http.StatusOK, quote)
+	return nil
+}
+
+func (s *Server) createBooking(w http.ResponseWriter, r *http.Request) error {
+	var req bookingRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	var v validator
+	v.check(req.UserID != "", "user_id", "is required")
+	v.check(req.CarID != "", "car_id", "is required")
+	checkWindow(&v, req.StartTime, req.EndTime)
+	v.check(req.StartTime.IsZero() || req.StartTime.After(s.now()), "start_time", "must be in the future")
+	if err := v.err(); err != nil {
+		return err
+	}
+	if _, err := s.cfg.Users.GetUser(r.Context(), req.UserID); err != nil {
+		return mustExist(err, "user_id")
+	}
+	if _, err := s.cfg.Cars.GetCar(r.Context(), req.CarID); err != nil {
+		return mustExist(err, "car_id")
+	}
+	booking, err := s.cfg.BookingService.CreateBooking(r.Context(), req.UserID, req.CarID, req.StartTime, req.EndTime)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, booking)
+	return nil
+}
+
+func (s *Server) getBooking(w http.ResponseWriter, r *http.Request) error {
+	booking, err := s.cfg.Bookings.GetBooking(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, booking)
+	return nil
+}
+
+func (s *Server) confirmBooking(w http.ResponseWriter, r *http.Request) error {
+	booking, err := s.cfg.BookingService.ConfirmBooking(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, booking)
+	return nil
+}
+
+type modifyRequest struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+func (s *Server) modifyBooking(w http.ResponseWriter, r *http.Request) error {
+	var req modifyRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	var v validator
+	checkWindow(&v, req.StartTime, req.EndTime)
+	if err := v.err(); err != nil {
+		return err
+	}
+	booking, err := s.cfg.BookingService.ModifyBooking(r.Context(), r.PathValue("id"), req.StartTime, req.EndTime)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, booking)
+	return nil
+}
+
+type cancelRequest struct {
+	Actor   models.CancellationActor `json:"actor"`
+	ActorID string                   `json:"actor_id"`
+}
+
+func (s *Server) cancelBooking(w http.ResponseWriter, r *http.Request) error {
+	var req cancelRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	var v validator
+	v.check(req.Actor == models.CancelledByRenter || req.Actor == models.CancelledByHost, "actor", "must be RENTER or HOST")
+	v.check(req.ActorID != "", "actor_id", "is required")
+	if err := v.err(); err != nil {
+		return err
+	}
+	refund, err := s.cfg.BookingService.CancelBooking(r.Context(), r.PathValue("id"), req.Actor, req.ActorID)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, refund)
+	return nil
+}
+
+func (s *Server) listRefunds(w http.ResponseWriter, r *http.Request) error {
+	if _, err := s.cfg.Bookings.GetBooking(r.Context(), r.PathValue("id")); err != nil {
+		return err
+	}
+	refunds, err := s.cfg.Bookings.GetRefundsForBooking(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, list(refunds))
+	return nil
+}
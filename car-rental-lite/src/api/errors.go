@@ -0,0 +1,165 @@
+package api
+
+import (
+	"car-rental-lite/src/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error codes returned in the "code" field of error responses.
+const (
+	CodeBadRequest  = "bad_request"
+	CodeNotFound    = "not_found"
+	CodeConflict    = "conflict"
+	CodeValidation  = "validation_failed"
+	CodeIneligible  = "ineligible"
+	CodeTimeout     = "timeout"
+	CodeCancelled   = "cancelled"
+	CodeInternal    = "internal"
+	maxRequestBytes = 1 << 20
+)
+
+// statusClientClosedRequest is nginx's status for a client that went away
+// before the response was ready.
+const statusClientClosedRequest = 499
+
+// ErrorBody is the JSON shape of every error response:
+//
+//	{"error": {"code": "conflict", "message": "car is already booked for these dates"}}
+type ErrorBody struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+	// BookingID names the booking in the way of a conflict, if any.
+	BookingID string `json:"booking_id,omitempty"`
+	// Reasons lists every eligibility check an ineligible renter failed.
+	Reasons []models.EligibilityReason `json:"reasons,omitempty"`
+}
+
+// FieldError names a request field that failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects the invalid fields of a request.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + " " + f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// validator accumulates field errors so one response reports all of them.
+type validator struct {
+	fields []FieldError
+}
+
+func (v *validator) check(ok bool, field, message string) {
+	if !ok {
+		v.fields = append(v.fields, FieldError{Field: field, Message: message})
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v.fields}
+}
+
+// badRequest is a request that couldn't be parsed at all.
+type badRequest struct {
+	msg string
+}
+
+func (e *badRequest) Error() string { return e.msg }
+
+// statusFor maps service and repository errors to an HTTP status and code.
+func statusFor(err error) (int, string) {
+	var verr *ValidationError
+	var bad *badRequest
+	switch {
+	case errors.As(err, &bad):
+		return http.StatusBadRequest, CodeBadRequest
+	case errors.As(err, &verr):
+		return http.StatusUnprocessableEntity, CodeValidation
+	case errors.Is(err, models.ErrValidation):
+		return http.StatusUnprocessableEntity, CodeValidation
+	case errors.Is(err, models.ErrNotFound):
+		return http.StatusNotFound, CodeNotFound
+	case errors.Is(err, models.ErrIneligible):
+		return http.StatusForbidden, CodeIneligible
+	case errors.Is(err, models.ErrConflict):
+		return http.StatusConflict, CodeConflict
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, CodeTimeout
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest, CodeCancelled
+	}
+	return http.StatusInternalServerError, CodeInternal
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := statusFor(err)
+	detail := ErrorDetail{Code: code, Message: err.Error()}
+	var verr *ValidationError
+	var field *models.ValidationError
+	var conflict *models.ConflictError
+	var inel *models.IneligibleError
+	switch {
+	case errors.As(err, &verr):
+		detail.Message = "request is invalid"
+		detail.Fields = verr.Fields
+	case errors.As(err, &field):
+		detail.Fields = []FieldError{{Field: field.Field, Message: field.Message}}
+	case errors.As(err, &conflict):
+		detail.BookingID = conflict.BookingID
+	case errors.As(err, &inel):
+		detail.Reasons = inel.Reasons
+	}
+	if status == http.StatusInternalServerError {
+		fmt.Printf("[api] %s %s: %v\n", r.Method, r.URL.Path, err)
+		detail.Message = "internal server error"
+	}
+	writeJSON(w, status, ErrorBody{Error: detail})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Printf("[api] encoding response: %v\n", err)
+	}
+}
+
+// decodeJSON reads a single JSON object into dst, rejecting unknown fields
+// and bodies over maxRequestBytes.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		if errors.Is(err, io.EOF) {
+			return &badRequest{msg: "request body is required"}
+		}
+		return &badRequest{msg: "invalid JSON: " + err.Error()}
+	}
+	if dec.More() {
+		return &badRequest{msg: "request body must be a single JSON object"}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"car-rental-lite/src/services"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// importFleet takes a JSON FleetImport, or a CSV of one section: cars or
+// availability, named by ?section. With ?dry_run=true nothing is written.
+// Rows that fail validation don't fail the request; they're reported in the
+// result. If a row can't be written the import stops there, and the result
+// is still returned, with the error's status, so the caller sees which rows
+// were written.
+func (s *Server) importFleet(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	var v validator
+	dryRun := boolParam(&v, q, "dry_run")
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	section := q.Get("section")
+	if mediaType == "text/csv" {
+		v.check(section == "cars" || section == "availability", "section", "must be cars or availability for a CSV import")
+	} else {
+		v.check(section == "", "section", "is only for CSV imports")
+	}
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	var batch services.FleetImport
+	var err error
+	switch {
+	case section == "cars":
+		batch.Cars, err = services.ReadCarsCSV(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	case section == "availability":
+		batch.Availability, err = services.ReadAvailabilityCSV(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	default:
+		err = decodeJSON(w, r, &batch)
+	}
+	if err != nil {
+		return err
+	}
+
+	res, err := s.cfg.FleetService.Import(r.Context(), r.PathValue("id"), &batch, dryRun)
+	if err != nil && res == nil {
+		return err
+	}
+	writePartial(w, r, res, err)
+	return nil
+}
+
+// writePartial writes the result of a bulk operation. If err stopped it
+// partway, the result so far is written with the error's status instead of
+// an error body, so the caller sees what was already done.
+func writePartial(w http.ResponseWriter, r *http.Request, res any, err error) {
+	status := http.StatusOK
+	if err != nil {
+		status, _ = statusFor(err)
+		if status == http.StatusInternalServerError {
+			fmt.Printf("[api] %s %s: %v\n", r.Method, r.URL.Path, err)
+		}
+	}
+	writeJSON(w, status, res)
+}
+
+// exportFleet returns the host's fleet for the report period as JSON, or
+// one section of it (cars, availability or bookings) as CSV.
+func (s *Server) exportFleet(w http.ResponseWriter, r *http.Request) error {
+	from, to, csv, err := reportPeriod(r)
+	if err != nil {
+		return err
+	}
+	section := r.URL.Query().Get("section")
+	var v validator
+	if csv {
+		v.check(section == "cars" || section == "availability" || section == "bookings", "section", "must be cars, availability or bookings for a CSV export")
+	} else {
+		v.check(section == "", "section", "is only for CSV exports")
+	}
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	export, err := s.cfg.FleetService.Export(r.Context(), r.PathValue("id"), from, to)
+	if err != nil {
+		return err
+	}
+	if !csv {
+		writeJSON(w, http.StatusOK, export)
+		return nil
+	}
+	write := map[string]func(io.Writer) error{
+		"cars":         export.WriteCarsCSV,
+		"availability": export.WriteAvailabilityCSV,
+		"bookings":     export.WriteBookingsCSV,
+	}[section]
+	w.Header().Set("Content-Type", "text/csv")
+	return write(w)
+}
+
+type activationRequest struct {
+	CarIDs []string `json:"car_ids"`
+	Active *bool    `json:"active"`
+	// CancelBookings cancels upcoming bookings on the host's behalf so the
+	// cars can be deactivated; otherwise they're reported as conflicts.
+	CancelBookings bool `json:"cancel_bookings"`
+}
+
+func (s *Server) setFleetActive(w http.ResponseWriter, r *http.Request) error {
+	var req activationRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	var v validator
+	v.check(len(req.CarIDs) > 0, "car_ids", "is required")
+	v.check(req.Active != nil, "active", "is required")
+	v.check(!req.CancelBookings || req.Active == nil || !*req.Active, "cancel_bookings", "only applies when deactivating")
+	if err := v.err(); err != nil {
+		return err
+	}
+	results, err := s.cfg.FleetService.SetActive(r.Context(), r.PathValue("id"), req.CarIDs, *req.Active, req.CancelBookings)
+	if err != nil && results == nil {
+		return err
+	}
+	writePartial(w, r, results, err)
+	return nil
+}
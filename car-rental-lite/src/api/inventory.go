@@ -0,0 +1,523 @@
+package api
+
+import (
+	"car-rental-lite/src/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	validPolicies  = []models.CancellationPolicy{models.CancellationPolicyFlexible, models.CancellationPolicyModerate, models.CancellationPolicyStrict}
+	validCarTypes  = models.CarTypes
+	validAmenities = models.Amenities
+	validSorts     = []models.SearchSort{models.SearchSortDistance, models.SearchSortPrice, models.SearchSortNewest, models.SearchSortRating, models.SearchSortHostRating}
+)
+
+func oneOf[T comparable](v T, allowed []T) bool {
+	for _, a := range allowed {
+		if v == a {
+			return true
+		}
+	}
+	return false
+}
+
+// list keeps empty results as [] rather than null in responses.
+func list[T any](xs []T) []T {
+	if xs == nil {
+		return []T{}
+	}
+	return xs
+}
+
+func checkWindow(v *validator, start, end time.Time) {
+	v.check(!start.IsZero(), "start_time", "is required")
+	v.check(!end.IsZero(), "end_time", "is required")
+	if !start.IsZero() && !end.IsZero() {
+		v.check(start.Before(end), "end_time", "must be after start_time")
+	}
+}
+
+// mustExist turns a missing referenced entity into a validation error on
+// field, since the request, not the URL, named it.
+func mustExist(err error, field string) error {
+	if errors.Is(err, models.ErrNotFound) {
+		return &ValidationError{Fields: []FieldError{{Field: field, Message: "does not exist"}}}
+	}
+	return err
+}
+
+func (s *Server) createHost(w http.ResponseWriter, r *http.Request) error {
+	var host models.Host
+	if err := decodeJSON(w, r, &host); err != nil {
+		return err
+	}
+	var v validator
+	v.check(host.ID == "", "id", "is assigned by the server")
+	v.check(host.Rating == (models.Rating{}), "rating", "is computed from reviews")
+	v.check(strings.TrimSpace(host.Name) != "", "name", "is required")
+	v.check(host.Email == "" || strings.Contains(host.Email, "@"), "email", "is not a valid email address")
+	v.check(host.CancellationPolicy == "" || oneOf(host.CancellationPolicy, validPolicies), "cancellation_policy", "must be FLEXIBLE, MODERATE or STRICT")
+	if err := v.err(); err != nil {
+		return err
+	}
+	host.CreatedAt = s.now()
+	if err := s.cfg.Users.CreateHost(r.Context(), &host); err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, host)
+	return nil
+}
+
+func (s *Server) getHost(w http.ResponseWriter, r *http.Request) error {
+	host, err := s.cfg.Users.GetHost(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, host)
+	return nil
+}
+
+func (s *Server) listHostCars(w http.ResponseWriter, r *http.Request) error {
+	if _, err := s.cfg.Users.GetHost(r.Context(), r.PathValue("id")); err != nil {
+		return err
+	}
+	cars, err := s.cfg.Cars.GetCarsByHost(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, list(cars))
+	return nil
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) error {
+	var user models.User
+	if err := decodeJSON(w, r, &user); err != nil {
+		return err
+	}
+	var v validator
+	v.check(user.ID == "", "id", "is assigned by the server")
+	v.check(user.Rating == (models.Rating{}), "rating", "is computed from reviews")
+	v.check(strings.TrimSpace(user.Name) != "", "name", "is required")
+	v.check(strings.Contains(user.Email, "@"), "email", "is not a valid email address")
+	v.check(!user.Banned, "banned", "is set by administrators")
+	v.check(user.DateOfBirth.Before(s.now()), "date_of_birth", "must be in the past")
+	if err := v.err(); err != nil {
+		return err
+	}
+	user.CreatedAt = s.now()
+	if err := s.cfg.Users.CreateUser(r.Context(), &user); err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, user)
+	return nil
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) error {
+	user, err := s.cfg.Users.GetUser(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, user)
+	return nil
+}
+
+// carRequest is a Car whose is_active defaults to true when omitted.
+type carRequest struct {
+	models.Car
+	IsActive *bool `json:"is_active"`
+}
+
+func (s *Server) createCar(w http.ResponseWriter, r *http.Request) error {
+	var req carRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	car := req.Car
+	car.IsActive = req.IsActive == nil || *req.IsActive
+
+	var v validator
+	v.check(car.ID == "", "id", "is assigned by the server")
+	v.check(car.Rating == (models.Rating{}), "rating", "is computed from reviews")
+	v.check(car.HostID != "", "host_id", "is required")
+	v.check(car.Make != "", "make", "is required")
+	v.check(car.Model != "", "model", "is required")
+	v.check(car.Year >= 1900 && car.Year <= s.now().Year()+1, "year", "is out of range")
+	v.check(oneOf(car.Type, validCarTypes), "type", "must be one of SEDAN, SUV, COMPACT, LUXURY, VAN")
+	v.check(car.PricePerDay > 0, "price_per_day", "must be positive")
+	v.check(car.PricePerHour >= 0, "price_per_hour", "must not be negative")
+	v.check(car.Location.Latitude >= -90 && car.Location.Latitude <= 90, "location.latitude", "must be between -90 and 90")
+	v.check(car.Location.Longitude >= -180 && car.Location.Longitude <= 180, "location.longitude", "must be between -180 and 180")
+	_, err := car.Location.TimeLocation()
+	v.check(err == nil, "location.time_zone", "is not a known IANA time zone")
+	for i, a := range car.Amenities {
+		v.check(oneOf(a, validAmenities), fmt.Sprintf("amenities[%d]", i), "is not a known amenity")
+	}
+	if err := v.err(); err != nil {
+		return err
+	}
+	if _, err := s.cfg.Users.GetHost(r.Context(), car.HostID); err != nil {
+		return mustExist(err, "host_id")
+	}
+	if err := s.cfg.InventoryService.RegisterCar(r.Context(), &car); err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, car)
+	return nil
+}
+
+func (s *Server) getCar(w http.ResponseWriter, r *http.Request) error {
+	car, err := s.cfg.Cars.GetCar(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, car)
+	return nil
+}
+
+type windowRequest struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	// Force applies an availability change even if it strands a booking.
+	Force bool `json:"force"`
+}
+
+func (s *Server) decodeWindow(w http.ResponseWriter, r *http.Request) (*windowRequest, error) {
+	var req windowRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return nil, err
+	}
+	var v validator
+	checkWindow(&v, req.StartTime, req.EndTime)
+	return &req, v.err()
+}
+
+func (s *Server) listAvailability(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	var v validator
+	from := timeParam(&v, q, "from")
+	to := timeParam(&v, q, "to")
+	if err := v.err(); err != nil {
+		return err
+	}
+	if from.IsZero() {
+		from = time.Unix(0, 0)
+	}
+	if to.IsZero() {
+		to = from.AddDate(100, 0, 0)
+	}
+	if _, err := s.cfg.Cars.GetCar(r.Context(), r.PathValue("id")); err != nil {
+		return err
+	}
+	slots, err := s.cfg.Inventory.GetAvailability(r.Context(), r.PathValue("id"), from, to)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, list(slots))
+	return nil
+}
+
+func (s *Server) addAvailability(w http.ResponseWriter, r *http.Request) error {
+	req, err := s.decodeWindow(w, r)
+	if err != nil {
+		return err
+	}
+	if _, err := s.cfg.Cars.GetCar(r.Context(), r.PathValue("id")); err != nil {
+		return err
+	}
+	slot, err := s.cfg.InventoryService.AddAvailability(r.Context(), r.PathValue("id"), req.StartTime, req.EndTime)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, slot)
+	return nil
+}
+
+func (s *Server) blockDates(w http.ResponseWriter, r *http.Request) error {
+	req, err := s.decodeWindow(w, r)
+	if err != nil {
+		return err
+	}
+	if _, err := s.cfg.Cars.GetCar(r.Context(), r.PathValue("id")); err != nil {
+		return err
+	}
+	if err := s.cfg.InventoryService.BlockDates(r.Context(), r.PathValue("id"), req.StartTime, req.EndTime, req.Force); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) updateAvailability(w http.ResponseWriter, r *http.Request) error {
+	req, err := s.decodeWindow(w, r)
+	if err != nil {
+		return err
+	}
+	if err := s.cfg.InventoryService.UpdateAvailability(r.Context(), r.PathValue("id"), req.StartTime, req.EndTime, req.Force); err != nil {
+		return err
+	}
+	slot, err := s.cfg.Inventory.GetAvailabilitySlot(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, slot)
+	return nil
+}
+
+func (s *Server) removeAvailability(w http.ResponseWriter, r *http.Request) error {
+	var v validator
+	force := boolParam(&v, r.URL.Query(), "force")
+	if err := v.err(); err != nil {
+		return err
+	}
+	if err := s.cfg.InventoryService.RemoveAvailability(r.Context(), r.PathValue("id"), force); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// recurrenceBody is the wire form of a RecurrenceRule: times of day as
+// "HH:MM" (up to "24:00") and dates as "YYYY-MM-DD" in the rule's time zone.
+type recurrenceBody struct {
+	ID         string           `json:"id,omitempty"`
+	CarID      string           `json:"car_id,omitempty"`
+	Frequency  models.Frequency `json:"frequency"`
+	Interval   int              `json:"interval,omitempty"`
+	ByWeekday  []string         `json:"by_weekday,omitempty"`
+	StartOfDay string           `json:"start_of_day"`
+	EndOfDay   string           `json:"end_of_day"`
+	StartDate  string           `json:"start_date"`
+	Until      string           `json:"until,omitempty"`
+	Exceptions []string         `json:"exceptions,omitempty"`
+	TimeZone   string           `json:"time_zone,omitempty"`
+}
+
+const dateLayout = "2006-01-02"
+
+func parseTimeOfDay(s string) (time.Duration, bool) {
+	var h, m int
+	if _, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || len(s) != 5 || m < 0 || m > 59 || h < 0 || h > 24 || (h == 24 && m != 0) {
+		return 0, false
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, true
+}
+
+func formatTimeOfDay(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+func (b *recurrenceBody) toRule(carID string) (*models.RecurrenceRule, error) {
+	var v validator
+	rule := &models.RecurrenceRule{CarID: carID, Frequency: b.Frequency, Interval: b.Interval, TimeZone: b.TimeZone}
+	v.check(b.ID == "", "id", "is assigned by the server")
+	v.check(b.CarID == "" || b.CarID == carID, "car_id", "must match the car in the URL")
+	v.check(b.Frequency == models.FrequencyDaily || b.Frequency == models.FrequencyWeekly, "frequency", "must be DAILY or WEEKLY")
+	for i, name := range b.ByWeekday {
+		wd, ok := parseWeekday(name)
+		v.check(ok, fmt.Sprintf("by_weekday[%d]", i), "is not a weekday name")
+		rule.ByWeekday = append(rule.ByWeekday, wd)
+	}
+	var ok bool
+	rule.StartOfDay, ok = parseTimeOfDay(b.StartOfDay)
+	v.check(ok, "start_of_day", "must be HH:MM")
+	rule.EndOfDay, ok = parseTimeOfDay(b.EndOfDay)
+	v.check(ok, "end_of_day", "must be HH:MM")
+	var err error
+	rule.StartDate, err = time.Parse(dateLayout, b.StartDate)
+	v.check(err == nil, "start_date", "must be YYYY-MM-DD")
+	if b.Until != "" {
+		rule.Until, err = time.Parse(dateLayout, b.Until)
+		v.check(err == nil, "until", "must be YYYY-MM-DD")
+	}
+	for i, ex := range b.Exceptions {
+		d, err := time.Parse(dateLayout, ex)
+		v.check(err == nil, fmt.Sprintf("exceptions[%d]", i), "must be YYYY-MM-DD")
+		rule.Exceptions = append(rule.Exceptions, d)
+	}
+	if b.TimeZone != "" {
+		_, err := models.LoadLocation(b.TimeZone)
+		v.check(err == nil, "time_zone", "is not a known IANA time zone")
+	}
+	return rule, v.err()
+}
+
+func recurrenceBodyOf(rule *models.RecurrenceRule) recurrenceBody {
+	b := recurrenceBody{
+		ID:         rule.ID,
+		CarID:      rule.CarID,
+		Frequency:  rule.Frequency,
+		Interval:   rule.Interval,
+		StartOfDay: formatTimeOfDay(rule.StartOfDay),
+		EndOfDay:   formatTimeOfDay(rule.EndOfDay),
+		StartDate:  rule.StartDate.Format(dateLayout),
+		TimeZone:   rule.TimeZone,
+	}
+	for _, wd := range rule.ByWeekday {
+		b.ByWeekday = append(b.ByWeekday, strings.ToUpper(wd.String()))
+	}
+	if !rule.Until.IsZero() {
+		b.Until = rule.Until.Format(dateLayout)
+	}
+	for _, ex := range rule.Exceptions {
+		b.Exceptions = append(b.Exceptions, ex.Format(dateLayout))
+	}
+	return b
+}
+
+func (s *Server) listRecurrence(w http.ResponseWriter, r *http.Request) error {
+	if _, err := s.cfg.Cars.GetCar(r.Context(), r.PathValue("id")); err != nil {
+		return err
+	}
+	rules, err := s.cfg.Inventory.GetRecurrenceRules(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	res := make([]recurrenceBody, 0, len(rules))
+	for _, rule := range rules {
+		res = append(res, recurrenceBodyOf(rule))
+	}
+	writeJSON(w, http.StatusOK, res)
+	return nil
+}
+
+func (s *Server) addRecurrence(w http.ResponseWriter, r *http.Request) error {
+	var body recurrenceBody
+	if err := decodeJSON(w, r, &body); err != nil {
+		return err
+	}
+	rule, err := body.toRule(r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	if _, err := s.cfg.Cars.GetCar(r.Context(), rule.CarID); err != nil {
+		return err
+	}
+	rule, err = s.cfg.InventoryService.AddRecurringAvailability(r.Context(), rule)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, recurrenceBodyOf(rule))
+	return nil
+}
+
+func (s *Server) removeRecurrence(w http.ResponseWriter, r *http.Request) error {
+	var v validator
+	force := boolParam(&v, r.URL.Query(), "force")
+	if err := v.err(); err != nil {
+		return err
+	}
+	if err := s.cfg.InventoryService.RemoveRecurringAvailability(r.Context(), r.PathValue("id"), force); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// checkSearchFilters checks the radius, window and price filters shared by
+// searches and saved searches.
+func checkSearchFilters(v *validator, q *models.SearchQuery) {
+	v.check(q.RadiusKm >= 0, "radius_km", "must not be negative")
+	checkWindow(v, q.StartTime, q.EndTime)
+	v.check(q.MinPrice >= 0, "min_price", "must not be negative")
+	v.check(q.MaxPrice == 0 || q.MaxPrice >= q.MinPrice, "max_price", "must not be below min_price")
+}
+
+// search takes its filters as query parameters; car_type and amenity may be
+// repeated.
+func (s *Server) search(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	var v validator
+	query := models.SearchQuery{
+		Location:  models.Location{Latitude: floatParam(&v, q, "lat"), Longitude: floatParam(&v, q, "lng")},
+		RadiusKm:  floatParam(&v, q, "radius_km"),
+		StartTime: timeParam(&v, q, "start_time"),
+		EndTime:   timeParam(&v, q, "end_time"),
+		MinPrice:  floatParam(&v, q, "min_price"),
+		MaxPrice:  floatParam(&v, q, "max_price"),
+		Make:      q.Get("make"),
+		Model:     q.Get("model"),
+		MinYear:   intParam(&v, q, "min_year"),
+		HostID:    q.Get("host_id"),
+		SortBy:    models.SearchSort(strings.ToUpper(q.Get("sort"))),
+		Limit:     intParam(&v, q, "limit"),
+		Cursor:    q.Get("cursor"),
+	}
+	v.check(q.Has("lat") && q.Has("lng"), "lat", "lat and lng are required")
+	v.check(query.Location.Latitude >= -90 && query.Location.Latitude <= 90, "lat", "must be between -90 and 90")
+	v.check(query.Location.Longitude >= -180 && query.Location.Longitude <= 180, "lng", "must be between -180 and 180")
+	checkSearchFilters(&v, &query)
+	v.check(query.SortBy == "" || oneOf(query.SortBy, validSorts), "sort", "must be DISTANCE, PRICE, NEWEST, RATING or HOST_RATING")
+	v.check(query.Limit >= 0 && query.Limit <= 100, "limit", "must be between 0 (default) and 100")
+	for _, t := range q["car_type"] {
+		ct := models.CarType(strings.ToUpper(t))
+		v.check(oneOf(ct, validCarTypes), "car_type", fmt.Sprintf("%q is not a known car type", t))
+		query.CarTypes = append(query.CarTypes, ct)
+	}
+	for _, a := range q["amenity"] {
+		am := models.Amenity(strings.ToUpper(a))
+		v.check(oneOf(am, validAmenities), "amenity", fmt.Sprintf("%q is not a known amenity", a))
+		query.Amenities = append(query.Amenities, am)
+	}
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	page, err := s.cfg.InventoryService.Search(r.Context(), query)
+	if err != nil {
+		return err
+	}
+	page.Results = list(page.Results)
+	writeJSON(w, http.StatusOK, page)
+	return nil
+}
+
+func floatParam(v *validator, q url.Values, name string) float64 {
+	if !q.Has(name) {
+		return 0
+	}
+	f, err := strconv.ParseFloat(q.Get(name), 64)
+	v.check(err == nil, name, "must be a number")
+	return f
+}
+
+func intParam(v *validator, q url.Values, name string) int {
+	if !q.Has(name) {
+		return 0
+	}
+	n, err := strconv.Atoi(q.Get(name))
+	v.check(err == nil, name, "must be an integer")
+	return n
+}
+
+func boolParam(v *validator, q url.Values, name string) bool {
+	if !q.Has(name) {
+		return false
+	}
+	b, err := strconv.ParseBool(q.Get(name))
+	v.check(err == nil, name, "must be true or false")
+	return b
+}
+
+func timeParam(v *validator, q url.Values, name string) time.Time {
+	if !q.Has(name) {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, q.Get(name))
+	v.check(err == nil, name, "must be an RFC 3339 timestamp")
+	return t
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// csvWriter is implemented by every report that can be exported.
+type csvWriter interface {
+	WriteCSV(w io.Writer) error
+}
+
+// reportPeriod reads the required from/to query parameters and the optional
+// format, which is "json" (default) or "csv".
+func reportPeriod(r *http.Request) (from, to time.Time, csv bool, err error) {
+	q := r.URL.Query()
+	var v validator
+	from = timeParam(&v, q, "from")
+	to = timeParam(&v, q, "to")
+	checkWindow(&v, from, to)
+	format := q.Get("format")
+	v.check(format == "" || format == "json" || format == "csv", "format", "must be json or csv")
+	return from, to, format == "csv", v.err()
+}
+
+// hostReport checks the host exists, builds the report and writes it in the
+// requested format.
+func (s *Server) hostReport(w http.ResponseWriter, r *http.Request, build func(hostID string, from, to time.Time) (csvWriter, error)) error {
+	from, to, csv, err := reportPeriod(r)
+	if err != nil {
+		return err
+	}
+	hostID := r.PathValue("id")
+	if _, err := s.cfg.Users.GetHost(r.Context(), hostID); err != nil {
+		return err
+	}
+	report, err := build(hostID, from, to)
+	if err != nil {
+		return err
+	}
+	if !csv {
+		writeJSON(w, http.StatusOK, report)
+		return nil
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	return report.WriteCSV(w)
+}
+
+func (s *Server) hostCalendar(w http.ResponseWriter, r *http.Request) error {
+	return s.hostReport(w, r, func(hostID string, from, to time.Time) (csvWriter, error) {
+		return s.cfg.HostReportService.Calendar(r.Context(), hostID, from, to)
+	})
+}
+
+func (s *Server) hostOccupancy(w http.ResponseWriter, r *http.Request) error {
+	return s.hostReport(w, r, func(hostID string, from, to time.Time) (csvWriter, error) {
+		return s.cfg.HostReportService.Occupancy(r.Context(), hostID, from, to)
+	})
+}
+
+func (s *Server) hostEarnings(w http.ResponseWriter, r *http.Request) error {
+	return s.hostReport(w, r, func(hostID string, from, to time.Time) (csvWriter, error) {
+		return s.cfg.HostReportService.Earnings(r.Context(), hostID, from, to)
+	})
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+)
+
+type reviewRequest struct {
+	ReviewerID string `json:"reviewer_id"`
+	Rating     int    `json:"rating"`
+	Comment    string `json:"comment"`
+}
+
+func (s *Server) submitReview(w http.ResponseWriter, r *http.Request) error {
+	var req reviewRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	var v validator
+	v.check(req.ReviewerID != "", "reviewer_id", "is required")
+	v.check(req.Rating >= 1 && req.Rating <= 5, "rating", "must be between 1 and 5")
+	if err := v.err(); err != nil {
+		return err
+	}
+	review, err := s.cfg.ReviewService.SubmitReview(r.Context(), r.PathValue("id"), req.ReviewerID, req.Rating, req.Comment)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, review)
+	return nil
+}
+
+// listBookingReviews returns the published reviews plus, while reviews are
+// still blind, the one written by viewer_id.
+func (s *Server) listBookingReviews(w http.ResponseWriter, r *http.Request) error {
+	if _, err := s.cfg.Bookings.GetBooking(r.Context(), r.PathValue("id")); err != nil {
+		return err
+	}
+	reviews, err := s.cfg.ReviewService.GetBookingReviews(r.Context(), r.PathValue("id"), r.URL.Query().Get("viewer_id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, list(reviews))
+	return nil
+}
+
+func (s *Server) listCarReviews(w http.ResponseWriter, r *http.Request) error {
+	if _, err := s.cfg.Cars.GetCar(r.Context(), r.PathValue("id")); err != nil {
+		return err
+	}
+	reviews, err := s.cfg.ReviewService.GetCarReviews(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, list(reviews))
+	return nil
+}
+
+func (s *Server) listUserReviews(w http.ResponseWriter, r *http.Request) error {
+	if _, err := s.cfg.Users.GetUser(r.Context(), r.PathValue("id")); err != nil {
+		return err
+	}
+	reviews, err := s.cfg.ReviewService.GetUserReviews(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, list(reviews))
+	return nil
+}
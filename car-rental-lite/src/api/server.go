@@ -0,0 +1,141 @@
+package api
+
+import (
+	"car-rental-lite/src/repositories"
+	"car-rental-lite/src/services"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultRequestTimeout bounds how long a handler's context stays alive.
+const DefaultRequestTimeout = 10 * time.Second
+
+// Config holds what the server needs. Reads that have no service method go
+// straight to the repositories.
+type Config struct {
+	Users       repositories.UserRepository
+	Cars        repositories.CarRepository
+	Bookings    repositories.BookingRepository
+	Inventory   repositories.InventoryRepository
+	Inspections repositories.InspectionRepository
+
+	InventoryService  *services.InventoryService
+	BookingService    *services.BookingService
+	InspectionService *services.InspectionService
+	TripService       *services.TripService
+	ClaimService      *services.ClaimService
+	HostReportService *services.HostReportService
+	ReviewService     *services.ReviewService
+	FleetService      *services.FleetService
+	WaitlistService   *services.WaitlistService
+
+	// RequestTimeout defaults to DefaultRequestTimeout.
+	RequestTimeout time.Duration
+}
+
+// Server exposes the car rental services as a JSON API.
+type Server struct {
+	cfg Config
+	now func() time.Time
+}
+
+func NewServer(cfg Config) *Server {
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = DefaultRequestTimeout
+	}
+	return &Server{cfg: cfg, now: time.Now}
+}
+
+// SetClock replaces time.Now, e.g. with a fake clock in tests.
+func (s *Server) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// handlerFunc is an http.HandlerFunc that returns its error instead of
+// writing it, so every error goes through writeError.
+type handlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// Handler returns the API's routes. Every request's context is cancelled when
+// the client goes away or RequestTimeout passes, and that context is what the
+// services see.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	handle := func(pattern string, h handlerFunc) {
+		mux.Handle(pattern, s.wrap(h))
+	}
+
+	handle("POST /hosts", s.createHost)
+	handle("GET /hosts/{id}", s.getHost)
+	handle("GET /hosts/{id}/cars", s.listHostCars)
+	handle("GET /hosts/{id}/calendar", s.hostCalendar)
+	handle("GET /hosts/{id}/occupancy", s.hostOccupancy)
+	handle("GET /hosts/{id}/earnings", s.hostEarnings)
+	handle("POST /hosts/{id}/fleet/import", s.importFleet)
+	handle("GET /hosts/{id}/fleet/export", s.exportFleet)
+	handle("POST /hosts/{id}/fleet/activation", s.setFleetActive)
+	handle("POST /users", s.createUser)
+	handle("GET /users/{id}", s.getUser)
+	handle("GET /users/{id}/reviews", s.listUserReviews)
+
+	handle("POST /cars", s.createCar)
+	handle("GET /cars/{id}", s.getCar)
+	handle("GET /cars/{id}/reviews", s.listCarReviews)
+	handle("GET /cars/{id}/availability", s.listAvailability)
+	handle("POST /cars/{id}/availability", s.addAvailability)
+	handle("POST /cars/{id}/blocks", s.blockDates)
+	handle("PUT /availability/{id}", s.updateAvailability)
+	handle("DELETE /availability/{id}", s.removeAvailability)
+	handle("GET /cars/{id}/recurrence", s.listRecurrence)
+	handle("POST /cars/{id}/recurrence", s.addRecurrence)
+	handle("DELETE /recurrence/{id}", s.removeRecurrence)
+	handle("GET /search", s.search)
+	handle("POST /users/{id}/saved-searches", s.saveSearch)
+	handle("GET /users/{id}/saved-searches", s.listSavedSearches)
+	handle("POST /saved-searches/{id}/cancel", s.cancelSavedSearch)
+
+	handle("POST /quotes", s.quote)
+	handle("POST /bookings", s.createBooking)
+	handle("GET /bookings/{id}", s.getBooking)
+	handle("POST /bookings/{id}/confirm", s.confirmBooking)
+	handle("POST /bookings/{id}/modify", s.modifyBooking)
+	handle("POST /bookings/{id}/cancel", s.cancelBooking)
+	handle("GET /bookings/{id}/refunds", s.listRefunds)
+
+	handle("GET /bookings/{id}/inspections", s.getInspections)
+	handle("POST /bookings/{id}/pickup", s.startTrip)
+	handle("POST /bookings/{id}/return", s.endTrip)
+
+	handle("POST /bookings/{id}/claims", s.fileClaim)
+	handle("GET /bookings/{id}/claims", s.listClaims)
+	handle("GET /claims/{id}", s.getClaim)
+	handle("POST /claims/{id}/review", s.reviewClaim)
+	handle("POST /claims/{id}/approve", s.approveClaim)
+	handle("POST /claims/{id}/reject", s.rejectClaim)
+
+	handle("POST /bookings/{id}/reviews", s.submitReview)
+	handle("GET /bookings/{id}/reviews", s.listBookingReviews)
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, ErrorBody{Error: ErrorDetail{Code: CodeNotFound, Message: "no route for " + r.Method + " " + r.URL.Path}})
+	})
+	return mux
+}
+
+func (s *Server) wrap(h handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), s.cfg.RequestTimeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		defer func() {
+			if p := recover(); p != nil {
+				writeError(w, r, fmt.Errorf("panic: %v", p))
+			}
+		}()
+		if err := h(w, r); err != nil {
+			writeError(w, r, err)
+		}
+	})
+}
@@ -0,0 +1,437 @@
+package api
+
+import (
+	"bytes"
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"car-rental-lite/src/services"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type apiFixture struct {
+	t   *testing.T
+	srv *httptest.Server
+	now time.Time
+}
+
+// newAPIFixture serves a fresh in-memory stack with every clock pinned to
+// Dec 20 2025.
+func newAPIFixture(t *testing.T) *apiFixture {
+	t.Helper()
+	f := &apiFixture{t: t, now: time.Date(2025, 12, 20, 12, 0, 0, 0, time.UTC)}
+	clock := func() time.Time { return f.now }
+
+	repo := repositories.NewInMemoryRepo()
+	bookings := services.NewBookingService(repo, repo, repo, repo)
+	bookings.SetClock(clock)
+	inspections := services.NewInspectionService(repo, repo, repo)
+	inspections.SetClock(clock)
+	reviews := services.NewReviewService(repo, repo, repo)
+	reviews.SetClock(clock)
+	inventory := services.NewInventoryService(repo, repo, repo, repo)
+	inventory.SetClock(clock)
+	fleet := services.NewFleetService(repo, repo, repo, repo, inventory, bookings)
+	fleet.SetClock(clock)
+	waitlist := services.NewWaitlistService(repo, repo, repo, repo)
+	waitlist.SetClock(clock)
+	server := NewServer(Config{
+		Users:             repo,
+		Cars:              repo,
+		Bookings:          repo,
+		Inventory:         repo,
+		Inspections:       repo,
+		InventoryService:  inventory,
+		BookingService:    bookings,
+		InspectionService: inspections,
+		TripService:       services.NewTripService(repo, inspections),
+		ClaimService:      services.NewClaimService(repo, repo, repo),
+		HostReportService: services.NewHostReportService(repo, repo, repo),
+		ReviewService:     reviews,
+		FleetService:      fleet,
+		WaitlistService:   waitlist,
+	})
+	server.SetClock(clock)
+	f.srv = httptest.NewServer(server.Handler())
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+// do sends body (raw if it is a string) and decodes the response into out
+// when out is non-nil. It returns the status code.
+func (f *apiFixture) do(method, path string, body, out any) int {
+	f.t.Helper()
+	var buf bytes.Buffer
+	switch b := body.(type) {
+	case nil:
+	case string:
+		buf.WriteString(b)
+	default:
+		if err := json.NewEncoder(&buf).Encode(b); err != nil {
+			f.t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, f.srv.URL+path, &buf)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusNoContent && !strings.HasPrefix(ct, "application/json") {
+		f.t.Errorf("Expected a JSON response to %s %s, got Content-Type %q", method, path, ct)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			f.t.Fatalf("Decoding response to %s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// seed creates a host, a licensed renter and a car available for all of
+// January 2026.
+func (f *apiFixture) seed() (user *models.User, car *models.Car) {
+	f.t.Helper()
+	var host models.Host
+	if code := f.do("POST", "/hosts", map[string]any{"name": "Hana", "cancellation_policy": "FLEXIBLE"}, &host); code != http.StatusCreated {
+		f.t.Fatalf("Expected 201 creating host, got %d", code)
+	}
+	user = &models.User{}
+	renter := map[string]any{"name": "Rae", "email": "rae@example.com", "driver_license": "D123",
+		"license_expires_at": "2030-01-01T00:00:00Z", "date_of_birth": "1990-06-15T00:00:00Z"}
+	if code := f.do("POST", "/users", renter, user); code != http.StatusCreated {
+		f.t.Fatalf("Expected 201 creating user, got %d", code)
+	}
+	car = &models.Car{}
+	if code := f.do("POST", "/cars", map[string]any{
+		"host_id": host.ID, "make": "Toyota", "model": "Corolla", "year": 2022, "type": "SEDAN",
+		"price_per_day": 100, "location": map[string]any{"latitude": 37.77, "longitude": -122.42},
+	}, car); code != http.StatusCreated {
+		f.t.Fatalf("Expected 201 creating car, got %d", code)
+	}
+	if code := f.do("POST", "/cars/"+car.ID+"/availability", window(jan(1), jan(31)), nil); code != http.StatusCreated {
+		f.t.Fatalf("Expected 201 adding availability, got %d", code)
+	}
+	return user, car
+}
+
+func jan(d int) time.Time {
+	return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC)
+}
+
+func window(start, end time.Time) map[string]any {
+	return map[string]any{"start_time": start, "end_time": end}
+}
+
+func TestBookingFlow(t *testing.T) {
+	f := newAPIFixture(t)
+	user, car := f.seed()
+
+	var page models.SearchPage
+	path := "/search?lat=37.77&lng=-122.42&radius_km=5&start_time=2026-01-02T00:00:00Z&end_time=2026-01-04T00:00:00Z"
+	if code := f.do("GET", path, nil, &page); code != http.StatusOK {
+		t.Fatalf("Expected 200 from search, got %d", code)
+	}
+	if len(page.Results) != 1 || page.Results[0].Car.ID != car.ID {
+		t.Fatalf("Expected search to find the car, got %+v", page.Results)
+	}
+
+	req := map[string]any{"user_id": user.ID, "car_id": car.ID, "start_time": jan(2), "end_time": jan(4)}
+	var booking models.Booking
+	if code := f.do("POST", "/bookings", req, &booking); code != http.StatusCreated {
+		t.Fatalf("Expected 201 creating booking, got %d", code)
+	}
+	if booking.Status != models.BookingStatusPending || booking.TotalPrice != 200 {
+		t.Errorf("Expected a PENDING booking at 200, got %s at %v", booking.Status, booking.TotalPrice)
+	}
+
+	var conflict ErrorBody
+	if code := f.do("POST", "/bookings", req, &conflict); code != http.StatusConflict || conflict.Error.BookingID != booking.ID {
+		t.Errorf("Expected 409 conflict for a double booking, got %d %+v", code, conflict)
+	}
+
+	if code := f.do("POST", "/bookings/"+booking.ID+"/confirm", nil, &booking); code != http.StatusOK || booking.Status != models.BookingStatusConfirmed {
+		t.Fatalf("Expected 200 and CONFIRMED, got %d %s", code, booking.Status)
+	}
+
+	var refused ErrorBody
+	stranger := map[string]any{"actor": "RENTER", "actor_id": "someone-else"}
+	if code := f.do("POST", "/bookings/"+booking.ID+"/cancel", stranger, &refused); code != http.StatusUnprocessableEntity ||
+		len(refused.Error.Fields) != 1 || refused.Error.Fields[0].Field != "actor_id" {
+		t.Errorf("Expected 422 on actor_id for a stranger cancelling, got %d %+v", code, refused)
+	}
+
+	var refund models.Refund
+	cancel := map[string]any{"actor": "RENTER", "actor_id": user.ID}
+	if code := f.do("POST", "/bookings/"+booking.ID+"/cancel", cancel, &refund); code != http.StatusOK {
+		t.Fatalf("Expected 200 cancelling, got %d", code)
+	}
+	if refund.Amount != 200 {
+		t.Errorf("Expected a full refund under FLEXIBLE 13 days out, got %v", refund.Amount)
+	}
+	var refunds []models.Refund
+	if f.do("GET", "/bookings/"+booking.ID+"/refunds", nil, &refunds); len(refunds) != 1 {
+		t.Errorf("Expected 1 refund on record, got %d", len(refunds))
+	}
+}
+
+func TestModifyBooking(t *testing.T) {
+	f := newAPIFixture(t)
+	user, car := f.seed()
+	var first, second models.Booking
+	f.do("POST", "/bookings", map[string]any{"user_id": user.ID, "car_id": car.ID, "start_time": jan(2), "end_time": jan(4)}, &first)
+	f.do("POST", "/bookings", map[string]any{"user_id": user.ID, "car_id": car.ID, "start_time": jan(6), "end_time": jan(8)}, &second)
+
+	var extended models.Booking
+	if code := f.do("POST", "/bookings/"+first.ID+"/modify", window(jan(2), jan(5)), &extended); code != http.StatusOK ||
+		extended.TotalPrice != 300 || len(extended.Modifications) != 1 || extended.Modifications[0].PriceDifference != 100 {
+		t.Fatalf("Expected 200 extending to 300 (+100), got %d %+v", code, extended)
+	}
+	var conflict ErrorBody
+	if code := f.do("POST", "/bookings/"+first.ID+"/modify", window(jan(2), jan(7)), &conflict); code != http.StatusConflict || conflict.Error.BookingID != second.ID {
+		t.Errorf("Expected 409 naming booking %s, got %d %+v", second.ID, code, conflict)
+	}
+}
+
+func TestHostReports(t *testing.T) {
+	f := newAPIFixture(t)
+	user, car := f.seed()
+	var booking models.Booking
+	f.do("POST", "/bookings", map[string]any{"user_id": user.ID, "car_id": car.ID, "start_time": jan(2), "end_time": jan(4)}, &booking)
+	f.do("POST", "/bookings/"+booking.ID+"/confirm", nil, nil)
+
+	period := "?from=2026-01-01T00:00:00Z&to=2026-02-01T00:00:00Z"
+	var occ services.OccupancyReport
+	if code := f.do("GET", "/hosts/"+car.HostID+"/occupancy"+period, nil, &occ); code != http.StatusOK || occ.Fleet.BookedHours != 48 {
+		t.Errorf("Expected 200 with 48 booked hours, got %d %+v", code, occ.Fleet)
+	}
+
+	resp, err := http.Get(f.srv.URL + "/hosts/" + car.HostID + "/earnings" + period + "&format=csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	want := "car_id,month,bookings,gross,refunds,commission,net\n" + car.ID + ",2026-01,1,200,0,30,170\nTOTAL,,1,200,0,30,170\n"
+	if resp.Header.Get("Content-Type") != "text/csv" || string(body) != want {
+		t.Errorf("Expected earnings CSV\n%s\ngot %s\n%s", want, resp.Header.Get("Content-Type"), body)
+	}
+
+	if code := f.do("GET", "/hosts/missing/calendar"+period, nil, nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown host, got %d", code)
+	}
+}
+
+func TestFleet(t *testing.T) {
+	f := newAPIFixture(t)
+	user, car := f.seed()
+	fleetPath := "/hosts/" + car.HostID + "/fleet"
+
+	var dry services.ImportResult
+	batch := map[string]any{
+		"cars":         []any{map[string]any{"id": "van1", "make": "Ford", "model": "Transit", "year": 2023, "type": "VAN", "price_per_day": 120}},
+		"availability": []any{map[string]any{"car_id": "van1", "start_time": "2026-01-01T00:00:00Z", "end_time": "2026-02-01T00:00:00Z"}},
+	}
+	if code := f.do("POST", fleetPath+"/import?dry_run=true", batch, &dry); code != http.StatusOK || !dry.DryRun || dry.Imported != 2 {
+		t.Fatalf("Expected a clean dry run of 2 rows, got %d %+v", code, dry)
+	}
+	if code := f.do("GET", "/cars/van1", nil, nil); code != http.StatusNotFound {
+		t.Errorf("Expected the dry run to create nothing, got %d", code)
+	}
+
+	csvBody := "id,make,model,year,type,price_per_day\nvan1,Ford,Transit,2023,VAN,120\nbad,Ford,,2023,VAN,0\n"
+	resp, err := http.Post(f.srv.URL+fleetPath+"/import?section=cars", "text/csv", strings.NewReader(csvBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res services.ImportResult
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK || res.Imported != 1 || res.Failed != 1 || len(res.Cars[1].Problems) != 2 {
+		t.Errorf("Expected van1 imported and the bad row reported, got %d %+v, %v", resp.StatusCode, res, err)
+	}
+	if code := f.do("POST", fleetPath+"/import?section=cars", batch, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a section on a JSON import, got %d", code)
+	}
+
+	var booking models.Booking
+	if code := f.do("POST", "/bookings", map[string]any{"user_id": user.ID, "car_id": car.ID, "start_time": "2026-01-02T00:00:00Z", "end_time": "2026-01-04T00:00:00Z"}, &booking); code != http.StatusCreated {
+		t.Fatalf("Expected 201 creating booking, got %d", code)
+	}
+	resp, err = http.Get(f.srv.URL + fleetPath + "/export?from=2026-01-01T00:00:00Z&to=2026-02-01T00:00:00Z&format=csv&section=bookings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/csv" || !strings.Contains(string(body), booking.ID+","+car.ID+","+user.ID+",PENDING") {
+		t.Errorf("Expected the booking in the CSV export, got %s", body)
+	}
+
+	var results []services.ActivationResult
+	if code := f.do("POST", fleetPath+"/activation", map[string]any{"car_ids": []string{car.ID, "van1"}, "active": false}, &results); code != http.StatusOK {
+		t.Fatalf("Expected 200 deactivating, got %d", code)
+	}
+	if len(results) != 2 || !results[0].IsActive || len(results[0].Conflicts) != 1 || results[1].IsActive {
+		t.Errorf("Expected the booked car flagged and van1 deactivated, got %+v", results)
+	}
+	if code := f.do("POST", fleetPath+"/activation", map[string]any{"car_ids": []string{car.ID}}, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 without active, got %d", code)
+	}
+}
+
+func TestSavedSearches(t *testing.T) {
+	f := newAPIFixture(t)
+	user, car := f.seed()
+	searchesPath := "/users/" + user.ID + "/saved-searches"
+	query := map[string]any{"location": map[string]any{"latitude": 37.77, "longitude": -122.42}, "radius_km": 5,
+		"start_time": jan(2), "end_time": jan(4), "car_types": []string{"SEDAN"}}
+
+	var saved models.SavedSearch
+	if code := f.do("POST", searchesPath, query, &saved); code != http.StatusCreated || saved.Status != models.SavedSearchActive {
+		t.Fatalf("Expected 201 with an active search, got %d %+v", code, saved)
+	}
+	query["car_types"] = []string{"BOAT"}
+	var invalid ErrorBody
+	if code := f.do("POST", searchesPath, query, &invalid); code != http.StatusUnprocessableEntity || invalid.Error.Fields[0].Field != "car_types" {
+		t.Errorf("Expected 422 for an unknown car type, got %d %+v", code, invalid.Error)
+	}
+	if code := f.do("POST", "/users/missing/saved-searches", map[string]any{"start_time": jan(2), "end_time": jan(4)}, nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown user, got %d", code)
+	}
+
+	if code := f.do("POST", "/saved-searches/"+saved.ID+"/cancel", map[string]any{"user_id": car.HostID}, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 cancelling someone else's search, got %d", code)
+	}
+	if code := f.do("POST", "/saved-searches/"+saved.ID+"/cancel", map[string]any{"user_id": user.ID}, &saved); code != http.StatusOK || saved.Status != models.SavedSearchCancelled {
+		t.Errorf("Expected 200 with the search cancelled, got %d %+v", code, saved)
+	}
+	if code := f.do("POST", "/saved-searches/"+saved.ID+"/cancel", map[string]any{"user_id": user.ID}, nil); code != http.StatusConflict {
+		t.Errorf("Expected 409 cancelling twice, got %d", code)
+	}
+
+	var searches []models.SavedSearch
+	if code := f.do("GET", searchesPath, nil, &searches); code != http.StatusOK || len(searches) != 1 || searches[0].ID != saved.ID {
+		t.Errorf("Expected the one saved search listed, got %d %+v", code, searches)
+	}
+	if code := f.do("GET", "/users/missing/saved-searches", nil, nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 listing for an unknown user, got %d", code)
+	}
+}
+
+func TestReviews(t *testing.T) {
+	f := newAPIFixture(t)
+	user, car := f.seed()
+	var booking models.Booking
+	f.do("POST", "/bookings", map[string]any{"user_id": user.ID, "car_id": car.ID, "start_time": jan(2), "end_time": jan(4)}, &booking)
+	f.do("POST", "/bookings/"+booking.ID+"/confirm", nil, nil)
+
+	review := map[string]any{"reviewer_id": user.ID, "rating": 4, "comment": "Smooth pickup"}
+	if code := f.do("POST", "/bookings/"+booking.ID+"/reviews", review, nil); code != http.StatusConflict {
+		t.Errorf("Expected 409 reviewing an unfinished trip, got %d", code)
+	}
+	f.now = jan(2)
+	pickup := map[string]any{"inspector_id": car.HostID, "fuel_level": 100}
+	if code := f.do("POST", "/bookings/"+booking.ID+"/inspections", map[string]any{"inspector_id": car.HostID, "type": "PICKUP"}, nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 filing a pickup report outside the trip flow, got %d", code)
+	}
+	if code := f.do("POST", "/bookings/"+booking.ID+"/pickup", pickup, nil); code != http.StatusOK {
+		t.Fatalf("Expected 200 picking up the car, got %d", code)
+	}
+	f.now = jan(4)
+	if code := f.do("POST", "/bookings/"+booking.ID+"/return", map[string]any{"inspector_id": car.HostID, "fuel_level": 100}, &booking); code != http.StatusOK {
+		t.Fatalf("Expected 200 returning the car, got %d", code)
+	}
+
+	if code := f.do("POST", "/bookings/"+booking.ID+"/reviews", review, nil); code != http.StatusCreated {
+		t.Fatalf("Expected 201 for the renter's review, got %d", code)
+	}
+	var seen []models.Review
+	if f.do("GET", "/bookings/"+booking.ID+"/reviews?viewer_id="+car.HostID, nil, &seen); len(seen) != 0 {
+		t.Errorf("Expected the host to see nothing yet, got %+v", seen)
+	}
+	if code := f.do("POST", "/bookings/"+booking.ID+"/reviews", map[string]any{"reviewer_id": car.HostID, "rating": 5}, nil); code != http.StatusCreated {
+		t.Fatalf("Expected 201 for the host's review, got %d", code)
+	}
+	if f.do("GET", "/cars/"+car.ID+"/reviews", nil, &seen); len(seen) != 1 || seen[0].Comment != "Smooth pickup" {
+		t.Errorf("Expected the renter's review on the car, got %+v", seen)
+	}
+	var rated models.Car
+	if f.do("GET", "/cars/"+car.ID, nil, &rated); rated.Rating != (models.Rating{Average: 4, Count: 1}) {
+		t.Errorf("Expected the car rated 4 from 1 review, got %+v", rated.Rating)
+	}
+	if f.do("GET", "/users/"+user.ID+"/reviews", nil, &seen); len(seen) != 1 || seen[0].Rating != 5 {
+		t.Errorf("Expected the host's 5 on the renter, got %+v", seen)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	f := newAPIFixture(t)
+	user, car := f.seed()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		status int
+		code   string
+		fields []string
+	}{
+		{"unknown booking", "GET", "/bookings/missing", nil, http.StatusNotFound, CodeNotFound, nil},
+		{"unknown route", "GET", "/nope", nil, http.StatusNotFound, CodeNotFound, nil},
+		{"malformed json", "POST", "/bookings", `{"user_id":`, http.StatusBadRequest, CodeBadRequest, nil},
+		{"unknown field", "POST", "/users", `{"name":"x","email":"x@y","admin":true}`, http.StatusBadRequest, CodeBadRequest, nil},
+		{"invalid car", "POST", "/cars", map[string]any{"make": "Kia", "year": 1800, "price_per_day": -1, "location": map[string]any{"time_zone": "Mars/Olympus"}},
+			http.StatusUnprocessableEntity, CodeValidation, []string{"host_id", "model", "year", "type", "price_per_day", "location.time_zone"}},
+		{"backwards window", "POST", "/bookings", map[string]any{"user_id": user.ID, "car_id": car.ID, "start_time": jan(4), "end_time": jan(2)},
+			http.StatusUnprocessableEntity, CodeValidation, []string{"end_time"}},
+		{"past start", "POST", "/bookings", map[string]any{"user_id": user.ID, "car_id": car.ID, "start_time": time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), "end_time": jan(2)},
+			http.StatusUnprocessableEntity, CodeValidation, []string{"start_time"}},
+		{"missing car", "POST", "/bookings", map[string]any{"user_id": user.ID, "car_id": "missing", "start_time": jan(2), "end_time": jan(4)},
+			http.StatusUnprocessableEntity, CodeValidation, []string{"car_id"}},
+		{"outside availability", "POST", "/bookings", map[string]any{"user_id": user.ID, "car_id": car.ID, "start_time": jan(30), "end_time": time.Date(2026, 2, 2, 0, 0, 0, 0, time.UTC)},
+			http.StatusConflict, CodeConflict, nil},
+		{"confirm unknown", "POST", "/bookings/missing/confirm", nil, http.StatusNotFound, CodeNotFound, nil},
+		{"client-set rating", "POST", "/users", map[string]any{"name": "x", "email": "x@y", "rating": map[string]any{"average": 5, "count": 9}},
+			http.StatusUnprocessableEntity, CodeValidation, []string{"rating"}},
+		{"client-set ban", "POST", "/users", map[string]any{"name": "x", "email": "x@y", "banned": true}, http.StatusUnprocessableEntity, CodeValidation, []string{"banned"}},
+		{"out of range review", "POST", "/bookings/missing/reviews", map[string]any{"reviewer_id": user.ID, "rating": 0},
+			http.StatusUnprocessableEntity, CodeValidation, []string{"rating"}},
+		{"reviews of unknown car", "GET", "/cars/missing/reviews", nil, http.StatusNotFound, CodeNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body ErrorBody
+			if code := f.do(tt.method, tt.path, tt.body, &body); code != tt.status {
+				t.Fatalf("Expected %d, got %d (%+v)", tt.status, code, body)
+			}
+			if body.Error.Code != tt.code || body.Error.Message == "" {
+				t.Errorf("Expected code %s with a message, got %+v", tt.code, body.Error)
+			}
+			var fields []string
+			for _, fe := range body.Error.Fields {
+				fields = append(fields, fe.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+				t.Errorf("Expected invalid fields %v, got %v", tt.fields, fields)
+			}
+		})
+	}
+
+	var unlicensed models.User
+	f.do("POST", "/users", map[string]any{"name": "Uma", "email": "uma@example.com", "date_of_birth": "1990-01-01T00:00:00Z"}, &unlicensed)
+	var refused ErrorBody
+	code := f.do("POST", "/bookings", map[string]any{"user_id": unlicensed.ID, "car_id": car.ID, "start_time": jan(2), "end_time": jan(4)}, &refused)
+	if code != http.StatusForbidden || refused.Error.Code != CodeIneligible || len(refused.Error.Reasons) != 1 || refused.Error.Reasons[0].Code != models.EligibilityLicenseMissing {
+		t.Errorf("Expected 403 with a LICENSE_MISSING reason, got %d %+v", code, refused.Error)
+	}
+}
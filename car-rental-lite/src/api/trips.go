@@ -0,0 +1,170 @@
+package api
+
+import (
+	"car-rental-lite/src/models"
+	"net/http"
+	"strings"
+)
+
+type inspectionRequest struct {
+	InspectorID string                `json:"inspector_id"`
+	Type        models.InspectionType `json:"type"`
+	Notes       string                `json:"notes"`
+	ImageURLs   []string              `json:"image_urls"`
+	FuelLevel   float64               `json:"fuel_level"`
+	Odometer    int                   `json:"odometer"`
+}
+
+// decodeInspection reads a report for the booking in the URL. The pickup and
+// return endpoints imply the type, so the body may omit it.
+func decodeInspection(w http.ResponseWriter, r *http.Request, implied models.InspectionType) (*models.InspectionReport, error) {
+	var req inspectionRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return nil, err
+	}
+	if req.Type == "" {
+		req.Type = implied
+	}
+	var v validator
+	v.check(req.InspectorID != "", "inspector_id", "is required")
+	v.check(req.Type == implied, "type", "must be "+string(implied)+" for this endpoint")
+	v.check(req.FuelLevel >= 0 && req.FuelLevel <= 100, "fuel_level", "must be between 0 and 100")
+	v.check(req.Odometer >= 0, "odometer", "must not be negative")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return &models.InspectionReport{
+		BookingID:   r.PathValue("id"),
+		InspectorID: req.InspectorID,
+		Type:        req.Type,
+		Notes:       req.Notes,
+		ImageURLs:   req.ImageURLs,
+		FuelLevel:   req.FuelLevel,
+		Odometer:    req.Odometer,
+	}, nil
+}
+
+type inspectionsResponse struct {
+	Pickup  *models.InspectionReport `json:"pickup"`
+	Dropoff *models.InspectionReport `json:"dropoff"`
+}
+
+func (s *Server) getInspections(w http.ResponseWriter, r *http.Request) error {
+	if _, err := s.cfg.Bookings.GetBooking(r.Context(), r.PathValue("id")); err != nil {
+		return err
+	}
+	pickup, dropoff, err := s.cfg.InspectionService.GetInspections(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, inspectionsResponse{Pickup: pickup, Dropoff: dropoff})
+	return nil
+}
+
+func (s *Server) startTrip(w http.ResponseWriter, r *http.Request) error {
+	report, err := decodeInspection(w, r, models.InspectionTypePickup)
+	if err != nil {
+		return err
+	}
+	booking, err := s.cfg.TripService.StartTrip(r.Context(), report)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, booking)
+	return nil
+}
+
+func (s *Server) endTrip(w http.ResponseWriter, r *http.Request) error {
+	report, err := decodeInspection(w, r, models.InspectionTypeDropoff)
+	if err != nil {
+		return err
+	}
+	booking, err := s.cfg.TripService.EndTrip(r.Context(), report)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, booking)
+	return nil
+}
+
+type claimRequest struct {
+	ClaimantID        string   `json:"claimant_id"`
+	Description       string   `json:"description"`
+	EvidenceImageURLs []string `json:"evidence_image_urls"`
+	EstimatedCost     float64  `json:"estimated_cost"`
+}
+
+func (s *Server) fileClaim(w http.ResponseWriter, r *http.Request) error {
+	var req claimRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	var v validator
+	v.check(req.ClaimantID != "", "claimant_id", "is required")
+	v.check(strings.TrimSpace(req.Description) != "", "description", "is required")
+	v.check(len(req.EvidenceImageURLs) > 0, "evidence_image_urls", "must include at least one image")
+	v.check(req.EstimatedCost > 0, "estimated_cost", "must be positive")
+	if err := v.err(); err != nil {
+		return err
+	}
+	claim, err := s.cfg.ClaimService.FileClaim(r.Context(), &models.DamageClaim{
+		BookingID:         r.PathValue("id"),
+		ClaimantID:        req.ClaimantID,
+		Description:       req.Description,
+		EvidenceImageURLs: req.EvidenceImageURLs,
+		EstimatedCost:     req.EstimatedCost,
+	})
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, claim)
+	return nil
+}
+
+func (s *Server) listClaims(w http.ResponseWriter, r *http.Request) error {
+	if _, err := s.cfg.Bookings.GetBooking(r.Context(), r.PathValue("id")); err != nil {
+		return err
+	}
+	claims, err := s.cfg.Inspections.GetClaimsForBooking(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, list(claims))
+	return nil
+}
+
+func (s *Server) getClaim(w http.ResponseWriter, r *http.Request) error {
+	claim, err := s.cfg.Inspections.GetClaim(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, claim)
+	return nil
+}
+
+func (s *Server) reviewClaim(w http.ResponseWriter, r *http.Request) error {
+	claim, err := s.cfg.ClaimService.StartReview(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, claim)
+	return nil
+}
+
+func (s *Server) approveClaim(w http.ResponseWriter, r *http.Request) error {
+	claim, err := s.cfg.ClaimService.Approve(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, claim)
+	return nil
+}
+
+func (s *Server) rejectClaim(w http.ResponseWriter, r *http.Request) error {
+	claim, err := s.cfg.ClaimService.Reject(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, claim)
+	return nil
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"car-rental-lite/src/models"
+	"fmt"
+	"net/http"
+)
+
+// saveSearch puts the renter on the waitlist. The body is a search query as
+// JSON; sort_by, limit and cursor are ignored.
+func (s *Server) saveSearch(w http.ResponseWriter, r *http.Request) error {
+	var query models.SearchQuery
+	if err := decodeJSON(w, r, &query); err != nil {
+		return err
+	}
+	var v validator
+	loc := query.Location
+	v.check(loc.Latitude >= -90 && loc.Latitude <= 90 && loc.Longitude >= -180 && loc.Longitude <= 180, "location", "must be a valid latitude and longitude")
+	checkSearchFilters(&v, &query)
+	for _, t := range query.CarTypes {
+		v.check(oneOf(t, validCarTypes), "car_types", fmt.Sprintf("%q is not a known car type", t))
+	}
+	for _, a := range query.Amenities {
+		v.check(oneOf(a, validAmenities), "amenities", fmt.Sprintf("%q is not a known amenity", a))
+	}
+	if err := v.err(); err != nil {
+		return err
+	}
+	search, err := s.cfg.WaitlistService.SaveSearch(r.Context(), r.PathValue("id"), query)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, search)
+	return nil
+}
+
+func (s *Server) listSavedSearches(w http.ResponseWriter, r *http.Request) error {
+	if _, err := s.cfg.Users.GetUser(r.Context(), r.PathValue("id")); err != nil {
+		return err
+	}
+	searches, err := s.cfg.WaitlistService.GetSavedSearches(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, list(searches))
+	return nil
+}
+
+type cancelSearchRequest struct {
+	UserID string `json:"user_id"`
+}
+
+func (s *Server) cancelSavedSearch(w http.ResponseWriter, r *http.Request) error {
+	var req cancelSearchRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	var v validator
+	v.check(req.UserID != "", "user_id", "is required")
+	if err := v.err(); err != nil {
+		return err
+	}
+	search, err := s.cfg.WaitlistService.CancelSavedSearch(r.Context(), r.PathValue("id"), req.UserID)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, search)
+	return nil
+}
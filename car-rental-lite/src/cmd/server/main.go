@@ -0,0 +1,95 @@
+// Command server runs the car rental JSON API over in-memory repositories.
+package main
+
+import (
+	"car-rental-lite/src/api"
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"car-rental-lite/src/services"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	timeout := flag.Duration("timeout", api.DefaultRequestTimeout, "per-request timeout")
+	sweep := flag.Duration("sweep", time.Minute, "how often unpaid booking holds are expired, closed review windows published and failed event deliveries retried")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	repo := repositories.NewInMemoryRepo()
+	inventory := services.NewInventoryService(repo, repo, repo, repo)
+	bookings := services.NewBookingService(repo, repo, repo, repo)
+	inspections := services.NewInspectionService(repo, repo, repo)
+	trips := services.NewTripService(repo, inspections)
+	claims := services.NewClaimService(repo, repo, repo)
+	reviews := services.NewReviewService(repo, repo, repo)
+	waitlist := services.NewWaitlistService(repo, repo, repo, repo)
+
+	// Domain events go through an in-process bus, after each write commits,
+	// and are lost if the process stops first. Everything is logged,
+	// which for now is also how renters hear about waitlist matches; the
+	// waitlist re-checks saved searches when a car frees up.
+	bus := services.NewEventBus()
+	bus.Subscribe(func(ctx context.Context, ev models.Event) error {
+		fmt.Printf("[events] %s %+v\n", ev.EventType(), ev)
+		return nil
+	})
+	bus.Subscribe(waitlist.HandleEvent, models.EventBookingCancelled, models.EventAvailabilityChanged)
+	inventory.SetEventPublisher(bus)
+	bookings.SetEventPublisher(bus)
+	trips.SetEventPublisher(bus)
+	claims.SetEventPublisher(bus)
+	waitlist.SetEventPublisher(bus)
+
+	services.NewHoldSweeper(bookings, *sweep).Start(ctx)
+	services.NewReviewPublisher(reviews, *sweep).Start(ctx)
+	services.NewEventRelay(bus, *sweep).Start(ctx)
+
+	server := api.NewServer(api.Config{
+		Users:             repo,
+		Cars:              repo,
+		Bookings:          repo,
+		Inventory:         repo,
+		Inspections:       repo,
+		InventoryService:  inventory,
+		BookingService:    bookings,
+		InspectionService: inspections,
+		TripService:       trips,
+		ClaimService:      claims,
+		HostReportService: services.NewHostReportService(repo, repo, repo),
+		ReviewService:     reviews,
+		FleetService:      services.NewFleetService(repo, repo, repo, repo, inventory, bookings),
+		WaitlistService:   waitlist,
+		RequestTimeout:    *timeout,
+	})
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("[server] listening on %s\n", *addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("[server] %v\n", err)
+		os.Exit(1)
+	}
+}
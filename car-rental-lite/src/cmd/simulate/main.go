@@ -0,0 +1,108 @@
+// Command simulate races concurrent renters for a generated fleet and
+// reports booking throughput, conflict rate and latency. It exits non-zero
+// if any car was overbooked, so it can gate changes to a repository.
+package main
+
+import (
+	"car-rental-lite/src/database"
+	"car-rental-lite/src/repositories"
+	"car-rental-lite/src/simulator"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+)
+
+func main() {
+	var cfg simulator.Config
+	flag.IntVar(&cfg.Hosts, "hosts", 10, "number of hosts")
+	flag.IntVar(&cfg.CarsPerHost, "cars", 5, "cars per host")
+	flag.IntVar(&cfg.Renters, "renters", 50, "concurrent renters")
+	flag.IntVar(&cfg.Attempts, "attempts", 20, "booking attempts per renter")
+	flag.Float64Var(&cfg.Contention, "contention", 0.5, "share of attempts aimed at the hot cars and days, 0 to 1")
+	flag.IntVar(&cfg.HotCars, "hot-cars", 1, "number of hot cars")
+	flag.IntVar(&cfg.HotDays, "hot-days", 3, "number of hot days")
+	flag.IntVar(&cfg.Days, "days", 30, "days of availability per car")
+	flag.IntVar(&cfg.MaxTripDays, "max-trip", 3, "longest trip in days")
+	flag.Float64Var(&cfg.CancelRate, "cancel-rate", 0.1, "share of bookings cancelled straight away, 0 to 1")
+	flag.Int64Var(&cfg.Seed, "seed", 1, "seed for the renters' choices")
+	repoKind := flag.String("repo", "memory", "repository to exercise: memory or sql")
+	dbPath := flag.String("db", "", "SQLite file for -repo sql; defaults to a fresh temporary file")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	repo, cleanup, err := openRepo(*repoKind, *dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[simulate] %v\n", err)
+		os.Exit(2)
+	}
+	defer cleanup()
+
+	report, err := simulator.Run(ctx, repo, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[simulate] %v\n", err)
+		cleanup()
+		os.Exit(2)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		printReport(report)
+	}
+	if err := report.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "[simulate] FAIL: %v\n", err)
+		cleanup()
+		os.Exit(1)
+	}
+}
+
+func openRepo(kind, dbPath string) (simulator.Repository, func(), error) {
+	switch kind {
+	case "memory":
+		return repositories.NewInMemoryRepo(), func() {}, nil
+	case "sql":
+		cleanup := func() {}
+		if dbPath == "" {
+			dir, err := os.MkdirTemp("", "simulate")
+			if err != nil {
+				return nil, nil, err
+			}
+			dbPath = filepath.Join(dir, "rental.db")
+			cleanup = func() { os.RemoveAll(dir) }
+		}
+		db, err := database.InitDB(dbPath)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		return repositories.NewSQLRepo(db), cleanup, nil
+	}
+	return nil, nil, fmt.Errorf("unknown repository %q, want memory or sql", kind)
+}
+
+func printReport(r *simulator.Report) {
+	fmt.Printf("attempts     %d\n", r.Attempts)
+	fmt.Printf("booked       %d (%d cancelled again)\n", r.Booked, r.Cancelled)
+	fmt.Printf("conflicts    %d (%.1f%%)\n", r.Conflicts, r.ConflictRate*100)
+	fmt.Printf("rejected     %d\n", r.Rejected)
+	fmt.Printf("errors       %d\n", r.Errors)
+	for _, e := range r.FirstErrors {
+		fmt.Printf("  %s\n", e)
+	}
+	fmt.Printf("elapsed      %s\n", r.Elapsed)
+	fmt.Printf("throughput   %.1f bookings/s\n", r.Throughput)
+	fmt.Printf("latency      p50 %s  p90 %s  p99 %s  max %s\n", r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.Max)
+	fmt.Printf("overbooked   %d pairs, %d promised bookings missing\n", len(r.Overbookings), r.Missing)
+	for _, o := range r.Overbookings {
+		fmt.Printf("  %s: %s and %s\n", o.CarID, o.First, o.Second)
+	}
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"car-rental-lite/src/repositories"
+	"strings"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqliteOptions make SQLite usable by several processes at once: WAL lets
+// readers run alongside the writer, transactions take the write lock up
+// front (BEGIN IMMEDIATE), and a busy writer is waited for instead of failing
+// straight away.
+const sqliteOptions = "_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate&_foreign_keys=on"
+
+// InitDB opens an SQLite database at dbPath and applies pending migrations.
+func InitDB(dbPath string) (*gorm.DB, error) {
+	dsn := dbPath
+	if strings.Contains(dsn, "?") {
+		dsn += "&" + sqliteOptions
+	} else {
+		dsn += "?" + sqliteOptions
+	}
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repositories.Migrate(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,109 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// IDGenerator produces unique, lexicographically sortable IDs.
+type IDGenerator interface {
+	NewID() string
+}
+
+// crockford is the Crockford base32 alphabet used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator generates ULIDs: a 48-bit millisecond timestamp followed by
+// 80 bits of randomness. IDs created within the same millisecond increment
+// the random part, so they stay strictly ordered.
+type ULIDGenerator struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	entropy  io.Reader
+	lastMs   uint64
+	lastRand [10]byte
+}
+
+func NewULIDGenerator() *ULIDGenerator {
+	return &ULIDGenerator{
+		now:     time.Now,
+		entropy: rand.Reader,
+	}
+}
+
+func (g *ULIDGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(g.now().UnixMilli())
+	if ms <= g.lastMs {
+		// Same (or earlier, if the clock stepped back) millisecond: keep the
+		// previous timestamp and bump the random part to stay monotonic.
+		ms = g.lastMs
+		incrementBytes(g.lastRand[:])
+	} else {
+		if _, err := io.ReadFull(g.entropy, g.lastRand[:]); err != nil {
+			panic(fmt.Sprintf("idgen: reading entropy: %v", err))
+		}
+		g.lastMs = ms
+	}
+
+	var raw [16]byte
+	for i := 0; i < 6; i++ {
+		raw[i] = byte(ms >> (40 - 8*i))
+	}
+	copy(raw[6:], g.lastRand[:])
+	return encodeULID(raw)
+}
+
+func incrementBytes(b []byte) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return
+		}
+	}
+}
+
+// encodeULID writes the 128-bit value as 26 base32 characters, 5 bits at a
+// time starting from the most significant bit (the first character carries
+// only 3 bits).
+func encodeULID(raw [16]byte) string {
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[raw[15]&0x1F]
+		shiftRight5(&raw)
+	}
+	return string(out)
+}
+
+func shiftRight5(raw *[16]byte) {
+	var carry byte
+	for i := 0; i < len(raw); i++ {
+		next := raw[i] << 3
+		raw[i] = raw[i]>>5 | carry
+		carry = next
+	}
+}
+
+// SequenceGenerator returns prefix-000000000001, prefix-000000000002, ...
+// It's deterministic, which keeps tests and demos reproducible.
+type SequenceGenerator struct {
+	mu     sync.Mutex
+	prefix string
+	next   uint64
+}
+
+func NewSequenceGenerator(prefix string) *SequenceGenerator {
+	return &SequenceGenerator{prefix: prefix}
+}
+
+func (g *SequenceGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.next++
+	return fmt.Sprintf("%s-%012d", g.prefix, g.next)
+}
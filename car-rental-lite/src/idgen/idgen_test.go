@@ -0,0 +1,59 @@
+package idgen
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestULIDGeneratorSortableAndUnique(t *testing.T) {
+	g := NewULIDGenerator()
+	fixed := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return fixed }
+
+	ids := make([]string, 1000)
+	seen := make(map[string]bool)
+	for i := range ids {
+		ids[i] = g.NewID()
+		if len(ids[i]) != 26 {
+			t.Fatalf("Expected 26 characters, got %q", ids[i])
+		}
+		if seen[ids[i]] {
+			t.Fatalf("Duplicate ID %q", ids[i])
+		}
+		seen[ids[i]] = true
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Error("Expected IDs generated in the same millisecond to be sorted")
+	}
+
+	g.now = func() time.Time { return fixed.Add(time.Millisecond) }
+	if next := g.NewID(); next <= ids[len(ids)-1] {
+		t.Errorf("Expected %q to sort after %q", next, ids[len(ids)-1])
+	}
+}
+
+func TestULIDGeneratorEncodesTimestamp(t *testing.T) {
+	g := NewULIDGenerator()
+	g.now = func() time.Time { return time.UnixMilli(0) }
+	if id := g.NewID(); id[:10] != "0000000000" {
+		t.Errorf("Expected zero timestamp prefix, got %q", id)
+	}
+	g = NewULIDGenerator()
+	g.now = func() time.Time { return time.UnixMilli(1) }
+	if id := g.NewID(); id[:10] != "0000000001" {
+		t.Errorf("Expected timestamp prefix 0000000001, got %q", id)
+	}
+}
+
+func TestSequenceGeneratorDeterministic(t *testing.T) {
+	a, b := NewSequenceGenerator("bk"), NewSequenceGenerator("bk")
+	for i := 0; i < 3; i++ {
+		if x, y := a.NewID(), b.NewID(); x != y {
+			t.Fatalf("Expected identical sequences, got %q and %q", x, y)
+		}
+	}
+	if id := a.NewID(); id != "bk-000000000004" {
+		t.Errorf("Expected bk-000000000004, got %q", id)
+	}
+}
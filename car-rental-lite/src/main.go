@@ -13,14 +13,25 @@ func main() {
 	ctx := context.Background()
 
 	repo := repositories.NewInMemoryRepo()
-	invService := services.NewInventoryService(repo, repo, repo)
-	bookService := services.NewBookingService(repo, repo, repo)
+	invService := services.NewInventoryService(repo, repo, repo, repo)
+	bookService := services.NewBookingService(repo, repo, repo, repo)
+	bookService.SetPricingEngine(services.NewPricingEngine(
+		&services.BaseRateRule{},
+		&services.SurchargeRule{WeekendPercent: 10},
+		&services.DurationDiscountRule{WeeklyPercent: 10, MonthlyPercent: 25},
+		&services.FeeRule{CleaningFee: 25, ServiceFeePercent: 10},
+		&services.TaxRule{RatesByCity: map[string]float64{"San Francisco": 8.625}},
+	))
 
-	host := &models.Host{ID: "host1", Name: "Host Alice", Email: "alice@example.com"}
+	host := &models.Host{ID: "host1", Name: "Host Alice", Email: "alice@example.com", CancellationPolicy: models.CancellationPolicyModerate}
 	repo.CreateHost(ctx, host)
 
-	user := &models.User{ID: "user1", Name: "Renter Bob", Email: "bob@example.com"}
+	licenseExpiry := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	user := &models.User{ID: "user1", Name: "Renter Bob", Email: "bob@example.com",
+		DriverLicense: "D1234567", LicenseExpiresAt: licenseExpiry, DateOfBirth: time.Date(1990, 6, 15, 0, 0, 0, 0, time.UTC)}
 	repo.CreateUser(ctx, user)
+	repo.CreateUser(ctx, &models.User{ID: "user2", Name: "Renter Cara", Email: "cara@example.com",
+		DriverLicense: "D7654321", LicenseExpiresAt: licenseExpiry, DateOfBirth: time.Date(1985, 2, 1, 0, 0, 0, 0, time.UTC)})
 
 	car := &models.Car{
 		ID:           "car1",
@@ -28,9 +39,11 @@ func main() {
 		Make:         "Toyota",
 		Model:        "Camry",
 		Year:         2022,
+		Type:         models.CarTypeSedan,
+		Amenities:    []models.Amenity{models.AmenityBluetooth, models.AmenityGPS},
 		PricePerDay:  100.0,
 		IsActive:     true,
-		Location:     models.Location{City: "San Francisco"},
+		Location:     models.Location{Latitude: 37.7749, Longitude: -122.4194, City: "San Francisco"},
 	}
 	err := invService.RegisterCar(ctx, car)
 	if err != nil {
@@ -39,7 +52,7 @@ func main() {
 
 	startAvail := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
 	endAvail := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
-	err = invService.AddAvailability(ctx, car.ID, startAvail, endAvail)
+	_, err = invService.AddAvailability(ctx, car.ID, startAvail, endAvail)
 	if err != nil {
 		panic(err)
 	}
@@ -47,18 +60,38 @@ func main() {
 
 	searchStart := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
 	searchEnd := time.Date(2026, 1, 4, 10, 0, 0, 0, time.UTC)
-	cars, err := invService.Search(ctx, models.Location{City: "San Francisco"}, 10.0, searchStart, searchEnd)
+	searchLocation := models.Location{Latitude: 37.7849, Longitude: -122.4094, City: "San Francisco"}
+	page, err := invService.Search(ctx, models.SearchQuery{
+		Location:  searchLocation,
+		RadiusKm:  10.0,
+		StartTime: searchStart,
+		EndTime:   searchEnd,
+		MaxPrice:  150,
+		SortBy:    models.SearchSortPrice,
+	})
 	if err != nil {
 		panic(err)
 	}
-	fmt.Printf("Search Found %d cars available\n", len(cars))
+	fmt.Printf("Search Found %d cars available\n", len(page.Results))
+	for _, r := range page.Results {
+		fmt.Printf("  %s %s %.2f km away\n", r.Car.Make, r.Car.Model, r.DistanceKm)
+	}
 
 	fmt.Println("Booking Car 1...")
 	booking, err := bookService.CreateBooking(ctx, user.ID, car.ID, searchStart, searchEnd)
 	if err != nil {
 		fmt.Printf("Booking failed: %v\n", err)
 	} else {
-		fmt.Printf("Booking Success! ID: %s, Price: %.2f\n", booking.CarID, booking.TotalPrice)
+		fmt.Printf("Booking Success! ID: %s, Price: %.2f\n", booking.ID, booking.TotalPrice)
+		for _, item := range booking.PriceBreakdown {
+			fmt.Printf("  %-30s %8.2f\n", item.Description, item.Amount)
+		}
+		fmt.Printf("Booking held (%s) until %s, confirming payment...\n", booking.Status, booking.HoldExpiresAt.Format(time.Kitchen))
+		booking, err = bookService.ConfirmBooking(ctx, booking.ID)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Booking %s\n", booking.Status)
 	}
 
 	fmt.Println("Attempting Double Booking...")
@@ -68,4 +101,14 @@ func main() {
 	} else {
 		fmt.Println("Error: Double Booking Allowed!")
 	}
+
+	if booking != nil {
+		fmt.Println("Renter cancelling...")
+		refund, err := bookService.CancelBooking(ctx, booking.ID, models.CancelledByRenter, user.ID)
+		if err != nil {
+			fmt.Printf("Cancellation failed: %v\n", err)
+		} else {
+			fmt.Printf("Refunded %.2f (%.0f%%): %s\n", refund.Amount, refund.Percent, refund.Reason)
+		}
+	}
 }
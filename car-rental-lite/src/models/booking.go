@@ -5,26 +5,118 @@ import "time"
 type BookingStatus string
 
 const (
-	BookingStatusPending   BookingStatus = "PENDING"
+	BookingStatusPending   BookingStatus = "PENDING" // held awaiting payment
 	BookingStatusConfirmed BookingStatus = "CONFIRMED"
-	BookingStatusCancelled BookingStatus = "CANCELLED"
+	BookingStatusActive    BookingStatus = "ACTIVE" // car picked up
 	BookingStatusCompleted BookingStatus = "COMPLETED"
+	BookingStatusCancelled BookingStatus = "CANCELLED"
+	BookingStatusExpired   BookingStatus = "EXPIRED" // hold lapsed without payment
 )
 
+// bookingTransitions is the booking state machine:
+//
+//	PENDING -> CONFIRMED -> ACTIVE -> COMPLETED
+//	PENDING -> CANCELLED | EXPIRED
+//	CONFIRMED -> CANCELLED
+var bookingTransitions = map[BookingStatus][]BookingStatus{
+	BookingStatusPending:   {BookingStatusConfirmed, BookingStatusCancelled, BookingStatusExpired},
+	BookingStatusConfirmed: {BookingStatusActive, BookingStatusCancelled},
+	BookingStatusActive:    {BookingStatusCompleted},
+}
+
+// CanTransitionTo reports whether the state machine allows s -> next.
+func (s BookingStatus) CanTransitionTo(next BookingStatus) bool {
+	for _, allowed := range bookingTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// HoldsCar reports whether a booking in this status blocks the car's
+// calendar for its window.
+func (s BookingStatus) HoldsCar() bool {
+	return s != BookingStatusCancelled && s != BookingStatusExpired
+}
+
+// IsOpen reports whether the renter still expects to get (or has) the car,
+// i.e. the booking hasn't reached a terminal state.
+func (s BookingStatus) IsOpen() bool {
+	return s == BookingStatusPending || s == BookingStatusConfirmed || s == BookingStatusActive
+}
+
 type Booking struct {
-	ID         string
-	CarID      string
-	UserID     string
-	StartTime  time.Time
-	EndTime    time.Time
-	Status     BookingStatus
-	TotalPrice float64
-	CreatedAt  time.Time
+	ID         string        `json:"id"`
+	CarID      string        `json:"car_id"`
+	UserID     string        `json:"user_id"`
+	StartTime  time.Time     `json:"start_time"`
+	EndTime    time.Time     `json:"end_time"`
+	Status     BookingStatus `json:"status"`
+	TotalPrice float64       `json:"total_price"`
+	// PriceBreakdown itemizes the price quoted at booking time, or at the
+	// latest modification.
+	PriceBreakdown []PriceLineItem `json:"price_breakdown"`
+	// Adjustments are charges added after the trip (late return, mileage,
+	// fuel). TotalPrice includes them.
+	Adjustments []PriceLineItem `json:"adjustments"`
+	// Modifications is the history of date changes, oldest first.
+	Modifications []BookingModification `json:"modifications"`
+	// HoldExpiresAt is when a PENDING booking lapses if payment hasn't
+	// confirmed it.
+	HoldExpiresAt time.Time `json:"hold_expires_at"`
+	// ConfirmedAt is when payment confirmed the booking. It stays set if the
+	// booking is later cancelled, which is how earnings tell a paid
+	// cancellation from a released hold.
+	ConfirmedAt time.Time         `json:"confirmed_at"`
+	PickedUpAt  time.Time         `json:"picked_up_at"`
+	ReturnedAt  time.Time         `json:"returned_at"`
+	CancelledBy CancellationActor `json:"cancelled_by"`
+	CancelledAt time.Time         `json:"cancelled_at"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// Clone returns a deep copy, so repositories can hand out bookings without
+// sharing state with concurrent writers.
+func (b *Booking) Clone() *Booking {
+	c := *b
+	c.PriceBreakdown = append([]PriceLineItem(nil), b.PriceBreakdown...)
+	c.Adjustments = append([]PriceLineItem(nil), b.Adjustments...)
+	c.Modifications = append([]BookingModification(nil), b.Modifications...)
+	return &c
+}
+
+// Upcoming reports whether the renter still expects to pick the car up at or
+// after t: the booking is CONFIRMED, or PENDING with its hold live at t, and
+// hasn't ended.
+func (b *Booking) Upcoming(t time.Time) bool {
+	switch b.Status {
+	case BookingStatusConfirmed:
+		return b.EndTime.After(t)
+	case BookingStatusPending:
+		return b.EndTime.After(t) && !t.After(b.HoldExpiresAt)
+	}
+	return false
+}
+
+// BookingModification records one change of a booking's dates and the
+// re-quoted price.
+type BookingModification struct {
+	ModifiedAt    time.Time `json:"modified_at"`
+	PreviousStart time.Time `json:"previous_start"`
+	PreviousEnd   time.Time `json:"previous_end"`
+	NewStart      time.Time `json:"new_start"`
+	NewEnd        time.Time `json:"new_end"`
+	PreviousPrice float64   `json:"previous_price"`
+	NewPrice      float64   `json:"new_price"`
+	// PriceDifference is NewPrice - PreviousPrice: owed by the renter when
+	// positive, due back to them when negative.
+	PriceDifference float64 `json:"price_difference"`
 }
 
 type AvailabilitySlot struct {
-	ID        string
-	CarID     string
-	StartTime time.Time
-	EndTime   time.Time
+	ID        string    `json:"id"`
+	CarID     string    `json:"car_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
 }
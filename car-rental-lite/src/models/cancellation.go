@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type CancellationPolicy string
+
+const (
+	CancellationPolicyFlexible CancellationPolicy = "FLEXIBLE"
+	CancellationPolicyModerate CancellationPolicy = "MODERATE"
+	CancellationPolicyStrict   CancellationPolicy = "STRICT"
+)
+
+type CancellationActor string
+
+const (
+	CancelledByRenter CancellationActor = "RENTER"
+	CancelledByHost   CancellationActor = "HOST"
+)
+
+// Refund records the money returned to the renter when a booking is cancelled.
+type Refund struct {
+	ID          string             `json:"id"`
+	BookingID   string             `json:"booking_id"`
+	UserID      string             `json:"user_id"`
+	Amount      float64            `json:"amount"`
+	Percent     float64            `json:"percent"` // of the booking's TotalPrice
+	CancelledBy CancellationActor  `json:"cancelled_by"`
+	Policy      CancellationPolicy `json:"policy"`
+	Reason      string             `json:"reason"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
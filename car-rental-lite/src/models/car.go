@@ -1,5 +1,10 @@
 package models
 
+import (
+	"math"
+	"time"
+)
+
 type CarType string
 
 const (
@@ -10,23 +15,91 @@ const (
 	CarTypeVan     CarType = "VAN"
 )
 
+// CarTypes lists every CarType.
+var CarTypes = []CarType{CarTypeSedan, CarTypeSUV, CarTypeCompact, CarTypeLuxury, CarTypeVan}
+
+type Amenity string
+
+const (
+	AmenityGPS           Amenity = "GPS"
+	AmenityBluetooth     Amenity = "BLUETOOTH"
+	AmenityChildSeat     Amenity = "CHILD_SEAT"
+	AmenityPetFriendly   Amenity = "PET_FRIENDLY"
+	AmenityBikeRack      Amenity = "BIKE_RACK"
+	AmenityAllWheelDrive Amenity = "ALL_WHEEL_DRIVE"
+	AmenityElectric      Amenity = "ELECTRIC"
+)
+
+// Amenities lists every Amenity.
+var Amenities = []Amenity{AmenityGPS, AmenityBluetooth, AmenityChildSeat, AmenityPetFriendly, AmenityBikeRack, AmenityAllWheelDrive, AmenityElectric}
+
 type Location struct {
-	Latitude  float64
-	Longitude float64
-	Address   string
-	City      string
-	ZipCode   string
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Address   string  `json:"address"`
+	City      string  `json:"city"`
+	ZipCode   string  `json:"zip_code"`
+	// TimeZone is the IANA name of the car's local time zone, e.g.
+	// "America/New_York"; empty means UTC. Rental days, and recurrence rules
+	// that don't name their own zone, follow its calendar.
+	TimeZone string `json:"time_zone"`
+}
+
+// TimeLocation loads the location's time zone.
+func (l Location) TimeLocation() (*time.Location, error) {
+	return LoadLocation(l.TimeZone)
+}
+
+const earthRadiusKm = 6371.0
+
+// DistanceKm returns the great-circle (haversine) distance between l and other.
+func (l Location) DistanceKm(other Location) float64 {
+	lat1 := l.Latitude * math.Pi / 180
+	lat2 := other.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (other.Longitude - l.Longitude) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
 }
 
 type Car struct {
-	ID           string
-	HostID       string
-	Make         string
-	Model        string
-	Year         int
-	Type         CarType
-	Location     Location
-	PricePerDay  float64
-	LicensePlate string
-	IsActive     bool
+	ID           string    `json:"id"`
+	HostID       string    `json:"host_id"`
+	Make         string    `json:"make"`
+	Model        string    `json:"model"`
+	Year         int       `json:"year"`
+	Type         CarType   `json:"type"`
+	Location     Location  `json:"location"`
+	PricePerDay  float64   `json:"price_per_day"`
+	PricePerHour float64   `json:"price_per_hour"` // for partial days; zero charges partial days as full days
+	LicensePlate string    `json:"license_plate"`
+	Amenities    []Amenity `json:"amenities"`
+	IsActive     bool      `json:"is_active"`
+	Rating       Rating    `json:"rating"`
+}
+
+// HasAmenities reports whether the car offers every amenity in want.
+func (c *Car) HasAmenities(want []Amenity) bool {
+	for _, w := range want {
+		found := false
+		for _, a := range c.Amenities {
+			if a == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// CarSearchResult is a car matched by a location search together with its
+// distance from the search point.
+type CarSearchResult struct {
+	Car        *Car    `json:"car"`
+	DistanceKm float64 `json:"distance_km"`
 }
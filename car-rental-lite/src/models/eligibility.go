@@ -0,0 +1,18 @@
+package models
+
+// EligibilityCode identifies why a renter may not book a car.
+type EligibilityCode string
+
+const (
+	EligibilityUnderage        EligibilityCode = "UNDERAGE"
+	EligibilityLicenseMissing  EligibilityCode = "LICENSE_MISSING"
+	EligibilityLicenseExpired  EligibilityCode = "LICENSE_EXPIRED"
+	EligibilityBanned          EligibilityCode = "BANNED"
+	EligibilityTooManyBookings EligibilityCode = "TOO_MANY_BOOKINGS"
+)
+
+// EligibilityReason is one failed eligibility check.
+type EligibilityReason struct {
+	Code    EligibilityCode `json:"code"`
+	Message string          `json:"message"`
+}
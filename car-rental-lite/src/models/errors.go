@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinels for the kinds of domain failure. Match them with errors.Is;
+// use errors.As with the structured types below for the details.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	ErrIneligible = errors.New("renter is not eligible")
+)
+
+// NotFoundError reports a lookup for an ID that doesn't exist.
+type NotFoundError struct {
+	Entity string // e.g. "booking", "availability slot"
+	ID     string
+}
+
+func (e *NotFoundError) Error() string {
+	if e.ID == "" {
+		return e.Entity + " not found"
+	}
+	return fmt.Sprintf("%s %s not found", e.Entity, e.ID)
+}
+
+func (e *NotFoundError) Is(target error) bool { return target == ErrNotFound }
+
+// ConflictError reports a request that is valid on its own but clashes with
+// the current state, e.g. an overlapping booking or a disallowed status
+// change. Err is the specific reason and is reachable through errors.Is.
+type ConflictError struct {
+	Err error
+	// BookingID is the booking that is in the way, or whose state rules the
+	// request out. Empty when the conflict isn't about a booking.
+	BookingID string
+}
+
+func (e *ConflictError) Error() string {
+	if e.BookingID == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v (booking %s)", e.Err, e.BookingID)
+}
+
+func (e *ConflictError) Unwrap() error { return e.Err }
+
+func (e *ConflictError) Is(target error) bool { return target == ErrConflict }
+
+// ValidationError reports a bad input value. Field is the snake_case name of
+// the offending field, matching its JSON tag.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string { return e.Message }
+
+func (e *ValidationError) Is(target error) bool { return target == ErrValidation }
+
+// IneligibleError reports a renter who fails one or more eligibility checks
+// for the car they tried to book.
+type IneligibleError struct {
+	UserID  string
+	Reasons []EligibilityReason
+}
+
+func (e *IneligibleError) Error() string {
+	msgs := make([]string, len(e.Reasons))
+	for i, r := range e.Reasons {
+		msgs[i] = r.Message
+	}
+	return fmt.Sprintf("user %s is not eligible: %s", e.UserID, strings.Join(msgs, "; "))
+}
+
+func (e *IneligibleError) Is(target error) bool { return target == ErrIneligible }
@@ -0,0 +1,111 @@
+package models
+
+import "time"
+
+// EventType names a domain event, e.g. for routing to subscribers.
+type EventType string
+
+const (
+	EventBookingCreated      EventType = "BOOKING_CREATED"
+	EventBookingCancelled    EventType = "BOOKING_CANCELLED"
+	EventBookingCompleted    EventType = "BOOKING_COMPLETED"
+	EventAvailabilityChanged EventType = "AVAILABILITY_CHANGED"
+	EventClaimFiled          EventType = "CLAIM_FILED"
+	EventWaitlistMatched     EventType = "WAITLIST_MATCHED"
+)
+
+// Event is something that happened in the domain. Services only emit an
+// event once the write it describes has succeeded.
+type Event interface {
+	EventType() EventType
+}
+
+// BookingCreated is emitted when a renter places a PENDING hold on a car.
+type BookingCreated struct {
+	BookingID  string    `json:"booking_id"`
+	UserID     string    `json:"user_id"`
+	CarID      string    `json:"car_id"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	TotalPrice float64   `json:"total_price"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+func (BookingCreated) EventType() EventType { return EventBookingCreated }
+
+// BookingCancelled is emitted when the renter or host cancels a booking,
+// releasing the car for [StartTime, EndTime).
+type BookingCancelled struct {
+	BookingID    string            `json:"booking_id"`
+	UserID       string            `json:"user_id"`
+	CarID        string            `json:"car_id"`
+	StartTime    time.Time         `json:"start_time"`
+	EndTime      time.Time         `json:"end_time"`
+	CancelledBy  CancellationActor `json:"cancelled_by"`
+	RefundAmount float64           `json:"refund_amount"`
+	OccurredAt   time.Time         `json:"occurred_at"`
+}
+
+func (BookingCancelled) EventType() EventType { return EventBookingCancelled }
+
+// BookingCompleted is emitted when the car is returned and the trip ends.
+type BookingCompleted struct {
+	BookingID  string    `json:"booking_id"`
+	UserID     string    `json:"user_id"`
+	CarID      string    `json:"car_id"`
+	TotalPrice float64   `json:"total_price"` // including post-trip adjustments
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+func (BookingCompleted) EventType() EventType { return EventBookingCompleted }
+
+// AvailabilityChange is what happened to a car's availability.
+type AvailabilityChange string
+
+const (
+	AvailabilityAdded       AvailabilityChange = "ADDED"
+	AvailabilityUpdated     AvailabilityChange = "UPDATED"
+	AvailabilityRemoved     AvailabilityChange = "REMOVED"
+	AvailabilityBlocked     AvailabilityChange = "BLOCKED"
+	AvailabilityRuleAdded   AvailabilityChange = "RULE_ADDED"
+	AvailabilityRuleRemoved AvailabilityChange = "RULE_REMOVED"
+)
+
+// AvailabilityChanged is emitted when a host adds, moves, removes or blocks
+// availability. StartTime and EndTime bound the affected range; for a
+// recurrence rule they're its RecurrenceRule.Span, whose end may be zero.
+type AvailabilityChanged struct {
+	CarID      string             `json:"car_id"`
+	Change     AvailabilityChange `json:"change"`
+	SlotID     string             `json:"slot_id,omitempty"`
+	RuleID     string             `json:"rule_id,omitempty"`
+	StartTime  time.Time          `json:"start_time"`
+	EndTime    time.Time          `json:"end_time"`
+	OccurredAt time.Time          `json:"occurred_at"`
+}
+
+func (AvailabilityChanged) EventType() EventType { return EventAvailabilityChanged }
+
+// ClaimFiled is emitted when a host submits a damage claim.
+type ClaimFiled struct {
+	ClaimID       string    `json:"claim_id"`
+	BookingID     string    `json:"booking_id"`
+	ClaimantID    string    `json:"claimant_id"`
+	EstimatedCost float64   `json:"estimated_cost"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+func (ClaimFiled) EventType() EventType { return EventClaimFiled }
+
+// WaitlistMatched is emitted when a car becomes bookable for a renter's
+// saved search.
+type WaitlistMatched struct {
+	SavedSearchID string    `json:"saved_search_id"`
+	UserID        string    `json:"user_id"`
+	CarID         string    `json:"car_id"`
+	StartTime     time.Time `json:"start_time"`
+	EndTime       time.Time `json:"end_time"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+func (WaitlistMatched) EventType() EventType { return EventWaitlistMatched }
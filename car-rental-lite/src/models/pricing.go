@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+type PriceComponent string
+
+const (
+	PriceComponentRental           PriceComponent = "RENTAL"
+	PriceComponentSeasonal         PriceComponent = "SEASONAL_ADJUSTMENT"
+	PriceComponentWeekendSurcharge PriceComponent = "WEEKEND_SURCHARGE"
+	PriceComponentHolidaySurcharge PriceComponent = "HOLIDAY_SURCHARGE"
+	PriceComponentDurationDiscount PriceComponent = "DURATION_DISCOUNT"
+	PriceComponentCleaningFee      PriceComponent = "CLEANING_FEE"
+	PriceComponentServiceFee       PriceComponent = "SERVICE_FEE"
+	PriceComponentTax              PriceComponent = "TAX"
+
+	// Post-trip adjustments
+	PriceComponentLateReturn     PriceComponent = "LATE_RETURN_FEE"
+	PriceComponentMileageOverage PriceComponent = "MILEAGE_OVERAGE"
+	PriceComponentFuelRefill     PriceComponent = "FUEL_REFILL"
+)
+
+// PriceLineItem is one line of an itemized price. Discounts are negative.
+type PriceLineItem struct {
+	Component   PriceComponent `json:"component"`
+	Description string         `json:"description"`
+	Amount      float64        `json:"amount"`
+}
+
+// SeasonalPrice overrides a car's daily rate between two local dates
+// (inclusive). An empty CarID applies to all of the host's cars.
+type SeasonalPrice struct {
+	ID          string    `json:"id"`
+	HostID      string    `json:"host_id"`
+	CarID       string    `json:"car_id"`
+	Name        string    `json:"name"`
+	StartDate   time.Time `json:"start_date"`
+	EndDate     time.Time `json:"end_date"`
+	PricePerDay float64   `json:"price_per_day"`
+}
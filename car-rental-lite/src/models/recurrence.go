@@ -0,0 +1,167 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+type Frequency string
+
+const (
+	FrequencyDaily  Frequency = "DAILY"
+	FrequencyWeekly Frequency = "WEEKLY"
+)
+
+// TimeWindow is a half-open [Start, End) range.
+type TimeWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// RecurrenceRule is an RRULE-style availability pattern, e.g. "every weekday
+// 08:00–20:00 until March" or "weekends only". Times of day and dates are
+// interpreted in TimeZone, so 08:00 stays 08:00 local across DST changes.
+type RecurrenceRule struct {
+	ID        string         `json:"id"`
+	CarID     string         `json:"car_id"`
+	Frequency Frequency      `json:"frequency"`
+	Interval  int            `json:"interval"`   // every N days/weeks; 0 or 1 means every one
+	ByWeekday []time.Weekday `json:"by_weekday"` // WEEKLY only; empty means the weekday of StartDate
+
+	// StartOfDay and EndOfDay are offsets from local midnight. An EndOfDay
+	// of 24h runs to the following midnight, so consecutive days join up.
+	StartOfDay time.Duration `json:"start_of_day"`
+	EndOfDay   time.Duration `json:"end_of_day"`
+
+	StartDate  time.Time   `json:"start_date"` // first local date the rule applies
+	Until      time.Time   `json:"until"`      // last local date the rule applies; zero means no end
+	Exceptions []time.Time `json:"exceptions"` // local dates on which the rule doesn't apply
+	TimeZone   string      `json:"time_zone"`  // IANA name, e.g. "America/Los_Angeles"; empty means UTC
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+var locationCache sync.Map
+
+// LoadLocation is time.LoadLocation with a process-wide cache, since rules
+// are expanded on every availability check.
+func LoadLocation(name string) (*time.Location, error) {
+	if loc, ok := locationCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locationCache.Store(name, loc)
+	return loc, nil
+}
+
+func (r *RecurrenceRule) Location() (*time.Location, error) {
+	return LoadLocation(r.TimeZone)
+}
+
+// Span returns the range the rule's occurrences can fall in: from local
+// midnight on StartDate to the local midnight after Until. End is zero when
+// the rule has no end.
+func (r *RecurrenceRule) Span() (TimeWindow, error) {
+	loc, err := r.Location()
+	if err != nil {
+		return TimeWindow{}, err
+	}
+	y, m, d := r.StartDate.Date()
+	w := TimeWindow{Start: time.Date(y, m, d, 0, 0, 0, 0, loc)}
+	if !r.Until.IsZero() {
+		y, m, d = r.Until.Date()
+		w.End = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	}
+	return w, nil
+}
+
+// civilDay numbers a calendar date independently of time zone and DST.
+func civilDay(y int, m time.Month, d int) int64 {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400
+}
+
+func civilDayOf(t time.Time) int64 {
+	return civilDay(t.Date())
+}
+
+func (r *RecurrenceRule) appliesOn(date time.Time) bool {
+	day := civilDayOf(date)
+	first := civilDayOf(r.StartDate)
+	if day < first {
+		return false
+	}
+	if !r.Until.IsZero() && day > civilDayOf(r.Until) {
+		return false
+	}
+	for _, ex := range r.Exceptions {
+		if civilDayOf(ex) == day {
+			return false
+		}
+	}
+
+	interval := int64(r.Interval)
+	if interval < 1 {
+		interval = 1
+	}
+	switch r.Frequency {
+	case FrequencyDaily:
+		return (day-first)%interval == 0
+	case FrequencyWeekly:
+		weekdays := r.ByWeekday
+		if len(weekdays) == 0 {
+			weekdays = []time.Weekday{r.StartDate.Weekday()}
+		}
+		match := false
+		for _, wd := range weekdays {
+			if date.Weekday() == wd {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+		// Weeks are counted from the Monday on or before StartDate.
+		// 1970-01-01 (day 0) was a Thursday, so day+3 is Monday-aligned.
+		return ((day+3)/7-(first+3)/7)%interval == 0
+	}
+	return false
+}
+
+// Occurrences expands the rule into the windows that overlap [from, to),
+// in chronological order.
+func (r *RecurrenceRule) Occurrences(from, to time.Time) ([]TimeWindow, error) {
+	loc, err := r.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	// Occurrences never extend past the following local midnight, so nothing
+	// before from's local date can overlap the range.
+	y, m, d := from.In(loc).Date()
+	cur := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	last := civilDayOf(to.In(loc))
+
+	var res []TimeWindow
+	for ; civilDayOf(cur) <= last; cur = time.Date(cur.Year(), cur.Month(), cur.Day()+1, 0, 0, 0, 0, loc) {
+		if !r.appliesOn(cur) {
+			continue
+		}
+		w := TimeWindow{Start: atOffset(cur, r.StartOfDay, loc), End: atOffset(cur, r.EndOfDay, loc)}
+		if w.Start.Before(to) && w.End.After(from) {
+			res = append(res, w)
+		}
+	}
+	return res, nil
+}
+
+// atOffset returns the wall-clock time offset from midnight of date's local
+// day, e.g. 08:00 regardless of whether the day is 23, 24 or 25 hours long.
+func atOffset(date time.Time, offset time.Duration, loc *time.Location) time.Time {
+	h := int(offset / time.Hour)
+	min := int(offset % time.Hour / time.Minute)
+	sec := int(offset % time.Minute / time.Second)
+	return time.Date(date.Year(), date.Month(), date.Day(), h, min, sec, 0, loc)
+}
@@ -10,15 +10,25 @@ const (
 )
 
 type InspectionReport struct {
-	ID          string
-	BookingID   string
-	InspectorID string // UserID or HostID depending on who does it
-	Type        InspectionType
-	Notes       string
-	ImageURLs   []string
-	FuelLevel   float64 // Percentage 0-100
-	Odometer    int
-	CreatedAt   time.Time
+	ID          string         `json:"id"`
+	BookingID   string         `json:"booking_id"`
+	InspectorID string         `json:"inspector_id"` // UserID or HostID depending on who does it
+	Type        InspectionType `json:"type"`
+	Notes       string         `json:"notes"`
+	ImageURLs   []string       `json:"image_urls"`
+	FuelLevel   float64        `json:"fuel_level"` // Percentage 0-100
+	Odometer    int            `json:"odometer"`
+	// Computed on DROPOFF reports relative to the booking's PICKUP report.
+	FuelDelta float64   `json:"fuel_delta"` // percentage points; negative means fuel was used
+	Mileage   int       `json:"mileage"`    // odometer distance driven
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Clone returns a deep copy.
+func (r *InspectionReport) Clone() *InspectionReport {
+	c := *r
+	c.ImageURLs = append([]string(nil), r.ImageURLs...)
+	return &c
 }
 
 type ClaimStatus string
@@ -30,12 +40,37 @@ const (
 	ClaimStatusRejected    ClaimStatus = "REJECTED"
 )
 
+// claimTransitions: SUBMITTED -> UNDER_REVIEW -> APPROVED | REJECTED.
+var claimTransitions = map[ClaimStatus][]ClaimStatus{
+	ClaimStatusSubmitted:   {ClaimStatusUnderReview},
+	ClaimStatusUnderReview: {ClaimStatusApproved, ClaimStatusRejected},
+}
+
+// CanTransitionTo reports whether the claim workflow allows s -> next.
+func (s ClaimStatus) CanTransitionTo(next ClaimStatus) bool {
+	for _, allowed := range claimTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
 type DamageClaim struct {
-	ID                string
-	BookingID         string
-	Description       string
-	EvidenceImageURLs []string
-	EstimatedCost     float64
-	Status            ClaimStatus
-	CreatedAt         time.Time
+	ID                string      `json:"id"`
+	BookingID         string      `json:"booking_id"`
+	ClaimantID        string      `json:"claimant_id"` // host filing the claim
+	Description       string      `json:"description"`
+	EvidenceImageURLs []string    `json:"evidence_image_urls"`
+	EstimatedCost     float64     `json:"estimated_cost"`
+	Status            ClaimStatus `json:"status"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+}
+
+// Clone returns a deep copy.
+func (c *DamageClaim) Clone() *DamageClaim {
+	cl := *c
+	cl.EvidenceImageURLs = append([]string(nil), c.EvidenceImageURLs...)
+	return &cl
 }
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// ReviewerRole is which side of a booking wrote a review. A renter rates the
+// car and its host; a host rates the renter.
+type ReviewerRole string
+
+const (
+	ReviewerRenter ReviewerRole = "RENTER"
+	ReviewerHost   ReviewerRole = "HOST"
+)
+
+// Review is one party's feedback on a completed booking. Reviews are
+// double-blind: each stays hidden from the other party, and out of the
+// aggregates, until PublishedAt is set, which happens once both sides have
+// reviewed or at RevealAt, when the review window closes.
+type Review struct {
+	ID          string       `json:"id"`
+	BookingID   string       `json:"booking_id"`
+	CarID       string       `json:"car_id"`
+	HostID      string       `json:"host_id"`
+	UserID      string       `json:"user_id"` // the renter
+	Role        ReviewerRole `json:"role"`
+	Rating      int          `json:"rating"` // 1-5
+	Comment     string       `json:"comment"`
+	CreatedAt   time.Time    `json:"created_at"`
+	RevealAt    time.Time    `json:"reveal_at"`
+	PublishedAt time.Time    `json:"published_at"`
+}
+
+func (r *Review) Published() bool {
+	return !r.PublishedAt.IsZero()
+}
+
+// Rating aggregates the published review scores for a car, host or renter.
+type Rating struct {
+	Average float64 `json:"average"`
+	Count   int     `json:"count"`
+}
+
+// Add returns the aggregate with one more score folded in.
+func (r Rating) Add(score int) Rating {
+	return Rating{
+		Average: (r.Average*float64(r.Count) + float64(score)) / float64(r.Count+1),
+		Count:   r.Count + 1,
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+type SearchSort string
+
+const (
+	SearchSortDistance SearchSort = "DISTANCE"
+	SearchSortPrice    SearchSort = "PRICE"
+	SearchSortNewest   SearchSort = "NEWEST"
+	SearchSortRating   SearchSort = "RATING" // best-rated first; unrated cars last
+	// SearchSortHostRating orders by the host's rating across all their cars,
+	// best first; cars of unrated hosts come last.
+	SearchSortHostRating SearchSort = "HOST_RATING"
+)
+
+// SearchQuery describes a renter's search. Zero values mean "no filter".
+type SearchQuery struct {
+	Location  Location  `json:"location"`
+	RadiusKm  float64   `json:"radius_km"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+
+	MinPrice  float64   `json:"min_price"` // per day
+	MaxPrice  float64   `json:"max_price"` // per day
+	CarTypes  []CarType `json:"car_types"`
+	Make      string    `json:"make"`
+	Model     string    `json:"model"`
+	MinYear   int       `json:"min_year"`
+	HostID    string    `json:"host_id"`
+	Amenities []Amenity `json:"amenities"` // car must offer all of them
+
+	SortBy SearchSort `json:"sort_by"` // defaults to SearchSortDistance
+	Limit  int        `json:"limit"`   // page size, defaults to 20
+	Cursor string     `json:"cursor"`  // NextCursor from the previous page
+}
+
+type SearchPage struct {
+	Results    []*CarSearchResult `json:"results"`
+	NextCursor string             `json:"next_cursor"` // empty on the last page
+}
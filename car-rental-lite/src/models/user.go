@@ -3,18 +3,34 @@ package models
 import "time"
 
 type User struct {
-	ID            string
-	Name          string
-	Email         string
-	PhoneNumber   string
-	DriverLicense string
-	CreatedAt     time.Time
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	Email            string    `json:"email"`
+	PhoneNumber      string    `json:"phone_number"`
+	DriverLicense    string    `json:"driver_license"`
+	LicenseExpiresAt time.Time `json:"license_expires_at"`
+	DateOfBirth      time.Time `json:"date_of_birth"`
+	Banned           bool      `json:"banned"`
+	Rating           Rating    `json:"rating"` // from hosts' reviews
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// AgeOn is the user's age in whole years on day t.
+func (u *User) AgeOn(t time.Time) int {
+	dob := u.DateOfBirth
+	age := t.Year() - dob.Year()
+	if t.Month() < dob.Month() || (t.Month() == dob.Month() && t.Day() < dob.Day()) {
+		age--
+	}
+	return age
 }
 
 type Host struct {
-	ID          string
-	Name        string
-	Email       string
-	PhoneNumber string
-	CreatedAt   time.Time
+	ID                 string             `json:"id"`
+	Name               string             `json:"name"`
+	Email              string             `json:"email"`
+	PhoneNumber        string             `json:"phone_number"`
+	CancellationPolicy CancellationPolicy `json:"cancellation_policy"` // empty means FLEXIBLE
+	Rating             Rating             `json:"rating"`              // from renters' reviews of all the host's cars
+	CreatedAt          time.Time          `json:"created_at"`
 }
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type SavedSearchStatus string
+
+const (
+	SavedSearchActive    SavedSearchStatus = "ACTIVE"
+	SavedSearchMatched   SavedSearchStatus = "MATCHED"
+	SavedSearchCancelled SavedSearchStatus = "CANCELLED"
+	// SavedSearchExpired is a search whose window started without a match.
+	SavedSearchExpired SavedSearchStatus = "EXPIRED"
+)
+
+// SavedSearch is a renter's waitlist entry: a search kept so the renter can
+// be told when a matching car frees up for the whole window. Only Query's
+// filters are used; its sort and paging fields are ignored. A search
+// matches at most once.
+type SavedSearch struct {
+	ID        string            `json:"id"`
+	UserID    string            `json:"user_id"`
+	Query     SearchQuery       `json:"query"`
+	Status    SavedSearchStatus `json:"status"`
+	CreatedAt time.Time         `json:"created_at"`
+	// ClosedAt is when the search left ACTIVE; MatchedCarID is set if it
+	// matched.
+	ClosedAt     time.Time `json:"closed_at"`
+	MatchedCarID string    `json:"matched_car_id,omitempty"`
+}
+
+// Clone returns a deep copy.
+func (s *SavedSearch) Clone() *SavedSearch {
+	c := *s
+	c.Query.CarTypes = append([]CarType(nil), s.Query.CarTypes...)
+	c.Query.Amenities = append([]Amenity(nil), s.Query.Amenities...)
+	return &c
+}
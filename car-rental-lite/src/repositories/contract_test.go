@@ -0,0 +1,642 @@
+package repositories_test
+
+import (
+	"car-rental-lite/src/database"
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// contractRepo is what every repository implementation has to provide; the
+// same suite runs against each of them.
+type contractRepo interface {
+	repositories.UserRepository
+	repositories.CarRepository
+	repositories.BookingRepository
+	repositories.InventoryRepository
+	repositories.ReviewRepository
+}
+
+func TestInMemoryRepoContract(t *testing.T) {
+	runRepoContract(t, func(t *testing.T) contractRepo {
+		return repositories.NewInMemoryRepo()
+	})
+}
+
+func TestSQLRepoContract(t *testing.T) {
+	runRepoContract(t, func(t *testing.T) contractRepo {
+		return repositories.NewSQLRepo(openTestDB(t, filepath.Join(t.TempDir(), "rental.db")))
+	})
+}
+
+// TestSQLRepoReserveAcrossConnections races two repos with separate
+// connection pools on one database file, as two server processes would.
+func TestSQLRepoReserveAcrossConnections(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "rental.db")
+	first := repositories.NewSQLRepo(openTestDB(t, path))
+	second := repositories.NewSQLRepo(openTestDB(t, path))
+	seedCar(t, first, "car1")
+
+	const renters = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, renters)
+	for i := 0; i < renters; i++ {
+		repo := first
+		if i%2 == 1 {
+			repo = second
+		}
+		wg.Add(1)
+		go func(i int, repo contractRepo) {
+			defer wg.Done()
+			errs <- repo.ReserveBooking(ctx, &models.Booking{CarID: "car1", UserID: fmt.Sprintf("user%d", i), StartTime: jan(2), EndTime: jan(4), Status: models.BookingStatusConfirmed}, nil)
+		}(i, repo)
+	}
+	wg.Wait()
+	close(errs)
+
+	confirmed := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			confirmed++
+		case !errors.Is(err, repositories.ErrOverlap):
+			t.Errorf("Expected ErrOverlap, got %v", err)
+		}
+	}
+	if confirmed != 1 {
+		t.Fatalf("Expected exactly 1 confirmed booking, got %d", confirmed)
+	}
+}
+
+func openTestDB(t *testing.T, path string) *gorm.DB {
+	t.Helper()
+	db, err := database.InitDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func jan(d int) time.Time {
+	return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC)
+}
+
+// seedCar creates an active car in San Francisco, available for January 2026.
+var errLimit = errors.New("renter has too many bookings")
+
+func seedCar(t *testing.T, repo contractRepo, carID string) {
+	t.Helper()
+	ctx := context.Background()
+	car := &models.Car{ID: carID, HostID: "host1", PricePerDay: 100, IsActive: true, Location: models.Location{Latitude: 37.7749, Longitude: -122.4194, City: "San Francisco"}}
+	if err := repo.CreateCar(ctx, car); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.AddAvailability(ctx, &models.AvailabilitySlot{CarID: carID, StartTime: jan(1), EndTime: jan(31)}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runRepoContract(t *testing.T, newRepo func(t *testing.T) contractRepo) {
+	ctx := context.Background()
+
+	t.Run("UsersAndHosts", func(t *testing.T) {
+		repo := newRepo(t)
+		user := &models.User{Name: "Alice", Email: "alice@example.com", DriverLicense: "D123", LicenseExpiresAt: jan(31), DateOfBirth: time.Date(1990, 6, 15, 0, 0, 0, 0, time.UTC), Banned: true}
+		if err := repo.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		if user.ID == "" {
+			t.Fatal("Expected CreateUser to assign an ID")
+		}
+		got, err := repo.GetUser(ctx, user.ID)
+		if err != nil || got.Email != "alice@example.com" || got.DriverLicense != "D123" || !got.LicenseExpiresAt.Equal(jan(31)) || got.AgeOn(jan(1)) != 35 || !got.Banned {
+			t.Errorf("Expected the stored user back, got %+v, %v", got, err)
+		}
+		if err := repo.CreateHost(ctx, &models.Host{ID: "host1", CancellationPolicy: models.CancellationPolicyStrict}); err != nil {
+			t.Fatal(err)
+		}
+		if h, err := repo.GetHost(ctx, "host1"); err != nil || h.CancellationPolicy != models.CancellationPolicyStrict {
+			t.Errorf("Expected STRICT host, got %+v, %v", h, err)
+		}
+		_, err = repo.GetUser(ctx, "missing")
+		var nf *models.NotFoundError
+		if !errors.Is(err, repositories.ErrNotFound) || !errors.As(err, &nf) || nf.Entity != "user" || nf.ID != "missing" {
+			t.Errorf("Expected a NotFoundError for user missing, got %v", err)
+		}
+	})
+
+	t.Run("CarsAndSearch", func(t *testing.T) {
+		repo := newRepo(t)
+		sf := models.Location{Latitude: 37.7749, Longitude: -122.4194}
+		cars := []*models.Car{
+			{ID: "near", HostID: "host1", IsActive: true, Amenities: []models.Amenity{models.AmenityGPS}, Location: models.Location{Latitude: 37.78, Longitude: -122.42, TimeZone: "America/Los_Angeles"}},
+			{ID: "oakland", HostID: "host1", IsActive: true, Location: models.Location{Latitude: 37.8044, Longitude: -122.2712}},
+			{ID: "inactive", HostID: "host2", IsActive: false, Location: sf},
+			{ID: "la", HostID: "host2", IsActive: true, Location: models.Location{Latitude: 34.0522, Longitude: -118.2437}},
+		}
+		for _, c := range cars {
+			if err := repo.CreateCar(ctx, c); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := repo.GetCar(ctx, "near")
+		if err != nil || len(got.Amenities) != 1 || got.Location.Latitude != 37.78 || got.Location.TimeZone != "America/Los_Angeles" {
+			t.Errorf("Expected the stored car back, got %+v, %v", got, err)
+		}
+		if byHost, err := repo.GetCarsByHost(ctx, "host1"); err != nil || len(byHost) != 2 {
+			t.Errorf("Expected 2 cars for host1, got %d, %v", len(byHost), err)
+		}
+
+		results, err := repo.SearchCars(ctx, sf, 25)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 || results[0].Car.ID != "near" || results[1].Car.ID != "oakland" {
+			t.Fatalf("Expected [near oakland], got %v", carIDs(results))
+		}
+		if all, err := repo.SearchCars(ctx, sf, 0); err != nil || len(all) != 3 {
+			t.Errorf("Expected every active car without a radius, got %v, %v", carIDs(all), err)
+		}
+	})
+
+	t.Run("ReserveBooking", func(t *testing.T) {
+		repo := newRepo(t)
+		seedCar(t, repo, "car1")
+		// A second slot touching the first lets a booking span both.
+		if err := repo.AddAvailability(ctx, &models.AvailabilitySlot{CarID: "car1", StartTime: jan(31), EndTime: jan(31).AddDate(0, 0, 5)}); err != nil {
+			t.Fatal(err)
+		}
+
+		b := &models.Booking{CarID: "car1", UserID: "user1", StartTime: jan(2), EndTime: jan(4), Status: models.BookingStatusPending, TotalPrice: 200,
+			PriceBreakdown: []models.PriceLineItem{{Component: models.PriceComponentRental, Amount: 200}}}
+		if err := repo.ReserveBooking(ctx, b, nil); err != nil {
+			t.Fatal(err)
+		}
+		if b.ID == "" {
+			t.Fatal("Expected ReserveBooking to assign an ID")
+		}
+		stored, err := repo.GetBooking(ctx, b.ID)
+		if err != nil || !stored.StartTime.Equal(jan(2)) || len(stored.PriceBreakdown) != 1 {
+			t.Errorf("Expected the stored booking back, got %+v, %v", stored, err)
+		}
+
+		overlapping := &models.Booking{CarID: "car1", StartTime: jan(3), EndTime: jan(5), Status: models.BookingStatusPending}
+		err = repo.ReserveBooking(ctx, overlapping, nil)
+		var conflict *models.ConflictError
+		if !errors.Is(err, repositories.ErrOverlap) || !errors.Is(err, models.ErrConflict) {
+			t.Errorf("Expected an ErrOverlap conflict, got %v", err)
+		} else if errors.As(err, &conflict); conflict.BookingID != b.ID {
+			t.Errorf("Expected the conflict to name booking %s, got %q", b.ID, conflict.BookingID)
+		}
+		outside := &models.Booking{CarID: "car1", StartTime: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), EndTime: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)}
+		if err := repo.ReserveBooking(ctx, outside, nil); !errors.Is(err, repositories.ErrNoAvailability) {
+			t.Errorf("Expected ErrNoAvailability, got %v", err)
+		}
+		spanning := &models.Booking{CarID: "car1", StartTime: jan(30), EndTime: jan(31).AddDate(0, 0, 2), Status: models.BookingStatusPending}
+		if err := repo.ReserveBooking(ctx, spanning, nil); err != nil {
+			t.Errorf("Expected adjacent slots to cover the booking, got %v", err)
+		}
+		back2back := &models.Booking{CarID: "car1", UserID: "user1", StartTime: jan(4), EndTime: jan(6), Status: models.BookingStatusPending}
+		if err := repo.ReserveBooking(ctx, back2back, nil); err != nil {
+			t.Errorf("Expected a booking starting at the previous end to succeed, got %v", err)
+		}
+
+		forCar, err := repo.GetBookingsForCar(ctx, "car1", jan(1), jan(10))
+		if err != nil || len(forCar) != 2 || forCar[0].ID != b.ID {
+			t.Errorf("Expected the two early bookings in start order, got %d, %v", len(forCar), err)
+		}
+		forUser, err := repo.GetBookingsForUser(ctx, "user1", jan(3), jan(31))
+		if err != nil || len(forUser) != 2 || forUser[0].ID != b.ID || forUser[1].ID != back2back.ID {
+			t.Errorf("Expected user1's two bookings in start order, got %d, %v", len(forUser), err)
+		}
+		if overlap, err := repo.HasOverlappingBooking(ctx, "car1", jan(5), jan(7)); err != nil || !overlap {
+			t.Errorf("Expected an overlap on Jan 5-7, got %v, %v", overlap, err)
+		}
+	})
+
+	t.Run("ModifyBooking", func(t *testing.T) {
+		repo := newRepo(t)
+		seedCar(t, repo, "car1")
+		b := &models.Booking{CarID: "car1", UserID: "user1", StartTime: jan(2), EndTime: jan(4), Status: models.BookingStatusConfirmed, TotalPrice: 200}
+		other := &models.Booking{CarID: "car1", StartTime: jan(6), EndTime: jan(8), Status: models.BookingStatusConfirmed}
+		for _, booking := range []*models.Booking{b, other} {
+			if err := repo.ReserveBooking(ctx, booking, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		items := []models.PriceLineItem{{Component: models.PriceComponentRental, Amount: 300}}
+		mod := &models.BookingModification{ModifiedAt: jan(1), NewStart: jan(3), NewEnd: jan(6), NewPrice: 300}
+		if err := repo.ModifyBooking(ctx, b.ID, mod, items, nil); err != nil {
+			t.Fatal(err)
+		}
+		if !mod.PreviousStart.Equal(jan(2)) || mod.PreviousPrice != 200 || mod.PriceDifference != 100 {
+			t.Errorf("Expected the previous window and +100 filled in, got %+v", mod)
+		}
+		got, _ := repo.GetBooking(ctx, b.ID)
+		if !got.StartTime.Equal(jan(3)) || !got.EndTime.Equal(jan(6)) || got.TotalPrice != 300 || len(got.PriceBreakdown) != 1 || got.PriceBreakdown[0].Amount != 300 {
+			t.Errorf("Expected Jan 3-6 at 300, got %v-%v at %v", got.StartTime, got.EndTime, got.TotalPrice)
+		}
+		if len(got.Modifications) != 1 || got.Modifications[0].PriceDifference != 100 {
+			t.Errorf("Expected the modification in the history, got %+v", got.Modifications)
+		}
+		if overlap, _ := repo.HasOverlappingBooking(ctx, "car1", jan(2), jan(3)); overlap {
+			t.Error("Expected Jan 2 to be released")
+		}
+
+		var conflict *models.ConflictError
+		err := repo.ModifyBooking(ctx, b.ID, &models.BookingModification{NewStart: jan(3), NewEnd: jan(7)}, nil, nil)
+		if !errors.Is(err, repositories.ErrOverlap) || !errors.As(err, &conflict) || conflict.BookingID != other.ID {
+			t.Errorf("Expected an overlap with %s, got %v", other.ID, err)
+		}
+		if err := repo.ModifyBooking(ctx, b.ID, &models.BookingModification{NewStart: jan(30), NewEnd: jan(33)}, nil, nil); !errors.Is(err, repositories.ErrNoAvailability) {
+			t.Errorf("Expected ErrNoAvailability, got %v", err)
+		}
+		if err := repo.UpdateBookingStatus(ctx, b.ID, models.BookingStatusActive); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.ModifyBooking(ctx, b.ID, &models.BookingModification{NewStart: jan(2), NewEnd: jan(6)}, nil, nil); !errors.Is(err, repositories.ErrInvalidTransition) {
+			t.Errorf("Expected moving an ACTIVE booking's start to fail, got %v", err)
+		}
+		if err := repo.ModifyBooking(ctx, "missing", mod, nil, nil); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("SetCarActive", func(t *testing.T) {
+		repo := newRepo(t)
+		seedCar(t, repo, "car1")
+		// A lapsed hold and a finished trip don't block deactivation.
+		lapsed := &models.Booking{CarID: "car1", StartTime: jan(10), EndTime: jan(12), Status: models.BookingStatusPending, HoldExpiresAt: jan(1)}
+		done := &models.Booking{CarID: "car1", StartTime: jan(2), EndTime: jan(4), Status: models.BookingStatusCompleted}
+		upcoming := &models.Booking{CarID: "car1", StartTime: jan(6), EndTime: jan(8), Status: models.BookingStatusConfirmed}
+		for _, b := range []*models.Booking{lapsed, done, upcoming} {
+			if err := repo.ReserveBooking(ctx, b, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if got, err := repo.GetUpcomingBookingsForCar(ctx, "car1", jan(5)); err != nil || len(got) != 1 || got[0].ID != upcoming.ID {
+			t.Errorf("Expected only the confirmed booking to be upcoming, got %v, %v", got, err)
+		}
+		var conflict *models.ConflictError
+		err := repo.SetCarActive(ctx, "car1", false, jan(5))
+		if !errors.Is(err, repositories.ErrCarHasBookings) || !errors.As(err, &conflict) || conflict.BookingID != upcoming.ID {
+			t.Fatalf("Expected ErrCarHasBookings for the upcoming booking, got %v", err)
+		}
+		if err := repo.UpdateBookingStatus(ctx, upcoming.ID, models.BookingStatusCancelled); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.SetCarActive(ctx, "car1", false, jan(5)); err != nil {
+			t.Fatal(err)
+		}
+		if car, _ := repo.GetCar(ctx, "car1"); car.IsActive {
+			t.Error("Expected the car to be inactive")
+		}
+		if err := repo.ReserveBooking(ctx, &models.Booking{CarID: "car1", StartTime: jan(20), EndTime: jan(22), Status: models.BookingStatusPending}, nil); !errors.Is(err, repositories.ErrCarInactive) {
+			t.Errorf("Expected ErrCarInactive reserving an inactive car, got %v", err)
+		}
+		if err := repo.SetCarActive(ctx, "car1", true, jan(5)); err != nil {
+			t.Fatal(err)
+		}
+		if car, _ := repo.GetCar(ctx, "car1"); !car.IsActive {
+			t.Error("Expected the car to be active again")
+		}
+		if err := repo.SetCarActive(ctx, "missing", true, jan(5)); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("ConcurrentReserve", func(t *testing.T) {
+		repo := newRepo(t)
+		seedCar(t, repo, "car1")
+		const renters = 50
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		confirmed := 0
+		for i := 0; i < renters; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				err := repo.ReserveBooking(ctx, &models.Booking{CarID: "car1", UserID: fmt.Sprintf("user%d", i), StartTime: jan(2).Add(time.Duration(i%5) * time.Hour), EndTime: jan(4), Status: models.BookingStatusConfirmed}, nil)
+				switch {
+				case err == nil:
+					mu.Lock()
+					confirmed++
+					mu.Unlock()
+				case !errors.Is(err, repositories.ErrOverlap):
+					t.Errorf("Expected ErrOverlap, got %v", err)
+				}
+			}(i)
+		}
+		wg.Wait()
+		if confirmed != 1 {
+			t.Fatalf("Expected exactly 1 confirmed booking, got %d", confirmed)
+		}
+	})
+
+	t.Run("ConcurrentReserveWithBookingsCheck", func(t *testing.T) {
+		repo := newRepo(t)
+		const cars = 20
+		for i := 0; i < cars; i++ {
+			seedCar(t, repo, fmt.Sprintf("car%d", i))
+		}
+		// One renter races for every car; the check allows two bookings.
+		atMostTwo := func(others []*models.Booking) error {
+			if len(others) >= 2 {
+				return errLimit
+			}
+			return nil
+		}
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		reserved := 0
+		for i := 0; i < cars; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				err := repo.ReserveBooking(ctx, &models.Booking{CarID: fmt.Sprintf("car%d", i), UserID: "user1", StartTime: jan(2), EndTime: jan(4), Status: models.BookingStatusConfirmed}, atMostTwo)
+				switch {
+				case err == nil:
+					mu.Lock()
+					reserved++
+					mu.Unlock()
+				case !errors.Is(err, errLimit):
+					t.Errorf("Expected the check's error, got %v", err)
+				}
+			}(i)
+		}
+		wg.Wait()
+		if reserved != 2 {
+			t.Fatalf("Expected exactly 2 bookings for the renter, got %d", reserved)
+		}
+
+		// Moving a booking onto the others' dates is checked the same way.
+		seedCar(t, repo, "spare")
+		spare := &models.Booking{CarID: "spare", UserID: "user1", StartTime: jan(10), EndTime: jan(12), Status: models.BookingStatusConfirmed}
+		if err := repo.ReserveBooking(ctx, spare, atMostTwo); err != nil {
+			t.Fatal(err)
+		}
+		err := repo.ModifyBooking(ctx, spare.ID, &models.BookingModification{NewStart: jan(3), NewEnd: jan(5)}, nil, atMostTwo)
+		if !errors.Is(err, errLimit) {
+			t.Errorf("Expected the modification to be refused by the check, got %v", err)
+		}
+	})
+
+	t.Run("StatusTransitions", func(t *testing.T) {
+		repo := newRepo(t)
+		seedCar(t, repo, "car1")
+		b := &models.Booking{CarID: "car1", UserID: "user1", StartTime: jan(2), EndTime: jan(4), Status: models.BookingStatusPending, TotalPrice: 200}
+		if err := repo.ReserveBooking(ctx, b, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.UpdateBookingStatus(ctx, b.ID, models.BookingStatusCompleted); !errors.Is(err, repositories.ErrInvalidTransition) {
+			t.Errorf("Expected ErrInvalidTransition for PENDING -> COMPLETED, got %v", err)
+		}
+		if err := repo.MarkConfirmed(ctx, b.ID, jan(1)); err != nil {
+			t.Fatal(err)
+		}
+		if pending, err := repo.ListBookingsByStatus(ctx, models.BookingStatusConfirmed); err != nil || len(pending) != 1 {
+			t.Errorf("Expected 1 confirmed booking, got %d, %v", len(pending), err)
+		}
+		confirmed, err := repo.GetBooking(ctx, b.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if confirmed.Status != models.BookingStatusConfirmed || !confirmed.ConfirmedAt.Equal(jan(1)) {
+			t.Errorf("Expected CONFIRMED on Jan 1, got %s at %v", confirmed.Status, confirmed.ConfirmedAt)
+		}
+	})
+
+	t.Run("CancelBooking", func(t *testing.T) {
+		repo := newRepo(t)
+		seedCar(t, repo, "car1")
+		b := &models.Booking{CarID: "car1", UserID: "user1", StartTime: jan(2), EndTime: jan(4), Status: models.BookingStatusConfirmed, TotalPrice: 200}
+		if err := repo.ReserveBooking(ctx, b, nil); err != nil {
+			t.Fatal(err)
+		}
+		// The refund is computed from the booking as it stands inside the
+		// cancellation, after the price change below.
+		if err := repo.ModifyBooking(ctx, b.ID, &models.BookingModification{NewStart: jan(2), NewEnd: jan(4), NewPrice: 250}, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		halfRefund := func(current *models.Booking) *models.Refund {
+			return &models.Refund{BookingID: current.ID, UserID: current.UserID, Amount: current.TotalPrice / 2, Percent: 50, CancelledBy: models.CancelledByRenter, CreatedAt: jan(1)}
+		}
+		if err := repo.CancelBooking(ctx, b.ID, models.CancelledByRenter, halfRefund); err != nil {
+			t.Fatal(err)
+		}
+		called := false
+		again := func(current *models.Booking) *models.Refund {
+			called = true
+			return &models.Refund{BookingID: current.ID, Amount: 100, CreatedAt: jan(1)}
+		}
+		if err := repo.CancelBooking(ctx, b.ID, models.CancelledByRenter, again); !errors.Is(err, repositories.ErrInvalidTransition) || called {
+			t.Errorf("Expected a second cancellation to fail before computing a refund, got %v (computed: %v)", err, called)
+		}
+		refunds, err := repo.GetRefundsForBooking(ctx, b.ID)
+		if err != nil || len(refunds) != 1 || refunds[0].Amount != 125 {
+			t.Fatalf("Expected exactly one refund of 125, got %v, %v", refunds, err)
+		}
+		cancelled, _ := repo.GetBooking(ctx, b.ID)
+		if cancelled.CancelledBy != models.CancelledByRenter {
+			t.Errorf("Expected CancelledBy RENTER, got %q", cancelled.CancelledBy)
+		}
+		rebook := &models.Booking{CarID: "car1", StartTime: jan(2), EndTime: jan(4), Status: models.BookingStatusPending}
+		if err := repo.ReserveBooking(ctx, rebook, nil); err != nil {
+			t.Errorf("Expected cancelled dates to be bookable again, got %v", err)
+		}
+	})
+
+	t.Run("ReplaceAvailability", func(t *testing.T) {
+		repo := newRepo(t)
+		seedCar(t, repo, "car1")
+		slots, err := repo.GetAvailability(ctx, "car1", jan(1), jan(2))
+		if err != nil || len(slots) != 1 {
+			t.Fatalf("Expected 1 slot, got %d, %v", len(slots), err)
+		}
+		if got, err := repo.GetAvailabilitySlot(ctx, slots[0].ID); err != nil || !got.EndTime.Equal(jan(31)) {
+			t.Errorf("Expected the stored slot back, got %+v, %v", got, err)
+		}
+		b := &models.Booking{CarID: "car1", StartTime: jan(10), EndTime: jan(12), Status: models.BookingStatusConfirmed}
+		if err := repo.ReserveBooking(ctx, b, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// Blocking Jan 11 would strand the booking.
+		split := []*models.AvailabilitySlot{
+			{CarID: "car1", StartTime: jan(1), EndTime: jan(11)},
+			{CarID: "car1", StartTime: jan(12), EndTime: jan(31)},
+		}
+		if err := repo.ReplaceAvailability(ctx, "car1", []string{slots[0].ID}, split, false); !errors.Is(err, repositories.ErrStrandedBooking) {
+			t.Fatalf("Expected ErrStrandedBooking, got %v", err)
+		}
+		if ok, _ := repo.HasAvailabilitySlot(ctx, "car1", jan(10), jan(12)); !ok {
+			t.Fatal("Expected a refused change to leave availability untouched")
+		}
+		// Blocking Jan 20 doesn't touch it.
+		split = []*models.AvailabilitySlot{
+			{CarID: "car1", StartTime: jan(1), EndTime: jan(20)},
+			{CarID: "car1", StartTime: jan(21), EndTime: jan(31)},
+		}
+		if err := repo.ReplaceAvailability(ctx, "car1", []string{slots[0].ID}, split, false); err != nil {
+			t.Fatal(err)
+		}
+		if ok, _ := repo.HasAvailabilitySlot(ctx, "car1", jan(19), jan(22)); ok {
+			t.Error("Expected the blocked day to break availability")
+		}
+		if after, _ := repo.GetAvailability(ctx, "car1", jan(1), jan(31)); len(after) != 2 || !after[0].StartTime.Equal(jan(1)) {
+			t.Errorf("Expected 2 slots in start order, got %d", len(after))
+		}
+		if err := repo.RemoveAvailability(ctx, split[0].ID); err != nil {
+			t.Fatal(err)
+		}
+		if ok, _ := repo.HasAvailabilitySlot(ctx, "car1", jan(10), jan(12)); ok {
+			t.Error("Expected a forced removal to drop the slot")
+		}
+	})
+
+	t.Run("RecurrenceRules", func(t *testing.T) {
+		repo := newRepo(t)
+		if err := repo.CreateCar(ctx, &models.Car{ID: "car1", HostID: "host1", IsActive: true}); err != nil {
+			t.Fatal(err)
+		}
+		// Weekends only, all day.
+		rule := &models.RecurrenceRule{CarID: "car1", Frequency: models.FrequencyWeekly, ByWeekday: []time.Weekday{time.Saturday, time.Sunday},
+			EndOfDay: 24 * time.Hour, StartDate: jan(1), Exceptions: []time.Time{jan(17)}}
+		if err := repo.AddRecurrenceRule(ctx, rule); err != nil {
+			t.Fatal(err)
+		}
+		if rules, err := repo.GetRecurrenceRules(ctx, "car1"); err != nil || len(rules) != 1 || len(rules[0].ByWeekday) != 2 || len(rules[0].Exceptions) != 1 {
+			t.Fatalf("Expected the stored rule back, got %v, %v", rules, err)
+		}
+		if got, err := repo.GetRecurrenceRule(ctx, rule.ID); err != nil || got.CarID != "car1" {
+			t.Errorf("Expected the rule by ID, got %v, %v", got, err)
+		}
+		// Jan 3-4 2026 is a weekend; Jan 17 is excepted.
+		if ok, _ := repo.HasAvailabilitySlot(ctx, "car1", jan(3), jan(5)); !ok {
+			t.Error("Expected the weekend to be available")
+		}
+		if ok, _ := repo.HasAvailabilitySlot(ctx, "car1", jan(17), jan(18)); ok {
+			t.Error("Expected the exception date to be unavailable")
+		}
+		b := &models.Booking{CarID: "car1", StartTime: jan(3), EndTime: jan(5), Status: models.BookingStatusConfirmed}
+		if err := repo.ReserveBooking(ctx, b, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.RemoveRecurrenceRule(ctx, rule.ID, false); !errors.Is(err, repositories.ErrStrandedBooking) {
+			t.Errorf("Expected ErrStrandedBooking, got %v", err)
+		}
+		if err := repo.RemoveRecurrenceRule(ctx, rule.ID, true); err != nil {
+			t.Fatal(err)
+		}
+		if rules, _ := repo.GetRecurrenceRules(ctx, "car1"); len(rules) != 0 {
+			t.Errorf("Expected no rules after a forced removal, got %d", len(rules))
+		}
+		if _, err := repo.GetRecurrenceRule(ctx, rule.ID); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for a removed rule, got %v", err)
+		}
+	})
+
+	t.Run("FilterAvailable", func(t *testing.T) {
+		repo := newRepo(t)
+		seedCar(t, repo, "free")
+		seedCar(t, repo, "booked")
+		if err := repo.CreateCar(ctx, &models.Car{ID: "unlisted", HostID: "host1", IsActive: true}); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.ReserveBooking(ctx, &models.Booking{CarID: "booked", StartTime: jan(3), EndTime: jan(6), Status: models.BookingStatusConfirmed}, nil); err != nil {
+			t.Fatal(err)
+		}
+		got, err := repo.FilterAvailable(ctx, []string{"free", "booked", "unlisted", "missing"}, jan(5), jan(7))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || !got["free"] {
+			t.Errorf("Expected only the free car, got %v", got)
+		}
+	})
+	t.Run("Reviews", func(t *testing.T) {
+		repo := newRepo(t)
+		seedCar(t, repo, "car1")
+		if err := repo.CreateHost(ctx, &models.Host{ID: "host1"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.CreateUser(ctx, &models.User{ID: "user1"}); err != nil {
+			t.Fatal(err)
+		}
+		review := func(booking string, role models.ReviewerRole, rating int, created time.Time) *models.Review {
+			return &models.Review{BookingID: booking, CarID: "car1", HostID: "host1", UserID: "user1", Role: role, Rating: rating, CreatedAt: created, RevealAt: jan(15)}
+		}
+		renter := review("b1", models.ReviewerRenter, 4, jan(2))
+		host := review("b1", models.ReviewerHost, 5, jan(3))
+		for _, rv := range []*models.Review{renter, host, review("b2", models.ReviewerRenter, 2, jan(4))} {
+			if err := repo.CreateReview(ctx, rv); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var conflict *models.ConflictError
+		if err := repo.CreateReview(ctx, review("b1", models.ReviewerRenter, 1, jan(5))); !errors.Is(err, repositories.ErrDuplicateReview) || !errors.As(err, &conflict) || conflict.BookingID != "b1" {
+			t.Errorf("Expected a duplicate review conflict on b1, got %v", err)
+		}
+
+		if due, err := repo.ListDueReviews(ctx, jan(14)); err != nil || len(due) != 0 {
+			t.Errorf("Expected nothing due before the reveal, got %d, %v", len(due), err)
+		}
+		if err := repo.PublishReviews(ctx, []string{renter.ID, host.ID}, jan(3)); err != nil {
+			t.Fatal(err)
+		}
+		// Publishing again must not count the scores twice.
+		if err := repo.PublishReviews(ctx, []string{renter.ID}, jan(4)); err != nil {
+			t.Fatal(err)
+		}
+		due, err := repo.ListDueReviews(ctx, jan(15))
+		if err != nil || len(due) != 1 || due[0].BookingID != "b2" {
+			t.Fatalf("Expected only b2's review due, got %+v, %v", due, err)
+		}
+		if err := repo.PublishReviews(ctx, []string{due[0].ID}, jan(15)); err != nil {
+			t.Fatal(err)
+		}
+
+		car, _ := repo.GetCar(ctx, "car1")
+		if car.Rating != (models.Rating{Average: 3, Count: 2}) {
+			t.Errorf("Expected the car rated 3 from 2 reviews, got %+v", car.Rating)
+		}
+		h, _ := repo.GetHost(ctx, "host1")
+		u, _ := repo.GetUser(ctx, "user1")
+		if h.Rating != car.Rating || u.Rating != (models.Rating{Average: 5, Count: 1}) {
+			t.Errorf("Expected host %+v and renter 5 from 1, got %+v and %+v", car.Rating, h.Rating, u.Rating)
+		}
+
+		forCar, err := repo.GetReviewsForCar(ctx, "car1")
+		if err != nil || len(forCar) != 2 || forCar[0].BookingID != "b2" {
+			t.Errorf("Expected the car's 2 renter reviews newest first, got %+v, %v", forCar, err)
+		}
+		forUser, _ := repo.GetReviewsForUser(ctx, "user1")
+		if len(forUser) != 1 || !forUser[0].PublishedAt.Equal(jan(3)) {
+			t.Errorf("Expected the host's review published on Jan 3, got %+v", forUser)
+		}
+	})
+}
+
+func carIDs(results []*models.CarSearchResult) []string {
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.Car.ID)
+	}
+	return ids
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"car-rental-lite/src/models"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Repositories return the specific errors below wrapped in the structured
+// errors from models: *models.NotFoundError for a missing ID, and
+// *models.ConflictError (carrying the booking in the way) for ErrOverlap,
+// ErrNoAvailability, ErrStrandedBooking, ErrCarInactive, ErrCarHasBookings,
+// ErrInvalidTransition, ErrDuplicateInspection and ErrDuplicateReview. errors.Is matches both the
+// sentinel here and the kind (models.ErrConflict etc.).
+var (
+	// ErrNotFound is models.ErrNotFound, kept here so callers of this package
+	// don't need to import models to check for it.
+	ErrNotFound = models.ErrNotFound
+	// ErrOverlap is returned when a booking would overlap an existing
+	// non-cancelled booking for the same car.
+	ErrOverlap = errors.New("car is already booked for these dates")
+	// ErrNoAvailability is returned when no availability slot covers the
+	// requested window.
+	ErrNoAvailability = errors.New("host has not made the car available for these dates")
+	// ErrStrandedBooking is returned when an availability change would leave
+	// an open booking outside the car's availability.
+	ErrStrandedBooking = errors.New("change would leave an existing booking without availability")
+	// ErrCarInactive is returned when reserving a car its host has
+	// deactivated.
+	ErrCarInactive = errors.New("car is not currently active")
+	// ErrCarHasBookings is returned when deactivating a car that upcoming
+	// bookings still expect to pick up.
+	ErrCarHasBookings = errors.New("car has upcoming bookings")
+	// ErrInvalidTransition is returned when a status change isn't allowed by
+	// the booking or claim state machine.
+	ErrInvalidTransition = errors.New("invalid status transition")
+	// ErrDuplicateInspection is returned when a booking already has a report
+	// of the same type.
+	ErrDuplicateInspection = errors.New("booking already has an inspection report of this type")
+	// ErrDuplicateReview is returned when a side of a booking has already
+	// reviewed it.
+	ErrDuplicateReview = errors.New("booking has already been reviewed by this party")
+)
+
+// checkModifiable reports whether booking b may move to start a new window at
+// newStart: it must not have ended or been released, and a trip in progress
+// keeps its pickup time.
+func checkModifiable(b *models.Booking, newStart time.Time) error {
+	if !b.Status.IsOpen() {
+		return &models.ConflictError{Err: fmt.Errorf("%w: can't modify a %s booking", ErrInvalidTransition, b.Status), BookingID: b.ID}
+	}
+	if b.Status == models.BookingStatusActive && !newStart.Equal(b.StartTime) {
+		return &models.ConflictError{Err: fmt.Errorf("%w: can't move the start of an ACTIVE booking", ErrInvalidTransition), BookingID: b.ID}
+	}
+	return nil
+}
+
+// recordModification moves b to mod's window and price, and completes and
+// appends mod to its history.
+func recordModification(b *models.Booking, mod *models.BookingModification, breakdown []models.PriceLineItem) {
+	mod.PreviousStart, mod.PreviousEnd, mod.PreviousPrice = b.StartTime, b.EndTime, b.TotalPrice
+	mod.PriceDifference = math.Round((mod.NewPrice-mod.PreviousPrice)*100) / 100
+	b.StartTime, b.EndTime, b.TotalPrice = mod.NewStart, mod.NewEnd, mod.NewPrice
+	b.PriceBreakdown = append([]models.PriceLineItem(nil), breakdown...)
+	b.Modifications = append(b.Modifications, *mod)
+}
@@ -0,0 +1,164 @@
+package repositories
+
+import (
+	"car-rental-lite/src/models"
+	"math"
+)
+
+const (
+	// gridCellDeg is the side of a grid cell in degrees (~11km of latitude).
+	gridCellDeg = 0.1
+	kmPerDegLat = 111.32
+)
+
+type gridCell struct {
+	lat, lng int
+}
+
+func cellOf(loc models.Location) gridCell {
+	return gridCell{
+		lat: int(math.Floor(loc.Latitude / gridCellDeg)),
+		lng: int(math.Floor(loc.Longitude / gridCellDeg)),
+	}
+}
+
+// gridIndex buckets car IDs into fixed-size lat/lng cells so a radius query
+// only visits the cells overlapping the search circle's bounding box.
+// It isn't safe for concurrent use; InMemoryRepo guards it with its mutex.
+type gridIndex struct {
+	cells  map[gridCell]map[string]struct{}
+	carPos map[string]gridCell
+}
+
+func newGridIndex() *gridIndex {
+	return &gridIndex{
+		cells:  make(map[gridCell]map[string]struct{}),
+		carPos: make(map[string]gridCell),
+	}
+}
+
+// put indexes carID at loc, moving it if it was indexed elsewhere.
+func (g *gridIndex) put(carID string, loc models.Location) {
+	cell := cellOf(loc)
+	if old, ok := g.carPos[carID]; ok {
+		if old == cell {
+			return
+		}
+		delete(g.cells[old], carID)
+		if len(g.cells[old]) == 0 {
+			delete(g.cells, old)
+		}
+	}
+	if g.cells[cell] == nil {
+		g.cells[cell] = make(map[string]struct{})
+	}
+	g.cells[cell][carID] = struct{}{}
+	g.carPos[carID] = cell
+}
+
+// cellRange returns the corner cells of the bounding box of the circle. ok is
+// false when the box crosses a pole or the antimeridian.
+func cellRange(center models.Location, radiusKm float64) (lo, hi gridCell, ok bool) {
+	dLat := radiusKm / kmPerDegLat
+	cosLat := math.Cos(center.Latitude * math.Pi / 180)
+	if cosLat < 0.01 {
+		return lo, hi, false
+	}
+	dLng := radiusKm / (kmPerDegLat * cosLat)
+
+	minLat, maxLat := center.Latitude-dLat, center.Latitude+dLat
+	minLng, maxLng := center.Longitude-dLng, center.Longitude+dLng
+	if minLat < -90 || maxLat > 90 || minLng < -180 || maxLng > 180 {
+		return lo, hi, false
+	}
+	lo = cellOf(models.Location{Latitude: minLat, Longitude: minLng})
+	hi = cellOf(models.Location{Latitude: maxLat, Longitude: maxLng})
+	return lo, hi, true
+}
+
+// candidates returns the IDs of cars in cells overlapping the bounding box of
+// the circle. ok is false when the box is too large to be worth walking cell
+// by cell (or crosses a pole/the antimeridian) and the caller should scan.
+func (g *gridIndex) candidates(center models.Location, radiusKm float64) (ids []string, ok bool) {
+	lo, hi, ok := cellRange(center, radiusKm)
+	if !ok || (hi.lat-lo.lat+1)*(hi.lng-lo.lng+1) > len(g.cells) {
+		return nil, false
+	}
+
+	for lat := lo.lat; lat <= hi.lat; lat++ {
+		for lng := lo.lng; lng <= hi.lng; lng++ {
+			for id := range g.cells[gridCell{lat, lng}] {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, true
+}
+
+// maxSearchCells caps how many cells one saved search is bucketed into.
+// Wider searches are checked against every change instead.
+const maxSearchCells = 1024
+
+// searchIndex is gridIndex turned around: it buckets saved searches into
+// every cell their search circle's bounding box covers, so a change to one
+// car only visits the searches that could reach it. Searches with no radius,
+// or too wide to bucket, are kept in wide. It isn't safe for concurrent use;
+// InMemoryRepo guards it with its mutex.
+type searchIndex struct {
+	cells       map[gridCell]map[string]struct{}
+	wide        map[string]struct{}
+	searchCells map[string][]gridCell
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		cells:       make(map[gridCell]map[string]struct{}),
+		wide:        make(map[string]struct{}),
+		searchCells: make(map[string][]gridCell),
+	}
+}
+
+func (x *searchIndex) put(id string, center models.Location, radiusKm float64) {
+	x.remove(id)
+	lo, hi, ok := cellRange(center, radiusKm)
+	if radiusKm <= 0 || !ok || (hi.lat-lo.lat+1)*(hi.lng-lo.lng+1) > maxSearchCells {
+		x.wide[id] = struct{}{}
+		return
+	}
+	var cells []gridCell
+	for lat := lo.lat; lat <= hi.lat; lat++ {
+		for lng := lo.lng; lng <= hi.lng; lng++ {
+			cell := gridCell{lat, lng}
+			if x.cells[cell] == nil {
+				x.cells[cell] = make(map[string]struct{})
+			}
+			x.cells[cell][id] = struct{}{}
+			cells = append(cells, cell)
+		}
+	}
+	x.searchCells[id] = cells
+}
+
+func (x *searchIndex) remove(id string) {
+	delete(x.wide, id)
+	for _, cell := range x.searchCells[id] {
+		delete(x.cells[cell], id)
+		if len(x.cells[cell]) == 0 {
+			delete(x.cells, cell)
+		}
+	}
+	delete(x.searchCells, id)
+}
+
+// candidates returns the IDs of the searches whose bounding box covers loc,
+// and every wide search.
+func (x *searchIndex) candidates(loc models.Location) []string {
+	ids := make([]string, 0, len(x.wide))
+	for id := range x.wide {
+		ids = append(ids, id)
+	}
+	for id := range x.cells[cellOf(loc)] {
+		ids = append(ids, id)
+	}
+	return ids
+}
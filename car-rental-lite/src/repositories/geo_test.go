@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"car-rental-lite/src/models"
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSearchCarsOrdersByDistanceWithinRadius(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRepo()
+	sf := models.Location{Latitude: 37.7749, Longitude: -122.4194}
+	cars := []*models.Car{
+		{ID: "oakland", Location: models.Location{Latitude: 37.8044, Longitude: -122.2712}, IsActive: true},
+		{ID: "mission", Location: models.Location{Latitude: 37.7599, Longitude: -122.4148}, IsActive: true},
+		{ID: "san-jose", Location: models.Location{Latitude: 37.3382, Longitude: -121.8863}, IsActive: true},
+		{ID: "inactive", Location: sf, IsActive: false},
+	}
+	for _, c := range cars {
+		if err := repo.CreateCar(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := repo.SearchCars(ctx, sf, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range res {
+		got = append(got, r.Car.ID)
+	}
+	if fmt.Sprint(got) != "[mission oakland]" {
+		t.Fatalf("Expected [mission oakland], got %v", got)
+	}
+	if d := res[1].DistanceKm; d < 13 || d > 14 {
+		t.Errorf("Expected SF-Oakland distance of ~13.4km, got %.2f", d)
+	}
+}
+
+func TestSearchCarsIndexMatchesFullScan(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRepo()
+	rng := rand.New(rand.NewSource(1))
+	var all []*models.Car
+	for i := 0; i < 3000; i++ {
+		c := &models.Car{
+			ID:       fmt.Sprintf("car%d", i),
+			IsActive: true,
+			Location: models.Location{
+				Latitude:  37 + rng.Float64(),
+				Longitude: -123 + rng.Float64(),
+			},
+		}
+		all = append(all, c)
+		if err := repo.CreateCar(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	center := models.Location{Latitude: 37.5, Longitude: -122.5}
+	for _, radius := range []float64{0.5, 5, 25} {
+		var want []string
+		for _, c := range all {
+			if center.DistanceKm(c.Location) <= radius {
+				want = append(want, c.ID)
+			}
+		}
+		res, err := repo.SearchCars(ctx, center, radius)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for i, r := range res {
+			if i > 0 && r.DistanceKm < res[i-1].DistanceKm {
+				t.Fatalf("Results not ordered by distance at %d", i)
+			}
+			got = append(got, r.Car.ID)
+		}
+		sort.Strings(want)
+		sort.Strings(got)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("radius %.1f: index returned %d cars, full scan %d", radius, len(got), len(want))
+		}
+	}
+}
+
+func TestCandidateSavedSearchesMatchesFullScan(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRepo()
+	rng := rand.New(rand.NewSource(3))
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return start.AddDate(0, 0, d) }
+	var all []*models.SavedSearch
+	for i := 0; i < 2000; i++ {
+		from := rng.Intn(60)
+		s := &models.SavedSearch{
+			ID:     fmt.Sprintf("search%d", i),
+			Status: models.SavedSearchActive,
+			Query: models.SearchQuery{
+				Location:  models.Location{Latitude: 37 + rng.Float64(), Longitude: -123 + rng.Float64()},
+				RadiusKm:  []float64{0, 2, 10, 40, 500}[rng.Intn(5)],
+				StartTime: day(from),
+				EndTime:   day(from + 1 + rng.Intn(5)),
+			},
+		}
+		all = append(all, s)
+		if err := repo.CreateSavedSearch(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Closed searches drop out of the index.
+	for _, s := range all[:100] {
+		if err := repo.CloseSavedSearch(ctx, s.ID, models.SavedSearchCancelled, "", start); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		car := &models.Car{Location: models.Location{Latitude: 37 + rng.Float64(), Longitude: -123 + rng.Float64()}}
+		from, to := day(rng.Intn(60)), day(60)
+		var want []string
+		for _, s := range all[100:] {
+			q := s.Query
+			if q.StartTime.Before(to) && q.EndTime.After(from) && (q.RadiusKm <= 0 || car.Location.DistanceKm(q.Location) <= q.RadiusKm) {
+				want = append(want, s.ID)
+			}
+		}
+		res, err := repo.CandidateSavedSearches(ctx, car, from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, s := range res {
+			got = append(got, s.ID)
+		}
+		sort.Strings(want)
+		sort.Strings(got)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("car %d: index returned %d searches, full scan %d", i, len(got), len(want))
+		}
+	}
+}
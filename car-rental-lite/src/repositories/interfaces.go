@@ -6,6 +6,13 @@ import (
 	"time"
 )
 
+// BookingsCheck vets a reservation or modification against the renter's other
+// bookings overlapping its window, whatever their status. It runs in the same
+// critical section as the write, serialised per renter, so a limit across a
+// renter's bookings holds under concurrency. A non-nil error refuses the
+// write and is returned as is.
+type BookingsCheck func(others []*models.Booking) error
+
 type UserRepository interface {
 	CreateUser(ctx context.Context, user *models.User) error
 	GetUser(ctx context.Context, id string) (*models.User, error)
@@ -17,20 +24,128 @@ type CarRepository interface {
 	CreateCar(ctx context.Context, car *models.Car) error
 	GetCar(ctx context.Context, id string) (*models.Car, error)
 	GetCarsByHost(ctx context.Context, hostID string) ([]*models.Car, error)
-	SearchCars(ctx context.Context, location models.Location, radiusKm float64) ([]*models.Car, error)
+	// SearchCars returns active cars within radiusKm of location, ordered by
+	// distance.
+	SearchCars(ctx context.Context, location models.Location, radiusKm float64) ([]*models.CarSearchResult, error)
+	// SetCarActive turns the car on or off for new bookings. Deactivating it
+	// takes the car's lock, as ReserveBooking does, and fails with
+	// ErrCarHasBookings if a booking is still Upcoming at t, so no booking
+	// can be reserved between the check and the change.
+	SetCarActive(ctx context.Context, carID string, active bool, t time.Time) error
 }
 
 type BookingRepository interface {
 	CreateBooking(ctx context.Context, booking *models.Booking) error
 	GetBooking(ctx context.Context, id string) (*models.Booking, error)
+	// UpdateBookingStatus fails with ErrInvalidTransition unless the booking
+	// state machine allows moving from the current status to status.
 	UpdateBookingStatus(ctx context.Context, id string, status models.BookingStatus) error
+	ListBookingsByStatus(ctx context.Context, status models.BookingStatus) ([]*models.Booking, error)
+	// CancelBooking moves the booking to CANCELLED and stores the refund in one
+	// step, so a booking can't be refunded twice. refund is called once, in
+	// the same critical section, with a copy of the booking as it stands
+	// before cancelling, so a concurrent confirmation or modification can't
+	// leave the refund priced from a stale status or total. Fails with
+	// ErrInvalidTransition if the booking can't be cancelled.
+	CancelBooking(ctx context.Context, bookingID string, by models.CancellationActor, refund func(booking *models.Booking) *models.Refund) error
+	GetRefundsForBooking(ctx context.Context, bookingID string) ([]*models.Refund, error)
+	// MarkConfirmed moves a PENDING booking to CONFIRMED once it's paid.
+	MarkConfirmed(ctx context.Context, bookingID string, at time.Time) error
 	GetBookingsForCar(ctx context.Context, carID string, from, to time.Time) ([]*models.Booking, error)
+	// GetUpcomingBookingsForCar returns the car's bookings that are Upcoming
+	// at t, earliest first.
+	GetUpcomingBookingsForCar(ctx context.Context, carID string, t time.Time) ([]*models.Booking, error)
+	// GetBookingsForUser returns the renter's bookings overlapping [from, to),
+	// earliest first.
+	GetBookingsForUser(ctx context.Context, userID string, from, to time.Time) ([]*models.Booking, error)
 	HasOverlappingBooking(ctx context.Context, carID string, start, end time.Time) (bool, error)
+	// ReserveBooking atomically checks that the car is active and has
+	// availability covering the booking window and no overlapping booking,
+	// and runs check if it isn't nil, then inserts it. Returns
+	// ErrCarInactive, ErrNoAvailability or ErrOverlap when the checks fail.
+	ReserveBooking(ctx context.Context, booking *models.Booking, check BookingsCheck) error
+	// ModifyBooking moves an open booking to mod's new window and price,
+	// replacing its price breakdown, after checking availability and other
+	// bookings in the same critical section as ReserveBooking does. It fills
+	// in mod's previous window, price and difference and appends it to the
+	// booking's history. Fails with ErrInvalidTransition unless the booking
+	// is PENDING or CONFIRMED, or ACTIVE and keeping its start. check, if not
+	// nil, runs as for ReserveBooking over the renter's other bookings
+	// overlapping the new window.
+	ModifyBooking(ctx context.Context, bookingID string, mod *models.BookingModification, breakdown []models.PriceLineItem, check BookingsCheck) error
 }
 
 type InventoryRepository interface {
 	AddAvailability(ctx context.Context, slot *models.AvailabilitySlot) error
 	GetAvailability(ctx context.Context, carID string, from, to time.Time) ([]*models.AvailabilitySlot, error)
+	GetAvailabilitySlot(ctx context.Context, slotID string) (*models.AvailabilitySlot, error)
 	RemoveAvailability(ctx context.Context, slotID string) error
+	// ReplaceAvailability atomically removes the slots in removeIDs and adds
+	// the slots in add for one car. Unless force is set it fails with
+	// ErrStrandedBooking if an open booking would no longer be covered.
+	ReplaceAvailability(ctx context.Context, carID string, removeIDs []string, add []*models.AvailabilitySlot, force bool) error
+	// HasAvailabilitySlot reports whether explicit slots and recurrence rules
+	// together cover [start, end).
 	HasAvailabilitySlot(ctx context.Context, carID string, start, end time.Time) (bool, error)
+	AddRecurrenceRule(ctx context.Context, rule *models.RecurrenceRule) error
+	GetRecurrenceRules(ctx context.Context, carID string) ([]*models.RecurrenceRule, error)
+	GetRecurrenceRule(ctx context.Context, ruleID string) (*models.RecurrenceRule, error)
+	// RemoveRecurrenceRule fails with ErrStrandedBooking, unless force is set,
+	// if an open booking would no longer be covered.
+	RemoveRecurrenceRule(ctx context.Context, ruleID string, force bool) error
+	// FilterAvailable returns the subset of carIDs that have availability
+	// covering [start, end) and no overlapping booking.
+	FilterAvailable(ctx context.Context, carIDs []string, start, end time.Time) (map[string]bool, error)
+}
+
+type ReviewRepository interface {
+	// CreateReview fails with ErrDuplicateReview if the booking already has
+	// a review from the same side.
+	CreateReview(ctx context.Context, review *models.Review) error
+	GetReviewsForBooking(ctx context.Context, bookingID string) ([]*models.Review, error)
+	// GetReviewsForCar returns renters' reviews of the car, newest first.
+	GetReviewsForCar(ctx context.Context, carID string) ([]*models.Review, error)
+	// GetReviewsForUser returns hosts' reviews of the renter, newest first.
+	GetReviewsForUser(ctx context.Context, userID string) ([]*models.Review, error)
+	// ListDueReviews returns unpublished reviews whose RevealAt is at or
+	// before t.
+	ListDueReviews(ctx context.Context, t time.Time) ([]*models.Review, error)
+	// PublishReviews sets PublishedAt on the reviews and, in the same step,
+	// folds their ratings into the car, host and renter aggregates. Reviews
+	// that are already published are skipped, so no rating counts twice.
+	PublishReviews(ctx context.Context, ids []string, at time.Time) error
+}
+
+// WaitlistRepository stores renters' saved searches.
+type WaitlistRepository interface {
+	CreateSavedSearch(ctx context.Context, search *models.SavedSearch) error
+	GetSavedSearch(ctx context.Context, id string) (*models.SavedSearch, error)
+	// GetSavedSearchesForUser returns the renter's searches, newest first.
+	GetSavedSearchesForUser(ctx context.Context, userID string) ([]*models.SavedSearch, error)
+	// CandidateSavedSearches returns the ACTIVE searches, oldest first, whose
+	// window overlaps [start, end) and whose radius reaches the car. It's
+	// indexed, so a change to one car doesn't visit every saved search. The
+	// caller checks the remaining filters and the car's availability.
+	CandidateSavedSearches(ctx context.Context, car *models.Car, start, end time.Time) ([]*models.SavedSearch, error)
+	// CloseSavedSearch moves an ACTIVE search to status, recording the car
+	// for a match. Fails with ErrInvalidTransition if the search isn't
+	// ACTIVE, so a search closes, and matches, at most once.
+	CloseSavedSearch(ctx context.Context, id string, status models.SavedSearchStatus, carID string, at time.Time) error
+}
+
+type InspectionRepository interface {
+	// RecordTripInspection stores a PICKUP report and moves its booking
+	// CONFIRMED -> ACTIVE, or a DROPOFF report and moves it ACTIVE ->
+	// COMPLETED, appending the adjustments and adding them to TotalPrice, in
+	// one step. The report's CreatedAt is recorded as the pickup or return
+	// time. Fails with ErrDuplicateInspection (one report of each type per
+	// booking) or ErrInvalidTransition without storing either.
+	RecordTripInspection(ctx context.Context, report *models.InspectionReport, adjustments []models.PriceLineItem) error
+	GetInspectionsForBooking(ctx context.Context, bookingID string) ([]*models.InspectionReport, error)
+	CreateClaim(ctx context.Context, claim *models.DamageClaim) error
+	GetClaim(ctx context.Context, id string) (*models.DamageClaim, error)
+	GetClaimsForBooking(ctx context.Context, bookingID string) ([]*models.DamageClaim, error)
+	// UpdateClaimStatus fails with ErrInvalidTransition unless the claim
+	// workflow allows the change.
+	UpdateClaimStatus(ctx context.Context, id string, status models.ClaimStatus, at time.Time) error
 }
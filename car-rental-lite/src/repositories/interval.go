@@ -0,0 +1,223 @@
+package repositories
+
+import (
+	"car-rental-lite/src/models"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+type span struct {
+	start, end time.Time
+}
+
+// coverage is a sorted list of disjoint spans. Overlapping or touching spans
+// are merged on insert, so a window is available iff a single span covers it,
+// even when the host added it as several adjacent slots.
+type coverage struct {
+	spans []span
+}
+
+func (c *coverage) add(start, end time.Time) {
+	// First span that ends at or after start: everything before it is
+	// strictly to the left and untouched.
+	i := sort.Search(len(c.spans), func(i int) bool { return !c.spans[i].end.Before(start) })
+	j := i
+	for j < len(c.spans) && !c.spans[j].start.After(end) {
+		if c.spans[j].start.Before(start) {
+			start = c.spans[j].start
+		}
+		if c.spans[j].end.After(end) {
+			end = c.spans[j].end
+		}
+		j++
+	}
+	merged := append([]span{{start, end}}, c.spans[j:]...)
+	c.spans = append(c.spans[:i], merged...)
+}
+
+// covers reports whether [start, end) lies inside one merged span. O(log n).
+func (c *coverage) covers(start, end time.Time) bool {
+	i := sort.Search(len(c.spans), func(i int) bool { return c.spans[i].start.After(start) }) - 1
+	return i >= 0 && !c.spans[i].end.Before(end)
+}
+
+// overlapping returns the spans that overlap or touch [start, end].
+func (c *coverage) overlapping(start, end time.Time) []span {
+	i := sort.Search(len(c.spans), func(i int) bool { return !c.spans[i].end.Before(start) })
+	j := i
+	for j < len(c.spans) && !c.spans[j].start.After(end) {
+		j++
+	}
+	return c.spans[i:j]
+}
+
+// coversWithRules reports whether the explicit spans in cov, together with
+// the occurrences of rules, cover [start, end). Rules are only expanded over
+// the queried window and only when the explicit slots alone don't suffice.
+func coversWithRules(cov *coverage, rules []*models.RecurrenceRule, start, end time.Time) bool {
+	if cov.covers(start, end) {
+		return true
+	}
+	if len(rules) == 0 {
+		return false
+	}
+	var local coverage
+	for _, s := range cov.overlapping(start, end) {
+		local.add(s.start, s.end)
+	}
+	for _, rule := range rules {
+		windows, err := rule.Occurrences(start, end)
+		if err != nil {
+			// Rules are validated when added; an unloadable zone offers nothing.
+			continue
+		}
+		for _, w := range windows {
+			local.add(w.Start, w.End)
+		}
+	}
+	return local.covers(start, end)
+}
+
+// itNode is a treap node keyed by (start, id) and augmented with the largest
+// end time in its subtree, which lets overlap queries prune whole subtrees.
+type itNode struct {
+	id          string
+	start, end  time.Time
+	maxEnd      time.Time
+	prio        uint32
+	left, right *itNode
+}
+
+// intervalTree indexes [start, end) intervals for overlap queries in
+// O(log n + k) expected time.
+type intervalTree struct {
+	root *itNode
+}
+
+func nodeLess(aStart time.Time, aID string, b *itNode) bool {
+	if !aStart.Equal(b.start) {
+		return aStart.Before(b.start)
+	}
+	return aID < b.id
+}
+
+func (n *itNode) update() {
+	n.maxEnd = n.end
+	if n.left != nil && n.left.maxEnd.After(n.maxEnd) {
+		n.maxEnd = n.left.maxEnd
+	}
+	if n.right != nil && n.right.maxEnd.After(n.maxEnd) {
+		n.maxEnd = n.right.maxEnd
+	}
+}
+
+func rotateRight(n *itNode) *itNode {
+	l := n.left
+	n.left = l.right
+	l.right = n
+	n.update()
+	l.update()
+	return l
+}
+
+func rotateLeft(n *itNode) *itNode {
+	r := n.right
+	n.right = r.left
+	r.left = n
+	n.update()
+	r.update()
+	return r
+}
+
+func (t *intervalTree) insert(id string, start, end time.Time) {
+	t.root = insertNode(t.root, &itNode{id: id, start: start, end: end, maxEnd: end, prio: rand.Uint32()})
+}
+
+func insertNode(n, x *itNode) *itNode {
+	if n == nil {
+		return x
+	}
+	if nodeLess(x.start, x.id, n) {
+		n.left = insertNode(n.left, x)
+		if n.left.prio > n.prio {
+			n = rotateRight(n)
+		}
+	} else {
+		n.right = insertNode(n.right, x)
+		if n.right.prio > n.prio {
+			n = rotateLeft(n)
+		}
+	}
+	n.update()
+	return n
+}
+
+// remove deletes the interval inserted with id and start, if present.
+func (t *intervalTree) remove(id string, start time.Time) {
+	t.root = removeNode(t.root, id, start)
+}
+
+func removeNode(n *itNode, id string, start time.Time) *itNode {
+	if n == nil {
+		return nil
+	}
+	switch {
+	case n.id == id && n.start.Equal(start):
+		switch {
+		case n.left == nil:
+			return n.right
+		case n.right == nil:
+			return n.left
+		case n.left.prio > n.right.prio:
+			n = rotateRight(n)
+			n.right = removeNode(n.right, id, start)
+		default:
+			n = rotateLeft(n)
+			n.left = removeNode(n.left, id, start)
+		}
+	case nodeLess(start, id, n):
+		n.left = removeNode(n.left, id, start)
+	default:
+		n.right = removeNode(n.right, id, start)
+	}
+	n.update()
+	return n
+}
+
+// overlapping calls fn for every interval overlapping [start, end) until fn
+// returns false.
+func (t *intervalTree) overlapping(start, end time.Time, fn func(id string) bool) {
+	visitOverlapping(t.root, start, end, fn)
+}
+
+func visitOverlapping(n *itNode, start, end time.Time, fn func(id string) bool) bool {
+	if n == nil || !n.maxEnd.After(start) {
+		return true
+	}
+	if !visitOverlapping(n.left, start, end, fn) {
+		return false
+	}
+	if !n.start.Before(end) {
+		// n and its right subtree all start at or after end.
+		return true
+	}
+	if n.end.After(start) && !fn(n.id) {
+		return false
+	}
+	return visitOverlapping(n.right, start, end, fn)
+}
+
+// firstOverlap returns the ID of an interval other than except overlapping
+// [start, end), or "" if there is none.
+func (t *intervalTree) firstOverlap(start, end time.Time, except string) string {
+	found := ""
+	t.overlapping(start, end, func(id string) bool {
+		if id == except {
+			return true
+		}
+		found = id
+		return false
+	})
+	return found
+}
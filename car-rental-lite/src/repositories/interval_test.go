@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"car-rental-lite/src/models"
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func day(d int) time.Time {
+	return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCoverageMergesAdjacentSlots(t *testing.T) {
+	var c coverage
+	c.add(day(5), day(10))
+	c.add(day(1), day(5))
+	c.add(day(20), day(25))
+
+	if len(c.spans) != 2 {
+		t.Fatalf("Expected 2 merged spans, got %d", len(c.spans))
+	}
+	tests := []struct {
+		start, end time.Time
+		want       bool
+	}{
+		{day(3), day(7), true},
+		{day(1), day(10), true},
+		{day(9), day(11), false},
+		{day(12), day(13), false},
+		{day(8), day(22), false},
+		{day(21), day(25), true},
+	}
+	for _, tt := range tests {
+		if got := c.covers(tt.start, tt.end); got != tt.want {
+			t.Errorf("covers(%v, %v) = %v, want %v", tt.start.Day(), tt.end.Day(), got, tt.want)
+		}
+	}
+
+	c.add(day(9), day(21))
+	if len(c.spans) != 1 || !c.covers(day(1), day(25)) {
+		t.Errorf("Expected bridging slot to merge everything into one span, got %v", c.spans)
+	}
+}
+
+func TestIntervalTreeMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	base := day(1)
+	type iv struct {
+		id         string
+		start, end time.Time
+	}
+	var tree intervalTree
+	live := make(map[string]iv)
+
+	for i := 0; i < 2000; i++ {
+		if len(live) > 0 && rng.Intn(3) == 0 {
+			for id, v := range live {
+				tree.remove(id, v.start)
+				delete(live, id)
+				break
+			}
+			continue
+		}
+		start := base.Add(time.Duration(rng.Intn(1000)) * time.Hour)
+		v := iv{id: fmt.Sprintf("b%d", i), start: start, end: start.Add(time.Duration(1+rng.Intn(72)) * time.Hour)}
+		tree.insert(v.id, v.start, v.end)
+		live[v.id] = v
+	}
+
+	for q := 0; q < 500; q++ {
+		qs := base.Add(time.Duration(rng.Intn(1100)) * time.Hour)
+		qe := qs.Add(time.Duration(1+rng.Intn(48)) * time.Hour)
+
+		want := 0
+		var some string
+		for _, v := range live {
+			if v.start.Before(qe) && v.end.After(qs) {
+				want++
+				some = v.id
+			}
+		}
+		got := 0
+		tree.overlapping(qs, qe, func(string) bool { got++; return true })
+		if got != want {
+			t.Fatalf("query [%v, %v): tree found %d overlaps, brute force %d", qs, qe, got, want)
+		}
+		if (tree.firstOverlap(qs, qe, "") != "") != (want > 0) {
+			t.Fatalf("firstOverlap disagrees with brute force for [%v, %v)", qs, qe)
+		}
+		// Leaving out one of the overlaps only matters if it was the only one.
+		if (tree.firstOverlap(qs, qe, some) != "") != (want > 1) {
+			t.Fatalf("firstOverlap except %s disagrees with brute force for [%v, %v)", some, qs, qe)
+		}
+	}
+}
+
+func TestReserveBookingAcrossAdjacentSlots(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRepo()
+	for _, s := range []*models.AvailabilitySlot{
+		{CarID: "car1", StartTime: day(1), EndTime: day(5)},
+		{CarID: "car1", StartTime: day(5), EndTime: day(10)},
+	} {
+		if err := repo.AddAvailability(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := &models.Booking{CarID: "car1", StartTime: day(3), EndTime: day(7), Status: models.BookingStatusConfirmed}
+	if err := repo.ReserveBooking(ctx, b, nil); err != nil {
+		t.Fatalf("Expected adjacent slots to cover Jan 3-7, got %v", err)
+	}
+
+	if err := repo.UpdateBookingStatus(ctx, b.ID, models.BookingStatusCancelled); err != nil {
+		t.Fatal(err)
+	}
+	again := &models.Booking{CarID: "car1", StartTime: day(4), EndTime: day(6), Status: models.BookingStatusConfirmed}
+	if err := repo.ReserveBooking(ctx, again, nil); err != nil {
+		t.Fatalf("Expected cancelled booking to free the window, got %v", err)
+	}
+}
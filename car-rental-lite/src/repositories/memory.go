@@ -1,9 +1,12 @@
 package repositories
 
 import (
+	"car-rental-lite/src/idgen"
 	"car-rental-lite/src/models"
 	"context"
-	"errors"
+	"fmt"
+	"math"
+	"sort"
 	"sync"
 	"time"
 )
@@ -13,23 +16,127 @@ type InMemoryRepo struct {
 	hosts    map[string]*models.Host
 	cars     map[string]*models.Car
 	bookings map[string]*models.Booking
+	refunds  map[string][]*models.Refund           // booking ID -> refunds
+	reports  map[string][]*models.InspectionReport // booking ID -> reports
+	claims   map[string]*models.DamageClaim
+	reviews  map[string]*models.Review
+	searches map[string]*models.SavedSearch
 	slots    map[string][]*models.AvailabilitySlot
+	slotCar  map[string]string // slot ID -> car ID
+	ruleCar  map[string]string // recurrence rule ID -> car ID
+	index    map[string]*carIndex
+	geo      *gridIndex
+	waitlist *searchIndex // ACTIVE saved searches
 	mu       sync.RWMutex
+	ids      idgen.IDGenerator
+
+	// carLocks serialises check-and-reserve per car so bookings for
+	// different cars don't contend with each other. userLocks does the same
+	// per renter for reservations with a BookingsCheck; a user's lock is
+	// only ever taken while holding a car's, never the other way round.
+	carLocks  map[string]*sync.Mutex
+	userLocks map[string]*sync.Mutex
+	locksMu   sync.Mutex
 }
 
 func NewInMemoryRepo() *InMemoryRepo {
+	return NewInMemoryRepoWithIDs(idgen.NewULIDGenerator())
+}
+
+// NewInMemoryRepoWithIDs uses ids to fill in the ID of any entity created
+// without one.
+func NewInMemoryRepoWithIDs(ids idgen.IDGenerator) *InMemoryRepo {
 	return &InMemoryRepo{
-		users:    make(map[string]*models.User),
-		hosts:    make(map[string]*models.Host),
-		cars:     make(map[string]*models.Car),
-		bookings: make(map[string]*models.Booking),
-		slots:    make(map[string][]*models.AvailabilitySlot),
+		ids:       ids,
+		users:     make(map[string]*models.User),
+		hosts:     make(map[string]*models.Host),
+		cars:      make(map[string]*models.Car),
+		bookings:  make(map[string]*models.Booking),
+		refunds:   make(map[string][]*models.Refund),
+		reports:   make(map[string][]*models.InspectionReport),
+		claims:    make(map[string]*models.DamageClaim),
+		reviews:   make(map[string]*models.Review),
+		searches:  make(map[string]*models.SavedSearch),
+		slots:     make(map[string][]*models.AvailabilitySlot),
+		slotCar:   make(map[string]string),
+		ruleCar:   make(map[string]string),
+		index:     make(map[string]*carIndex),
+		geo:       newGridIndex(),
+		waitlist:  newSearchIndex(),
+		carLocks:  make(map[string]*sync.Mutex),
+		userLocks: make(map[string]*sync.Mutex),
+	}
+}
+
+// lockCar acquires the per-car lock and returns its release func.
+func (r *InMemoryRepo) lockCar(carID string) func() {
+	return r.lockKey(r.carLocks, carID)
+}
+
+// lockUser acquires the per-renter lock and returns its release func. The
+// caller must already hold the car's lock.
+func (r *InMemoryRepo) lockUser(userID string) func() {
+	return r.lockKey(r.userLocks, userID)
+}
+
+func (r *InMemoryRepo) lockKey(locks map[string]*sync.Mutex, key string) func() {
+	r.locksMu.Lock()
+	l, ok := locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		locks[key] = l
+	}
+	r.locksMu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// carIndex holds per-car availability and booking indexes so lookups don't
+// scan every slot or booking in the system.
+type carIndex struct {
+	availability coverage
+	rules        []*models.RecurrenceRule
+	holds        intervalTree               // bookings whose status HoldsCar
+	bookings     map[string]*models.Booking // every booking for the car
+}
+
+func (r *InMemoryRepo) carIndexLocked(carID string) *carIndex {
+	idx, ok := r.index[carID]
+	if !ok {
+		idx = &carIndex{bookings: make(map[string]*models.Booking)}
+		r.index[carID] = idx
+	}
+	return idx
+}
+
+// putBookingLocked stores a copy of b, replacing any booking with the same
+// ID. Bookings are copied in and out so callers never share the stored value.
+func (r *InMemoryRepo) putBookingLocked(b *models.Booking) {
+	b = b.Clone()
+	if old, ok := r.bookings[b.ID]; ok {
+		idx := r.carIndexLocked(old.CarID)
+		idx.holds.remove(old.ID, old.StartTime)
+		delete(idx.bookings, old.ID)
+	}
+	r.bookings[b.ID] = b
+	idx := r.carIndexLocked(b.CarID)
+	idx.bookings[b.ID] = b
+	if b.Status.HoldsCar() {
+		idx.holds.insert(b.ID, b.StartTime, b.EndTime)
+	}
+}
+
+func (r *InMemoryRepo) ensureID(id *string) {
+	if *id == "" {
+		*id = r.ids.NewID()
 	}
 }
 
 func (r *InMemoryRepo) CreateUser(ctx context.Context, user *models.User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.ensureID(&user.ID)
 	r.users[user.ID] = user
 	return nil
 }
@@ -38,13 +145,14 @@ func (r *InMemoryRepo) GetUser(ctx context.Context, id string) (*models.User, er
 	defer r.mu.RUnlock()
 	u, ok := r.users[id]
 	if !ok {
-		return nil, errors.New("user not found")
+		return nil, &models.NotFoundError{Entity: "user", ID: id}
 	}
 	return u, nil
 }
 func (r *InMemoryRepo) CreateHost(ctx context.Context, host *models.Host) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.ensureID(&host.ID)
 	r.hosts[host.ID] = host
 	return nil
 }
@@ -53,7 +161,7 @@ func (r *InMemoryRepo) GetHost(ctx context.Context, id string) (*models.Host, er
 	defer r.mu.RUnlock()
 	h, ok := r.hosts[id]
 	if !ok {
-		return nil, errors.New("host not found")
+		return nil, &models.NotFoundError{Entity: "host", ID: id}
 	}
 	return h, nil
 }
@@ -61,7 +169,9 @@ func (r *InMemoryRepo) GetHost(ctx context.Context, id string) (*models.Host, er
 func (r *InMemoryRepo) CreateCar(ctx context.Context, car *models.Car) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.ensureID(&car.ID)
 	r.cars[car.ID] = car
+	r.geo.put(car.ID, car.Location)
 	return nil
 }
 func (r *InMemoryRepo) GetCar(ctx context.Context, id string) (*models.Car, error) {
@@ -69,10 +179,48 @@ func (r *InMemoryRepo) GetCar(ctx context.Context, id string) (*models.Car, erro
 	defer r.mu.RUnlock()
 	c, ok := r.cars[id]
 	if !ok {
-		return nil, errors.New("car not found")
+		return nil, &models.NotFoundError{Entity: "car", ID: id}
 	}
 	return c, nil
 }
+
+// SetCarActive holds the car's lock so a deactivation can't race a
+// reservation.
+func (r *InMemoryRepo) SetCarActive(ctx context.Context, carID string, active bool, t time.Time) error {
+	unlock := r.lockCar(carID)
+	defer unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.cars[carID]
+	if !ok {
+		return &models.NotFoundError{Entity: "car", ID: carID}
+	}
+	if !active {
+		if idx, ok := r.index[carID]; ok {
+			if b := earliestUpcoming(idx.bookings, t); b != nil {
+				return &models.ConflictError{Err: ErrCarHasBookings, BookingID: b.ID}
+			}
+		}
+	}
+	updated := *c
+	updated.IsActive = active
+	r.cars[carID] = &updated
+	return nil
+}
+
+// earliestUpcoming returns the first-starting booking that is Upcoming at t,
+// or nil.
+func earliestUpcoming(bookings map[string]*models.Booking, t time.Time) *models.Booking {
+	var first *models.Booking
+	for _, b := range bookings {
+		if b.Upcoming(t) && (first == nil || b.StartTime.Before(first.StartTime)) {
+			first = b
+		}
+	}
+	return first
+}
+
 func (r *InMemoryRepo) GetCarsByHost(ctx context.Context, hostID string) ([]*models.Car, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -84,22 +232,50 @@ func (r *InMemoryRepo) GetCarsByHost(ctx context.Context, hostID string) ([]*mod
 	}
 	return res, nil
 }
-func (r *InMemoryRepo) SearchCars(ctx context.Context, location models.Location, radiusKm float64) ([]*models.Car, error) {
+
+// SearchCars returns active cars within radiusKm of location, nearest first.
+// A non-positive radius matches every active car.
+func (r *InMemoryRepo) SearchCars(ctx context.Context, location models.Location, radiusKm float64) ([]*models.CarSearchResult, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	var res []*models.Car
-	for _, c := range r.cars {
-		if c.IsActive {
-			res = append(res, c)
+
+	var candidates []*models.Car
+	ids, ok := r.geo.candidates(location, radiusKm)
+	if radiusKm > 0 && ok {
+		for _, id := range ids {
+			candidates = append(candidates, r.cars[id])
+		}
+	} else {
+		for _, c := range r.cars {
+			candidates = append(candidates, c)
 		}
 	}
+
+	var res []*models.CarSearchResult
+	for _, c := range candidates {
+		if !c.IsActive {
+			continue
+		}
+		d := location.DistanceKm(c.Location)
+		if radiusKm > 0 && d > radiusKm {
+			continue
+		}
+		res = append(res, &models.CarSearchResult{Car: c, DistanceKm: d})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].DistanceKm != res[j].DistanceKm {
+			return res[i].DistanceKm < res[j].DistanceKm
+		}
+		return res[i].Car.ID < res[j].Car.ID
+	})
 	return res, nil
 }
 
 func (r *InMemoryRepo) CreateBooking(ctx context.Context, booking *models.Booking) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.bookings[booking.ID] = booking
+	r.ensureID(&booking.ID)
+	r.putBookingLocked(booking)
 	return nil
 }
 func (r *InMemoryRepo) GetBooking(ctx context.Context, id string) (*models.Booking, error) {
@@ -107,70 +283,730 @@ func (r *InMemoryRepo) GetBooking(ctx context.Context, id string) (*models.Booki
 	defer r.mu.RUnlock()
 	b, ok := r.bookings[id]
 	if !ok {
-		return nil, errors.New("booking not found")
+		return nil, &models.NotFoundError{Entity: "booking", ID: id}
 	}
-	return b, nil
+	return b.Clone(), nil
 }
 func (r *InMemoryRepo) UpdateBookingStatus(ctx context.Context, id string, status models.BookingStatus) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	_, err := r.transitionLocked(id, status)
+	return err
+}
+
+// transitionLocked moves a booking along the state machine, keeping the
+// car's booking index in step, and returns the stored booking.
+func (r *InMemoryRepo) transitionLocked(id string, status models.BookingStatus) (*models.Booking, error) {
 	b, ok := r.bookings[id]
 	if !ok {
-		return errors.New("booking not found")
+		return nil, &models.NotFoundError{Entity: "booking", ID: id}
+	}
+	if !b.Status.CanTransitionTo(status) {
+		return nil, &models.ConflictError{Err: fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, b.Status, status), BookingID: id}
+	}
+	idx := r.carIndexLocked(b.CarID)
+	if b.Status.HoldsCar() && !status.HoldsCar() {
+		idx.holds.remove(b.ID, b.StartTime)
+	} else if !b.Status.HoldsCar() && status.HoldsCar() {
+		idx.holds.insert(b.ID, b.StartTime, b.EndTime)
 	}
 	b.Status = status
+	return b, nil
+}
+func (r *InMemoryRepo) CancelBooking(ctx context.Context, bookingID string, by models.CancellationActor, computeRefund func(*models.Booking) *models.Refund) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.bookings[bookingID]
+	if !ok {
+		return &models.NotFoundError{Entity: "booking", ID: bookingID}
+	}
+	if !current.Status.CanTransitionTo(models.BookingStatusCancelled) {
+		return &models.ConflictError{Err: fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, current.Status, models.BookingStatusCancelled), BookingID: bookingID}
+	}
+	refund := computeRefund(current.Clone())
+	b, err := r.transitionLocked(bookingID, models.BookingStatusCancelled)
+	if err != nil {
+		return err
+	}
+	b.CancelledBy = by
+	b.CancelledAt = refund.CreatedAt
+	r.ensureID(&refund.ID)
+	stored := *refund
+	r.refunds[bookingID] = append(r.refunds[bookingID], &stored)
 	return nil
 }
+func (r *InMemoryRepo) MarkConfirmed(ctx context.Context, bookingID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, err := r.transitionLocked(bookingID, models.BookingStatusConfirmed)
+	if err != nil {
+		return err
+	}
+	b.ConfirmedAt = at
+	return nil
+}
+func (r *InMemoryRepo) markPickedUpLocked(bookingID string, at time.Time) error {
+	b, err := r.transitionLocked(bookingID, models.BookingStatusActive)
+	if err != nil {
+		return err
+	}
+	b.PickedUpAt = at
+	return nil
+}
+func (r *InMemoryRepo) markReturnedLocked(bookingID string, at time.Time, adjustments []models.PriceLineItem) error {
+	b, err := r.transitionLocked(bookingID, models.BookingStatusCompleted)
+	if err != nil {
+		return err
+	}
+	b.ReturnedAt = at
+	for _, adj := range adjustments {
+		b.Adjustments = append(b.Adjustments, adj)
+		b.TotalPrice += adj.Amount
+	}
+	b.TotalPrice = math.Round(b.TotalPrice*100) / 100
+	return nil
+}
+func (r *InMemoryRepo) GetRefundsForBooking(ctx context.Context, bookingID string) ([]*models.Refund, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*models.Refund
+	for _, rf := range r.refunds[bookingID] {
+		c := *rf
+		res = append(res, &c)
+	}
+	return res, nil
+}
+func (r *InMemoryRepo) ListBookingsByStatus(ctx context.Context, status models.BookingStatus) ([]*models.Booking, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*models.Booking
+	for _, b := range r.bookings {
+		if b.Status == status {
+			res = append(res, b.Clone())
+		}
+	}
+	return res, nil
+}
 func (r *InMemoryRepo) GetBookingsForCar(ctx context.Context, carID string, from, to time.Time) ([]*models.Booking, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*models.Booking
+	idx, ok := r.index[carID]
+	if !ok {
+		return res, nil
+	}
+	for _, b := range idx.bookings {
+		// Check overlap
+		if b.StartTime.Before(to) && b.EndTime.After(from) {
+			res = append(res, b.Clone())
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].StartTime.Before(res[j].StartTime) })
+	return res, nil
+}
+func (r *InMemoryRepo) GetUpcomingBookingsForCar(ctx context.Context, carID string, t time.Time) ([]*models.Booking, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*models.Booking
+	idx, ok := r.index[carID]
+	if !ok {
+		return res, nil
+	}
+	for _, b := range idx.bookings {
+		if b.Upcoming(t) {
+			res = append(res, b.Clone())
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].StartTime.Before(res[j].StartTime) })
+	return res, nil
+}
+func (r *InMemoryRepo) GetBookingsForUser(ctx context.Context, userID string, from, to time.Time) ([]*models.Booking, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	var res []*models.Booking
 	for _, b := range r.bookings {
-		if b.CarID == carID {
-			// Check overlap
-			if b.StartTime.Before(to) && b.EndTime.After(from) {
-				res = append(res, b)
-			}
+		if b.UserID == userID && b.StartTime.Before(to) && b.EndTime.After(from) {
+			res = append(res, b.Clone())
 		}
 	}
+	sort.Slice(res, func(i, j int) bool { return res[i].StartTime.Before(res[j].StartTime) })
 	return res, nil
 }
 func (r *InMemoryRepo) HasOverlappingBooking(ctx context.Context, carID string, start, end time.Time) (bool, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	return r.hasOverlapLocked(carID, start, end), nil
+}
+
+// ReserveBooking holds the car's lock across the availability check, the
+// overlap check and the insert, so two renters can't both pass the checks,
+// and the renter's lock too when there is a check to run.
+func (r *InMemoryRepo) ReserveBooking(ctx context.Context, booking *models.Booking, check BookingsCheck) error {
+	unlock := r.lockCar(booking.CarID)
+	defer unlock()
+	if check != nil {
+		unlockUser := r.lockUser(booking.UserID)
+		defer unlockUser()
+	}
+	// The request may have been abandoned while it queued for the car.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	car, known := r.cars[booking.CarID]
+	hasSlot := r.hasSlotLocked(booking.CarID, booking.StartTime, booking.EndTime)
+	overlapping := r.overlappingBookingLocked(booking.CarID, booking.StartTime, booking.EndTime, "")
+	var others []*models.Booking
+	if check != nil {
+		others = r.userBookingsLocked(booking.UserID, booking.StartTime, booking.EndTime, "")
+	}
+	r.mu.RUnlock()
+
+	if check != nil {
+		if err := check(others); err != nil {
+			return err
+		}
+	}
+	if known && !car.IsActive {
+		return &models.ConflictError{Err: ErrCarInactive}
+	}
+	if !hasSlot {
+		return &models.ConflictError{Err: ErrNoAvailability}
+	}
+	if overlapping != "" {
+		return &models.ConflictError{Err: ErrOverlap, BookingID: overlapping}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ensureID(&booking.ID)
+	r.putBookingLocked(booking)
+	return nil
+}
+
+// userBookingsLocked returns copies of the renter's bookings other than
+// except overlapping [start, end), in any status.
+func (r *InMemoryRepo) userBookingsLocked(userID string, start, end time.Time, except string) []*models.Booking {
+	var res []*models.Booking
 	for _, b := range r.bookings {
-		if b.CarID == carID && b.Status != models.BookingStatusCancelled {
-			// Overlap logic: (StartA < EndB) and (EndA > StartB)
-			if start.Before(b.EndTime) && end.After(b.StartTime) {
-				return true, nil
-			}
+		if b.UserID == userID && b.ID != except && b.StartTime.Before(end) && b.EndTime.After(start) {
+			res = append(res, b.Clone())
+		}
+	}
+	return res
+}
+
+func (r *InMemoryRepo) hasOverlapLocked(carID string, start, end time.Time) bool {
+	return r.overlappingBookingLocked(carID, start, end, "") != ""
+}
+
+// overlappingBookingLocked returns the ID of a booking other than except
+// holding the car during [start, end), or "" if there is none.
+func (r *InMemoryRepo) overlappingBookingLocked(carID string, start, end time.Time, except string) string {
+	idx, ok := r.index[carID]
+	if !ok {
+		return ""
+	}
+	return idx.holds.firstOverlap(start, end, except)
+}
+
+func (r *InMemoryRepo) ModifyBooking(ctx context.Context, bookingID string, mod *models.BookingModification, breakdown []models.PriceLineItem, check BookingsCheck) error {
+	r.mu.RLock()
+	b, ok := r.bookings[bookingID]
+	var userID string
+	if ok {
+		userID = b.UserID
+	}
+	r.mu.RUnlock()
+	if !ok {
+		return &models.NotFoundError{Entity: "booking", ID: bookingID}
+	}
+	unlock := r.lockCar(b.CarID)
+	defer unlock()
+	if check != nil {
+		unlockUser := r.lockUser(userID)
+		defer unlockUser()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b = r.bookings[bookingID]
+	if err := checkModifiable(b, mod.NewStart); err != nil {
+		return err
+	}
+	if !r.hasSlotLocked(b.CarID, mod.NewStart, mod.NewEnd) {
+		return &models.ConflictError{Err: ErrNoAvailability, BookingID: b.ID}
+	}
+	if overlapping := r.overlappingBookingLocked(b.CarID, mod.NewStart, mod.NewEnd, b.ID); overlapping != "" {
+		return &models.ConflictError{Err: ErrOverlap, BookingID: overlapping}
+	}
+	if check != nil {
+		if err := check(r.userBookingsLocked(b.UserID, mod.NewStart, mod.NewEnd, b.ID)); err != nil {
+			return err
 		}
 	}
-	return false, nil
+	idx := r.carIndexLocked(b.CarID)
+	idx.holds.remove(b.ID, b.StartTime)
+	recordModification(b, mod, breakdown)
+	idx.holds.insert(b.ID, b.StartTime, b.EndTime)
+	return nil
 }
 
 func (r *InMemoryRepo) AddAvailability(ctx context.Context, slot *models.AvailabilitySlot) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.ensureID(&slot.ID)
 	r.slots[slot.CarID] = append(r.slots[slot.CarID], slot)
+	r.slotCar[slot.ID] = slot.CarID
+	r.carIndexLocked(slot.CarID).availability.add(slot.StartTime, slot.EndTime)
 	return nil
 }
+
+// GetAvailability returns the car's slots overlapping [from, to), ordered by
+// start time.
 func (r *InMemoryRepo) GetAvailability(ctx context.Context, carID string, from, to time.Time) ([]*models.AvailabilitySlot, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	return r.slots[carID], nil
+	var res []*models.AvailabilitySlot
+	for _, s := range r.slots[carID] {
+		if s.StartTime.Before(to) && s.EndTime.After(from) {
+			res = append(res, s)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].StartTime.Before(res[j].StartTime) })
+	return res, nil
 }
+func (r *InMemoryRepo) GetAvailabilitySlot(ctx context.Context, slotID string) (*models.AvailabilitySlot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, s := range r.slots[r.slotCar[slotID]] {
+		if s.ID == slotID {
+			return s, nil
+		}
+	}
+	return nil, &models.NotFoundError{Entity: "availability slot", ID: slotID}
+}
+
+// RemoveAvailability deletes a slot unconditionally. Use ReplaceAvailability
+// to protect existing bookings.
 func (r *InMemoryRepo) RemoveAvailability(ctx context.Context, slotID string) error {
+	slot, err := r.GetAvailabilitySlot(ctx, slotID)
+	if err != nil {
+		return err
+	}
+	return r.ReplaceAvailability(ctx, slot.CarID, []string{slotID}, nil, true)
+}
+
+// ReplaceAvailability holds the car's lock so a booking can't be reserved
+// against slots that are about to disappear.
+func (r *InMemoryRepo) ReplaceAvailability(ctx context.Context, carID string, removeIDs []string, add []*models.AvailabilitySlot, force bool) error {
+	unlock := r.lockCar(carID)
+	defer unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	remove := make(map[string]bool, len(removeIDs))
+	for _, id := range removeIDs {
+		remove[id] = true
+	}
+	var next []*models.AvailabilitySlot
+	for _, s := range r.slots[carID] {
+		if remove[s.ID] {
+			delete(remove, s.ID)
+			continue
+		}
+		next = append(next, s)
+	}
+	for id := range remove {
+		return &models.NotFoundError{Entity: "availability slot", ID: id}
+	}
+	for _, s := range add {
+		if s.CarID != carID {
+			return &models.ValidationError{Field: "car_id", Message: "availability slot belongs to a different car"}
+		}
+		if !s.StartTime.Before(s.EndTime) {
+			return &models.ValidationError{Field: "end_time", Message: "start time must be before end time"}
+		}
+		next = append(next, s)
+	}
+
+	var cov coverage
+	for _, s := range next {
+		cov.add(s.StartTime, s.EndTime)
+	}
+	idx := r.carIndexLocked(carID)
+	if !force {
+		for _, b := range idx.bookings {
+			if b.Status.IsOpen() && !coversWithRules(&cov, idx.rules, b.StartTime, b.EndTime) {
+				return &models.ConflictError{Err: ErrStrandedBooking, BookingID: b.ID}
+			}
+		}
+	}
+
+	for _, id := range removeIDs {
+		delete(r.slotCar, id)
+	}
+	for _, s := range add {
+		r.ensureID(&s.ID)
+		r.slotCar[s.ID] = carID
+	}
+	r.slots[carID] = next
+	idx.availability = cov
 	return nil
 }
 func (r *InMemoryRepo) HasAvailabilitySlot(ctx context.Context, carID string, start, end time.Time) (bool, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	slots := r.slots[carID]
-	for _, s := range slots {
-		if !s.StartTime.After(start) && !s.EndTime.Before(end) {
-			return true, nil
+	return r.hasSlotLocked(carID, start, end), nil
+}
+
+// FilterAvailable checks many cars under one lock, instead of one
+// HasAvailabilitySlot/HasOverlappingBooking pair per car.
+func (r *InMemoryRepo) FilterAvailable(ctx context.Context, carIDs []string, start, end time.Time) (map[string]bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make(map[string]bool, len(carIDs))
+	for _, id := range carIDs {
+		if r.hasSlotLocked(id, start, end) && !r.hasOverlapLocked(id, start, end) {
+			res[id] = true
+		}
+	}
+	return res, nil
+}
+
+// hasSlotLocked reports whether the car's merged availability, including
+// recurring rules, covers the window, so adjacent slots and rule occurrences
+// can jointly satisfy a booking.
+func (r *InMemoryRepo) hasSlotLocked(carID string, start, end time.Time) bool {
+	idx, ok := r.index[carID]
+	return ok && coversWithRules(&idx.availability, idx.rules, start, end)
+}
+
+func (r *InMemoryRepo) AddRecurrenceRule(ctx context.Context, rule *models.RecurrenceRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ensureID(&rule.ID)
+	idx := r.carIndexLocked(rule.CarID)
+	idx.rules = append(idx.rules, rule)
+	r.ruleCar[rule.ID] = rule.CarID
+	return nil
+}
+func (r *InMemoryRepo) GetRecurrenceRules(ctx context.Context, carID string) ([]*models.RecurrenceRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	idx, ok := r.index[carID]
+	if !ok {
+		return nil, nil
+	}
+	return append([]*models.RecurrenceRule(nil), idx.rules...), nil
+}
+
+func (r *InMemoryRepo) GetRecurrenceRule(ctx context.Context, ruleID string) (*models.RecurrenceRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if idx, ok := r.index[r.ruleCar[ruleID]]; ok {
+		for _, rule := range idx.rules {
+			if rule.ID == ruleID {
+				return rule, nil
+			}
+		}
+	}
+	return nil, &models.NotFoundError{Entity: "recurrence rule", ID: ruleID}
+}
+
+// RemoveRecurrenceRule deletes a rule under the car's lock. Unless force is
+// set it fails with ErrStrandedBooking if an open booking relied on it.
+func (r *InMemoryRepo) RemoveRecurrenceRule(ctx context.Context, ruleID string, force bool) error {
+	r.mu.RLock()
+	carID, ok := r.ruleCar[ruleID]
+	r.mu.RUnlock()
+	if !ok {
+		return &models.NotFoundError{Entity: "recurrence rule", ID: ruleID}
+	}
+
+	unlock := r.lockCar(carID)
+	defer unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	idx := r.carIndexLocked(carID)
+	var rest []*models.RecurrenceRule
+	for _, rule := range idx.rules {
+		if rule.ID != ruleID {
+			rest = append(rest, rule)
+		}
+	}
+	if !force {
+		for _, b := range idx.bookings {
+			if b.Status.IsOpen() && !coversWithRules(&idx.availability, rest, b.StartTime, b.EndTime) {
+				return &models.ConflictError{Err: ErrStrandedBooking, BookingID: b.ID}
+			}
+		}
+	}
+	idx.rules = rest
+	delete(r.ruleCar, ruleID)
+	return nil
+}
+
+func (r *InMemoryRepo) RecordTripInspection(ctx context.Context, report *models.InspectionReport, adjustments []models.PriceLineItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkInspectionLocked(report); err != nil {
+		return err
+	}
+	// The transition checks the status before changing anything, so a
+	// refused one leaves no report behind.
+	var err error
+	switch report.Type {
+	case models.InspectionTypePickup:
+		err = r.markPickedUpLocked(report.BookingID, report.CreatedAt)
+	case models.InspectionTypeDropoff:
+		err = r.markReturnedLocked(report.BookingID, report.CreatedAt, adjustments)
+	default:
+		err = fmt.Errorf("unknown inspection type %q", report.Type)
+	}
+	if err != nil {
+		return err
+	}
+	r.ensureID(&report.ID)
+	r.reports[report.BookingID] = append(r.reports[report.BookingID], report.Clone())
+	return nil
+}
+
+// checkInspectionLocked allows one report of each type per booking.
+func (r *InMemoryRepo) checkInspectionLocked(report *models.InspectionReport) error {
+	for _, existing := range r.reports[report.BookingID] {
+		if existing.Type == report.Type {
+			return &models.ConflictError{Err: ErrDuplicateInspection, BookingID: report.BookingID}
+		}
+	}
+	return nil
+}
+func (r *InMemoryRepo) GetInspectionsForBooking(ctx context.Context, bookingID string) ([]*models.InspectionReport, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*models.InspectionReport
+	for _, rep := range r.reports[bookingID] {
+		res = append(res, rep.Clone())
+	}
+	return res, nil
+}
+
+func (r *InMemoryRepo) CreateSavedSearch(ctx context.Context, search *models.SavedSearch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ensureID(&search.ID)
+	r.searches[search.ID] = search.Clone()
+	if search.Status == models.SavedSearchActive {
+		r.waitlist.put(search.ID, search.Query.Location, search.Query.RadiusKm)
+	}
+	return nil
+}
+
+func (r *InMemoryRepo) GetSavedSearch(ctx context.Context, id string) (*models.SavedSearch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.searches[id]
+	if !ok {
+		return nil, &models.NotFoundError{Entity: "saved search", ID: id}
+	}
+	return s.Clone(), nil
+}
+
+func (r *InMemoryRepo) GetSavedSearchesForUser(ctx context.Context, userID string) ([]*models.SavedSearch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*models.SavedSearch
+	for _, s := range r.searches {
+		if s.UserID == userID {
+			res = append(res, s.Clone())
+		}
+	}
+	sortSavedSearches(res)
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+	return res, nil
+}
+
+// CandidateSavedSearches only visits the searches indexed near the car.
+func (r *InMemoryRepo) CandidateSavedSearches(ctx context.Context, car *models.Car, start, end time.Time) ([]*models.SavedSearch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*models.SavedSearch
+	for _, id := range r.waitlist.candidates(car.Location) {
+		s := r.searches[id]
+		q := &s.Query
+		if !q.StartTime.Before(end) || !q.EndTime.After(start) {
+			continue
+		}
+		if q.RadiusKm > 0 && car.Location.DistanceKm(q.Location) > q.RadiusKm {
+			continue
+		}
+		res = append(res, s.Clone())
+	}
+	sortSavedSearches(res)
+	return res, nil
+}
+
+// sortSavedSearches orders searches oldest first.
+func sortSavedSearches(s []*models.SavedSearch) {
+	sort.Slice(s, func(i, j int) bool {
+		if !s[i].CreatedAt.Equal(s[j].CreatedAt) {
+			return s[i].CreatedAt.Before(s[j].CreatedAt)
+		}
+		return s[i].ID < s[j].ID
+	})
+}
+
+func (r *InMemoryRepo) CloseSavedSearch(ctx context.Context, id string, status models.SavedSearchStatus, carID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.searches[id]
+	if !ok {
+		return &models.NotFoundError{Entity: "saved search", ID: id}
+	}
+	if s.Status != models.SavedSearchActive {
+		return &models.ConflictError{Err: fmt.Errorf("%w: saved search is already %s", ErrInvalidTransition, s.Status)}
+	}
+	updated := s.Clone()
+	updated.Status, updated.ClosedAt, updated.MatchedCarID = status, at, carID
+	r.searches[id] = updated
+	r.waitlist.remove(id)
+	return nil
+}
+
+func (r *InMemoryRepo) CreateClaim(ctx context.Context, claim *models.DamageClaim) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ensureID(&claim.ID)
+	r.claims[claim.ID] = claim.Clone()
+	return nil
+}
+func (r *InMemoryRepo) GetClaim(ctx context.Context, id string) (*models.DamageClaim, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.claims[id]
+	if !ok {
+		return nil, &models.NotFoundError{Entity: "claim", ID: id}
+	}
+	return c.Clone(), nil
+}
+func (r *InMemoryRepo) GetClaimsForBooking(ctx context.Context, bookingID string) ([]*models.DamageClaim, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*models.DamageClaim
+	for _, c := range r.claims {
+		if c.BookingID == bookingID {
+			res = append(res, c.Clone())
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].CreatedAt.Before(res[j].CreatedAt) })
+	return res, nil
+}
+func (r *InMemoryRepo) UpdateClaimStatus(ctx context.Context, id string, status models.ClaimStatus, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.claims[id]
+	if !ok {
+		return &models.NotFoundError{Entity: "claim", ID: id}
+	}
+	if !c.Status.CanTransitionTo(status) {
+		return &models.ConflictError{Err: fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, c.Status, status), BookingID: c.BookingID}
+	}
+	c.Status = status
+	c.UpdatedAt = at
+	return nil
+}
+
+func (r *InMemoryRepo) CreateReview(ctx context.Context, review *models.Review) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.reviews {
+		if existing.BookingID == review.BookingID && existing.Role == review.Role {
+			return &models.ConflictError{Err: ErrDuplicateReview, BookingID: review.BookingID}
+		}
+	}
+	r.ensureID(&review.ID)
+	stored := *review
+	r.reviews[review.ID] = &stored
+	return nil
+}
+func (r *InMemoryRepo) GetReviewsForBooking(ctx context.Context, bookingID string) ([]*models.Review, error) {
+	return r.findReviews(func(rv *models.Review) bool { return rv.BookingID == bookingID }), nil
+}
+func (r *InMemoryRepo) GetReviewsForCar(ctx context.Context, carID string) ([]*models.Review, error) {
+	return r.findReviews(func(rv *models.Review) bool { return rv.CarID == carID && rv.Role == models.ReviewerRenter }), nil
+}
+func (r *InMemoryRepo) GetReviewsForUser(ctx context.Context, userID string) ([]*models.Review, error) {
+	return r.findReviews(func(rv *models.Review) bool { return rv.UserID == userID && rv.Role == models.ReviewerHost }), nil
+}
+func (r *InMemoryRepo) ListDueReviews(ctx context.Context, t time.Time) ([]*models.Review, error) {
+	return r.findReviews(func(rv *models.Review) bool { return !rv.Published() && !rv.RevealAt.After(t) }), nil
+}
+
+// findReviews returns copies of the matching reviews, newest first.
+func (r *InMemoryRepo) findReviews(match func(*models.Review) bool) []*models.Review {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*models.Review
+	for _, rv := range r.reviews {
+		if match(rv) {
+			c := *rv
+			res = append(res, &c)
 		}
 	}
-	return false, nil
+	sort.Slice(res, func(i, j int) bool {
+		if !res[i].CreatedAt.Equal(res[j].CreatedAt) {
+			return res[i].CreatedAt.After(res[j].CreatedAt)
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
+// PublishReviews replaces the car, host and user records rather than
+// updating them in place, since GetCar and friends hand out the stored
+// pointers.
+func (r *InMemoryRepo) PublishReviews(ctx context.Context, ids []string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range ids {
+		if _, ok := r.reviews[id]; !ok {
+			return &models.NotFoundError{Entity: "review", ID: id}
+		}
+	}
+	for _, id := range ids {
+		rv := r.reviews[id]
+		if rv.Published() {
+			continue
+		}
+		rv.PublishedAt = at
+		switch rv.Role {
+		case models.ReviewerRenter:
+			if c, ok := r.cars[rv.CarID]; ok {
+				updated := *c
+				updated.Rating = c.Rating.Add(rv.Rating)
+				r.cars[c.ID] = &updated
+			}
+			if h, ok := r.hosts[rv.HostID]; ok {
+				updated := *h
+				updated.Rating = h.Rating.Add(rv.Rating)
+				r.hosts[h.ID] = &updated
+			}
+		case models.ReviewerHost:
+			if u, ok := r.users[rv.UserID]; ok {
+				updated := *u
+				updated.Rating = u.Rating.Add(rv.Rating)
+				r.users[u.ID] = &updated
+			}
+		}
+	}
+	return nil
 }
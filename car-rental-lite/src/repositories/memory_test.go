@@ -0,0 +1,206 @@
+package repositories
+
+import (
+	"car-rental-lite/src/idgen"
+	"car-rental-lite/src/models"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newRepoWithCar(t *testing.T, carID string) *InMemoryRepo {
+	t.Helper()
+	ctx := context.Background()
+	repo := NewInMemoryRepo()
+	if err := repo.CreateCar(ctx, &models.Car{ID: carID, HostID: "host1", IsActive: true}); err != nil {
+		t.Fatal(err)
+	}
+	slot := &models.AvailabilitySlot{
+		ID:        "slot-" + carID,
+		CarID:     carID,
+		StartTime: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := repo.AddAvailability(ctx, slot); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestReserveBookingConcurrentSameWindow(t *testing.T) {
+	repo := newRepoWithCar(t, "car1")
+	start := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 4, 10, 0, 0, 0, time.UTC)
+
+	const renters = 500
+	var wg sync.WaitGroup
+	errs := make(chan error, renters)
+	for i := 0; i < renters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- repo.ReserveBooking(context.Background(), &models.Booking{
+				ID:        fmt.Sprintf("b%d", i),
+				CarID:     "car1",
+				UserID:    fmt.Sprintf("user%d", i),
+				StartTime: start,
+				EndTime:   end,
+				Status:    models.BookingStatusConfirmed,
+			}, nil)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	confirmed := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			confirmed++
+		case !errors.Is(err, ErrOverlap):
+			t.Errorf("Expected ErrOverlap, got %v", err)
+		}
+	}
+	if confirmed != 1 {
+		t.Fatalf("Expected exactly 1 confirmed booking, got %d", confirmed)
+	}
+}
+
+func TestReserveBookingConcurrentNoOverbooking(t *testing.T) {
+	repo := newRepoWithCar(t, "car1")
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Staggered windows of 1-3 days starting on every 6 hours of the month,
+	// so many requests partially overlap each other.
+	const renters = 400
+	var wg sync.WaitGroup
+	for i := 0; i < renters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			start := base.Add(time.Duration(i%100) * 6 * time.Hour)
+			end := start.Add(time.Duration(1+i%3) * 24 * time.Hour)
+			err := repo.ReserveBooking(context.Background(), &models.Booking{
+				ID:        fmt.Sprintf("b%d", i),
+				CarID:     "car1",
+				StartTime: start,
+				EndTime:   end,
+				Status:    models.BookingStatusConfirmed,
+			}, nil)
+			if err != nil && !errors.Is(err, ErrOverlap) && !errors.Is(err, ErrNoAvailability) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	bookings, err := repo.GetBookingsForCar(context.Background(), "car1", base, base.AddDate(0, 2, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bookings) == 0 {
+		t.Fatal("Expected at least one booking to succeed")
+	}
+	for i, a := range bookings {
+		for _, b := range bookings[i+1:] {
+			if a.StartTime.Before(b.EndTime) && a.EndTime.After(b.StartTime) {
+				t.Errorf("Overbooked: %s [%v, %v) overlaps %s [%v, %v)", a.ID, a.StartTime, a.EndTime, b.ID, b.StartTime, b.EndTime)
+			}
+		}
+	}
+}
+
+func TestReserveBookingOutsideAvailability(t *testing.T) {
+	repo := newRepoWithCar(t, "car1")
+	err := repo.ReserveBooking(context.Background(), &models.Booking{
+		ID:        "b1",
+		CarID:     "car1",
+		StartTime: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+		Status:    models.BookingStatusConfirmed,
+	}, nil)
+	if !errors.Is(err, ErrNoAvailability) {
+		t.Fatalf("Expected ErrNoAvailability, got %v", err)
+	}
+}
+
+func TestCreateBookingAssignsUniqueIDs(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRepoWithIDs(idgen.NewSequenceGenerator("bk"))
+	first := &models.Booking{CarID: "car1"}
+	second := &models.Booking{CarID: "car2"}
+	if err := repo.CreateBooking(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateBooking(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != "bk-000000000001" || second.ID != "bk-000000000002" {
+		t.Fatalf("Expected sequential IDs, got %q and %q", first.ID, second.ID)
+	}
+	if b, err := repo.GetBooking(ctx, first.ID); err != nil || b.CarID != "car1" {
+		t.Errorf("Expected first booking to survive the second insert, got %v, %v", b, err)
+	}
+}
+
+func TestRecordTripInspectionIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepoWithCar(t, "car1")
+	b := &models.Booking{
+		ID:         "b1",
+		CarID:      "car1",
+		StartTime:  time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+		EndTime:    time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC),
+		Status:     models.BookingStatusPending,
+		TotalPrice: 200,
+	}
+	if err := repo.ReserveBooking(ctx, b, nil); err != nil {
+		t.Fatal(err)
+	}
+	pickup := &models.InspectionReport{BookingID: "b1", Type: models.InspectionTypePickup, CreatedAt: b.StartTime}
+
+	// A pending booking can't be picked up, so the report isn't kept either.
+	if err := repo.RecordTripInspection(ctx, pickup, nil); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Expected ErrInvalidTransition, got %v", err)
+	}
+	if reports, _ := repo.GetInspectionsForBooking(ctx, "b1"); len(reports) != 0 {
+		t.Fatalf("Expected no reports after a refused pickup, got %d", len(reports))
+	}
+
+	if err := repo.MarkConfirmed(ctx, "b1", b.StartTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RecordTripInspection(ctx, pickup, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.GetBooking(ctx, "b1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.BookingStatusActive || !got.PickedUpAt.Equal(b.StartTime) {
+		t.Errorf("Expected ACTIVE picked up at %v, got %s at %v", b.StartTime, got.Status, got.PickedUpAt)
+	}
+	if err := repo.RecordTripInspection(ctx, pickup, nil); !errors.Is(err, ErrDuplicateInspection) {
+		t.Errorf("Expected ErrDuplicateInspection, got %v", err)
+	}
+
+	returned := b.EndTime.Add(3 * time.Hour)
+	dropoff := &models.InspectionReport{BookingID: "b1", Type: models.InspectionTypeDropoff, CreatedAt: returned}
+	adjustments := []models.PriceLineItem{{Component: models.PriceComponentLateReturn, Amount: 25}, {Component: models.PriceComponentFuelRefill, Amount: 12.5}}
+	if err := repo.RecordTripInspection(ctx, dropoff, adjustments); err != nil {
+		t.Fatal(err)
+	}
+	done, err := repo.GetBooking(ctx, "b1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != models.BookingStatusCompleted || done.TotalPrice != 237.5 || len(done.Adjustments) != 2 || !done.ReturnedAt.Equal(returned) {
+		t.Errorf("Expected COMPLETED at 237.50 with 2 adjustments, returned at %v, got %s at %.2f with %d, returned at %v", returned, done.Status, done.TotalPrice, len(done.Adjustments), done.ReturnedAt)
+	}
+	if reports, _ := repo.GetInspectionsForBooking(ctx, "b1"); len(reports) != 2 {
+		t.Errorf("Expected the pickup and dropoff reports, got %d", len(reports))
+	}
+}
@@ -0,0 +1,996 @@
+package repositories
+
+import (
+	"car-rental-lite/src/idgen"
+	"car-rental-lite/src/models"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SQLRepo implements UserRepository, CarRepository, BookingRepository and
+// InventoryRepository on top of GORM. Checks that must not race, like
+// ReserveBooking, run inside a transaction that first takes the car's row
+// lock, so they hold across processes sharing the database.
+type SQLRepo struct {
+	db  *gorm.DB
+	ids idgen.IDGenerator
+}
+
+func NewSQLRepo(db *gorm.DB) *SQLRepo {
+	return NewSQLRepoWithIDs(db, idgen.NewULIDGenerator())
+}
+
+// NewSQLRepoWithIDs uses ids to fill in the ID of any entity created without
+// one.
+func NewSQLRepoWithIDs(db *gorm.DB, ids idgen.IDGenerator) *SQLRepo {
+	return &SQLRepo{db: db, ids: ids}
+}
+
+func (r *SQLRepo) ensureID(id *string) {
+	if *id == "" {
+		*id = r.ids.NewID()
+	}
+}
+
+// notFound maps gorm.ErrRecordNotFound to the *models.NotFoundError
+// InMemoryRepo returns.
+func notFound(err error, entity, id string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.NotFoundError{Entity: entity, ID: id}
+	}
+	return err
+}
+
+func toNanos(t time.Time) int64 {
+	return t.UnixNano()
+}
+
+func fromNanos(n int64) time.Time {
+	return time.Unix(0, n).UTC()
+}
+
+// releasedStatuses are the booking statuses that no longer hold the car.
+var releasedStatuses = []models.BookingStatus{models.BookingStatusCancelled, models.BookingStatusExpired}
+
+var openStatuses = []models.BookingStatus{models.BookingStatusPending, models.BookingStatusConfirmed, models.BookingStatusActive}
+
+func (r *SQLRepo) CreateUser(ctx context.Context, user *models.User) error {
+	r.ensureID(&user.ID)
+	row := userRow{ID: user.ID, Name: user.Name, Email: user.Email, PhoneNumber: user.PhoneNumber, DriverLicense: user.DriverLicense,
+		LicenseExpiresAt: user.LicenseExpiresAt, DateOfBirth: user.DateOfBirth, Banned: user.Banned, CreatedAt: user.CreatedAt}
+	return r.db.WithContext(ctx).Create(&row).Error
+}
+func (r *SQLRepo) GetUser(ctx context.Context, id string) (*models.User, error) {
+	var row userRow
+	if err := r.db.WithContext(ctx).First(&row, "id = ?", id).Error; err != nil {
+		return nil, notFound(err, "user", id)
+	}
+	return &models.User{ID: row.ID, Name: row.Name, Email: row.Email, PhoneNumber: row.PhoneNumber, DriverLicense: row.DriverLicense,
+		LicenseExpiresAt: row.LicenseExpiresAt, DateOfBirth: row.DateOfBirth, Banned: row.Banned,
+		Rating: models.Rating{Average: row.RatingAverage, Count: row.RatingCount}, CreatedAt: row.CreatedAt}, nil
+}
+func (r *SQLRepo) CreateHost(ctx context.Context, host *models.Host) error {
+	r.ensureID(&host.ID)
+	row := hostRow{ID: host.ID, Name: host.Name, Email: host.Email, PhoneNumber: host.PhoneNumber, CancellationPolicy: host.CancellationPolicy, CreatedAt: host.CreatedAt}
+	return r.db.WithContext(ctx).Create(&row).Error
+}
+func (r *SQLRepo) GetHost(ctx context.Context, id string) (*models.Host, error) {
+	var row hostRow
+	if err := r.db.WithContext(ctx).First(&row, "id = ?", id).Error; err != nil {
+		return nil, notFound(err, "host", id)
+	}
+	return &models.Host{ID: row.ID, Name: row.Name, Email: row.Email, PhoneNumber: row.PhoneNumber, CancellationPolicy: row.CancellationPolicy,
+		Rating: models.Rating{Average: row.RatingAverage, Count: row.RatingCount}, CreatedAt: row.CreatedAt}, nil
+}
+
+func toCarRow(c *models.Car) *carRow {
+	return &carRow{
+		ID:            c.ID,
+		HostID:        c.HostID,
+		Make:          c.Make,
+		Model:         c.Model,
+		Year:          c.Year,
+		Type:          c.Type,
+		Latitude:      c.Location.Latitude,
+		Longitude:     c.Location.Longitude,
+		Address:       c.Location.Address,
+		City:          c.Location.City,
+		ZipCode:       c.Location.ZipCode,
+		TimeZone:      c.Location.TimeZone,
+		PricePerDay:   c.PricePerDay,
+		PricePerHour:  c.PricePerHour,
+		LicensePlate:  c.LicensePlate,
+		Amenities:     c.Amenities,
+		IsActive:      c.IsActive,
+		RatingAverage: c.Rating.Average,
+		RatingCount:   c.Rating.Count,
+	}
+}
+
+func (row *carRow) toModel() *models.Car {
+	return &models.Car{
+		ID:     row.ID,
+		HostID: row.HostID,
+		Make:   row.Make,
+		Model:  row.Model,
+		Year:   row.Year,
+		Type:   row.Type,
+		Location: models.Location{
+			Latitude:  row.Latitude,
+			Longitude: row.Longitude,
+			Address:   row.Address,
+			City:      row.City,
+			ZipCode:   row.ZipCode,
+			TimeZone:  row.TimeZone,
+		},
+		PricePerDay:  row.PricePerDay,
+		PricePerHour: row.PricePerHour,
+		LicensePlate: row.LicensePlate,
+		Amenities:    row.Amenities,
+		IsActive:     row.IsActive,
+		Rating:       models.Rating{Average: row.RatingAverage, Count: row.RatingCount},
+	}
+}
+
+func (r *SQLRepo) CreateCar(ctx context.Context, car *models.Car) error {
+	r.ensureID(&car.ID)
+	return r.db.WithContext(ctx).Create(toCarRow(car)).Error
+}
+func (r *SQLRepo) GetCar(ctx context.Context, id string) (*models.Car, error) {
+	var row carRow
+	if err := r.db.WithContext(ctx).First(&row, "id = ?", id).Error; err != nil {
+		return nil, notFound(err, "car", id)
+	}
+	return row.toModel(), nil
+}
+func (r *SQLRepo) GetCarsByHost(ctx context.Context, hostID string) ([]*models.Car, error) {
+	var rows []carRow
+	if err := r.db.WithContext(ctx).Where("host_id = ?", hostID).Order("id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	res := make([]*models.Car, 0, len(rows))
+	for i := range rows {
+		res = append(res, rows[i].toModel())
+	}
+	return res, nil
+}
+
+// SetCarActive locks the car's row so a deactivation can't race a
+// reservation.
+func (r *SQLRepo) SetCarActive(ctx context.Context, carID string, active bool, t time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockCarTx(tx, carID); err != nil {
+			return err
+		}
+		if !active {
+			upcoming, err := upcomingBookingsTx(tx, carID, t)
+			if err != nil {
+				return err
+			}
+			if len(upcoming) > 0 {
+				return &models.ConflictError{Err: ErrCarHasBookings, BookingID: upcoming[0].ID}
+			}
+		}
+		return tx.Model(&carRow{}).Where("id = ?", carID).Update("is_active", active).Error
+	})
+}
+
+// SearchCars narrows candidates with a lat/lng bounding box on the indexed
+// columns, then filters and orders them by haversine distance. A non-positive
+// radius matches every active car.
+func (r *SQLRepo) SearchCars(ctx context.Context, location models.Location, radiusKm float64) ([]*models.CarSearchResult, error) {
+	q := r.db.WithContext(ctx).Where("is_active = ?", true)
+	if radiusKm > 0 {
+		dLat := radiusKm / 111.0
+		q = q.Where("latitude BETWEEN ? AND ?", location.Latitude-dLat, location.Latitude+dLat)
+		// Longitude degrees shrink towards the poles; skip the longitude
+		// bound where the box would wrap or degenerate.
+		if cos := math.Cos(location.Latitude * math.Pi / 180); cos > 0.01 {
+			dLng := radiusKm / (111.0 * cos)
+			if location.Longitude-dLng > -180 && location.Longitude+dLng < 180 {
+				q = q.Where("longitude BETWEEN ? AND ?", location.Longitude-dLng, location.Longitude+dLng)
+			}
+		}
+	}
+	var rows []carRow
+	if err := q.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var res []*models.CarSearchResult
+	for i := range rows {
+		c := rows[i].toModel()
+		d := location.DistanceKm(c.Location)
+		if radiusKm > 0 && d > radiusKm {
+			continue
+		}
+		res = append(res, &models.CarSearchResult{Car: c, DistanceKm: d})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].DistanceKm != res[j].DistanceKm {
+			return res[i].DistanceKm < res[j].DistanceKm
+		}
+		return res[i].Car.ID < res[j].Car.ID
+	})
+	return res, nil
+}
+
+func toBookingRow(b *models.Booking) *bookingRow {
+	return &bookingRow{
+		ID:             b.ID,
+		CarID:          b.CarID,
+		UserID:         b.UserID,
+		StartTime:      toNanos(b.StartTime),
+		EndTime:        toNanos(b.EndTime),
+		Status:         b.Status,
+		TotalPrice:     b.TotalPrice,
+		PriceBreakdown: b.PriceBreakdown,
+		Adjustments:    b.Adjustments,
+		Modifications:  b.Modifications,
+		HoldExpiresAt:  b.HoldExpiresAt,
+		ConfirmedAt:    b.ConfirmedAt,
+		PickedUpAt:     b.PickedUpAt,
+		ReturnedAt:     b.ReturnedAt,
+		CancelledBy:    b.CancelledBy,
+		CancelledAt:    b.CancelledAt,
+		CreatedAt:      b.CreatedAt,
+	}
+}
+
+func (row *bookingRow) toModel() *models.Booking {
+	return &models.Booking{
+		ID:             row.ID,
+		CarID:          row.CarID,
+		UserID:         row.UserID,
+		StartTime:      fromNanos(row.StartTime),
+		EndTime:        fromNanos(row.EndTime),
+		Status:         row.Status,
+		TotalPrice:     row.TotalPrice,
+		PriceBreakdown: row.PriceBreakdown,
+		Adjustments:    row.Adjustments,
+		Modifications:  row.Modifications,
+		HoldExpiresAt:  row.HoldExpiresAt,
+		ConfirmedAt:    row.ConfirmedAt,
+		PickedUpAt:     row.PickedUpAt,
+		ReturnedAt:     row.ReturnedAt,
+		CancelledBy:    row.CancelledBy,
+		CancelledAt:    row.CancelledAt,
+		CreatedAt:      row.CreatedAt,
+	}
+}
+
+func bookingsFromRows(rows []bookingRow) []*models.Booking {
+	res := make([]*models.Booking, 0, len(rows))
+	for i := range rows {
+		res = append(res, rows[i].toModel())
+	}
+	return res
+}
+
+func (r *SQLRepo) CreateBooking(ctx context.Context, booking *models.Booking) error {
+	r.ensureID(&booking.ID)
+	return r.db.WithContext(ctx).Create(toBookingRow(booking)).Error
+}
+func (r *SQLRepo) GetBooking(ctx context.Context, id string) (*models.Booking, error) {
+	var row bookingRow
+	if err := r.db.WithContext(ctx).First(&row, "id = ?", id).Error; err != nil {
+		return nil, notFound(err, "booking", id)
+	}
+	return row.toModel(), nil
+}
+func (r *SQLRepo) UpdateBookingStatus(ctx context.Context, id string, status models.BookingStatus) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return transitionTx(tx, id, status, nil)
+	})
+}
+
+// transitionTx moves a booking along the state machine. update may change
+// other fields of the row and returns their column names. The write is
+// conditional on the status that was read, so a concurrent change fails with
+// ErrInvalidTransition instead of being overwritten.
+func transitionTx(tx *gorm.DB, id string, status models.BookingStatus, update func(row *bookingRow) []string) error {
+	var row bookingRow
+	if err := tx.First(&row, "id = ?", id).Error; err != nil {
+		return notFound(err, "booking", id)
+	}
+	from := row.Status
+	if !from.CanTransitionTo(status) {
+		return &models.ConflictError{Err: fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, status), BookingID: id}
+	}
+	row.Status = status
+	columns := []string{"status"}
+	if update != nil {
+		columns = append(columns, update(&row)...)
+	}
+	res := tx.Model(&row).Where("status = ?", from).Select(columns).Updates(&row)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return &models.ConflictError{Err: fmt.Errorf("%w: status changed concurrently", ErrInvalidTransition), BookingID: id}
+	}
+	return nil
+}
+func (r *SQLRepo) CancelBooking(ctx context.Context, bookingID string, by models.CancellationActor, computeRefund func(*models.Booking) *models.Refund) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The transaction holds the write lock from the start, so the row
+		// read here is the one the transition below changes.
+		var current bookingRow
+		if err := tx.First(&current, "id = ?", bookingID).Error; err != nil {
+			return notFound(err, "booking", bookingID)
+		}
+		if !current.Status.CanTransitionTo(models.BookingStatusCancelled) {
+			return &models.ConflictError{Err: fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, current.Status, models.BookingStatusCancelled), BookingID: bookingID}
+		}
+		refund := computeRefund(current.toModel())
+		err := transitionTx(tx, bookingID, models.BookingStatusCancelled, func(row *bookingRow) []string {
+			row.CancelledBy = by
+			row.CancelledAt = refund.CreatedAt
+			return []string{"cancelled_by", "cancelled_at"}
+		})
+		if err != nil {
+			return err
+		}
+		r.ensureID(&refund.ID)
+		return tx.Create(&refundRow{
+			ID:          refund.ID,
+			BookingID:   bookingID,
+			UserID:      refund.UserID,
+			Amount:      refund.Amount,
+			Percent:     refund.Percent,
+			CancelledBy: refund.CancelledBy,
+			Policy:      refund.Policy,
+			Reason:      refund.Reason,
+			CreatedAt:   refund.CreatedAt,
+		}).Error
+	})
+}
+func (r *SQLRepo) MarkConfirmed(ctx context.Context, bookingID string, at time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return transitionTx(tx, bookingID, models.BookingStatusConfirmed, func(row *bookingRow) []string {
+			row.ConfirmedAt = at
+			return []string{"confirmed_at"}
+		})
+	})
+}
+func (r *SQLRepo) GetRefundsForBooking(ctx context.Context, bookingID string) ([]*models.Refund, error) {
+	var rows []refundRow
+	if err := r.db.WithContext(ctx).Where("booking_id = ?", bookingID).Order("created_at").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	var res []*models.Refund
+	for _, row := range rows {
+		res = append(res, &models.Refund{
+			ID:          row.ID,
+			BookingID:   row.BookingID,
+			UserID:      row.UserID,
+			Amount:      row.Amount,
+			Percent:     row.Percent,
+			CancelledBy: row.CancelledBy,
+			Policy:      row.Policy,
+			Reason:      row.Reason,
+			CreatedAt:   row.CreatedAt,
+		})
+	}
+	return res, nil
+}
+func (r *SQLRepo) ListBookingsByStatus(ctx context.Context, status models.BookingStatus) ([]*models.Booking, error) {
+	var rows []bookingRow
+	if err := r.db.WithContext(ctx).Where("status = ?", status).Order("start_time").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return bookingsFromRows(rows), nil
+}
+func (r *SQLRepo) GetBookingsForCar(ctx context.Context, carID string, from, to time.Time) ([]*models.Booking, error) {
+	var rows []bookingRow
+	err := r.db.WithContext(ctx).
+		Where("car_id = ? AND start_time < ? AND end_time > ?", carID, toNanos(to), toNanos(from)).
+		Order("start_time").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return bookingsFromRows(rows), nil
+}
+func (r *SQLRepo) GetUpcomingBookingsForCar(ctx context.Context, carID string, t time.Time) ([]*models.Booking, error) {
+	return upcomingBookingsTx(r.db.WithContext(ctx), carID, t)
+}
+
+// upcomingBookingsTx narrows the car's bookings to live statuses ending after
+// t in SQL, and leaves the hold expiry to Booking.Upcoming.
+func upcomingBookingsTx(tx *gorm.DB, carID string, t time.Time) ([]*models.Booking, error) {
+	var rows []bookingRow
+	err := tx.Where("car_id = ? AND status IN ? AND end_time > ?", carID,
+		[]models.BookingStatus{models.BookingStatusPending, models.BookingStatusConfirmed}, toNanos(t)).
+		Order("start_time").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	var res []*models.Booking
+	for _, b := range bookingsFromRows(rows) {
+		if b.Upcoming(t) {
+			res = append(res, b)
+		}
+	}
+	return res, nil
+}
+func (r *SQLRepo) GetBookingsForUser(ctx context.Context, userID string, from, to time.Time) ([]*models.Booking, error) {
+	var rows []bookingRow
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND start_time < ? AND end_time > ?", userID, toNanos(to), toNanos(from)).
+		Order("start_time").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return bookingsFromRows(rows), nil
+}
+func (r *SQLRepo) HasOverlappingBooking(ctx context.Context, carID string, start, end time.Time) (bool, error) {
+	return hasOverlapTx(r.db.WithContext(ctx), carID, start, end)
+}
+
+// ReserveBooking runs the availability check, the overlap check and the
+// insert in one transaction that starts by locking the car's row, so two
+// renters can't both pass the checks even from different processes. On
+// SQLite that lock covers the whole database, so it serialises each renter's
+// reservations for check as well.
+func (r *SQLRepo) ReserveBooking(ctx context.Context, booking *models.Booking, check BookingsCheck) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockCarTx(tx, booking.CarID); err != nil {
+			return err
+		}
+		if err := runBookingsCheckTx(tx, check, booking.UserID, booking.StartTime, booking.EndTime, ""); err != nil {
+			return err
+		}
+		var active []bool
+		if err := tx.Model(&carRow{}).Where("id = ?", booking.CarID).Pluck("is_active", &active).Error; err != nil {
+			return err
+		}
+		if !active[0] {
+			return &models.ConflictError{Err: ErrCarInactive}
+		}
+		hasSlot, err := hasSlotTx(tx, booking.CarID, booking.StartTime, booking.EndTime)
+		if err != nil {
+			return err
+		}
+		if !hasSlot {
+			return &models.ConflictError{Err: ErrNoAvailability}
+		}
+		overlapping, err := overlappingBookingTx(tx, booking.CarID, booking.StartTime, booking.EndTime, "")
+		if err != nil {
+			return err
+		}
+		if overlapping != "" {
+			return &models.ConflictError{Err: ErrOverlap, BookingID: overlapping}
+		}
+		r.ensureID(&booking.ID)
+		return tx.Create(toBookingRow(booking)).Error
+	})
+}
+
+func (r *SQLRepo) ModifyBooking(ctx context.Context, bookingID string, mod *models.BookingModification, breakdown []models.PriceLineItem, check BookingsCheck) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var carIDs []string
+		if err := tx.Model(&bookingRow{}).Where("id = ?", bookingID).Pluck("car_id", &carIDs).Error; err != nil {
+			return err
+		}
+		if len(carIDs) == 0 {
+			return &models.NotFoundError{Entity: "booking", ID: bookingID}
+		}
+		// Lock before reading the booking, so its status and window can't
+		// change underneath the checks.
+		if err := lockCarTx(tx, carIDs[0]); err != nil {
+			return err
+		}
+		var row bookingRow
+		if err := tx.First(&row, "id = ?", bookingID).Error; err != nil {
+			return notFound(err, "booking", bookingID)
+		}
+		b := row.toModel()
+		if err := checkModifiable(b, mod.NewStart); err != nil {
+			return err
+		}
+		hasSlot, err := hasSlotTx(tx, b.CarID, mod.NewStart, mod.NewEnd)
+		if err != nil {
+			return err
+		}
+		if !hasSlot {
+			return &models.ConflictError{Err: ErrNoAvailability, BookingID: b.ID}
+		}
+		overlapping, err := overlappingBookingTx(tx, b.CarID, mod.NewStart, mod.NewEnd, b.ID)
+		if err != nil {
+			return err
+		}
+		if overlapping != "" {
+			return &models.ConflictError{Err: ErrOverlap, BookingID: overlapping}
+		}
+		if err := runBookingsCheckTx(tx, check, b.UserID, mod.NewStart, mod.NewEnd, b.ID); err != nil {
+			return err
+		}
+		recordModification(b, mod, breakdown)
+		return tx.Model(&row).Select("start_time", "end_time", "total_price", "price_breakdown", "modifications").Updates(toBookingRow(b)).Error
+	})
+}
+
+// lockCarTx bumps the car's version, taking the write lock on its row (the
+// whole database on SQLite) until the transaction ends.
+func lockCarTx(tx *gorm.DB, carID string) error {
+	res := tx.Model(&carRow{}).Where("id = ?", carID).UpdateColumn("version", gorm.Expr("version + 1"))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return &models.NotFoundError{Entity: "car", ID: carID}
+	}
+	return nil
+}
+
+// runBookingsCheckTx runs check, if any, over the renter's bookings other
+// than except overlapping [start, end).
+func runBookingsCheckTx(tx *gorm.DB, check BookingsCheck, userID string, start, end time.Time, except string) error {
+	if check == nil {
+		return nil
+	}
+	var rows []bookingRow
+	err := tx.Where("user_id = ? AND id <> ? AND start_time < ? AND end_time > ?", userID, except, toNanos(end), toNanos(start)).
+		Order("start_time").Find(&rows).Error
+	if err != nil {
+		return err
+	}
+	return check(bookingsFromRows(rows))
+}
+
+func hasOverlapTx(tx *gorm.DB, carID string, start, end time.Time) (bool, error) {
+	id, err := overlappingBookingTx(tx, carID, start, end, "")
+	return id != "", err
+}
+
+// overlappingBookingTx returns the ID of a booking other than except holding
+// the car during [start, end), or "" if there is none.
+func overlappingBookingTx(tx *gorm.DB, carID string, start, end time.Time, except string) (string, error) {
+	var ids []string
+	err := tx.Model(&bookingRow{}).
+		Where("car_id = ? AND id <> ? AND status NOT IN ? AND start_time < ? AND end_time > ?", carID, except, releasedStatuses, toNanos(end), toNanos(start)).
+		Limit(1).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+// hasSlotTx merges the slots overlapping or touching the window with the
+// car's recurrence rules, as InMemoryRepo does.
+func hasSlotTx(tx *gorm.DB, carID string, start, end time.Time) (bool, error) {
+	var slots []slotRow
+	err := tx.Where("car_id = ? AND start_time <= ? AND end_time >= ?", carID, toNanos(end), toNanos(start)).Find(&slots).Error
+	if err != nil {
+		return false, err
+	}
+	var cov coverage
+	for _, s := range slots {
+		cov.add(fromNanos(s.StartTime), fromNanos(s.EndTime))
+	}
+	if cov.covers(start, end) {
+		return true, nil
+	}
+	rules, err := rulesTx(tx, carID)
+	if err != nil {
+		return false, err
+	}
+	return coversWithRules(&cov, rules, start, end), nil
+}
+
+func toSlotRow(s *models.AvailabilitySlot) *slotRow {
+	return &slotRow{ID: s.ID, CarID: s.CarID, StartTime: toNanos(s.StartTime), EndTime: toNanos(s.EndTime)}
+}
+
+func (row *slotRow) toModel() *models.AvailabilitySlot {
+	return &models.AvailabilitySlot{ID: row.ID, CarID: row.CarID, StartTime: fromNanos(row.StartTime), EndTime: fromNanos(row.EndTime)}
+}
+
+func (r *SQLRepo) AddAvailability(ctx context.Context, slot *models.AvailabilitySlot) error {
+	r.ensureID(&slot.ID)
+	return r.db.WithContext(ctx).Create(toSlotRow(slot)).Error
+}
+
+// GetAvailability returns the car's slots overlapping [from, to), ordered by
+// start time.
+func (r *SQLRepo) GetAvailability(ctx context.Context, carID string, from, to time.Time) ([]*models.AvailabilitySlot, error) {
+	var rows []slotRow
+	err := r.db.WithContext(ctx).
+		Where("car_id = ? AND start_time < ? AND end_time > ?", carID, toNanos(to), toNanos(from)).
+		Order("start_time").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	var res []*models.AvailabilitySlot
+	for i := range rows {
+		res = append(res, rows[i].toModel())
+	}
+	return res, nil
+}
+func (r *SQLRepo) GetAvailabilitySlot(ctx context.Context, slotID string) (*models.AvailabilitySlot, error) {
+	var row slotRow
+	if err := r.db.WithContext(ctx).First(&row, "id = ?", slotID).Error; err != nil {
+		return nil, notFound(err, "availability slot", slotID)
+	}
+	return row.toModel(), nil
+}
+
+// RemoveAvailability deletes a slot unconditionally. Use ReplaceAvailability
+// to protect existing bookings.
+func (r *SQLRepo) RemoveAvailability(ctx context.Context, slotID string) error {
+	slot, err := r.GetAvailabilitySlot(ctx, slotID)
+	if err != nil {
+		return err
+	}
+	return r.ReplaceAvailability(ctx, slot.CarID, []string{slotID}, nil, true)
+}
+
+// ReplaceAvailability locks the car's row so a booking can't be reserved
+// against slots that are about to disappear.
+func (r *SQLRepo) ReplaceAvailability(ctx context.Context, carID string, removeIDs []string, add []*models.AvailabilitySlot, force bool) error {
+	for _, s := range add {
+		if s.CarID != carID {
+			return &models.ValidationError{Field: "car_id", Message: "availability slot belongs to a different car"}
+		}
+		if !s.StartTime.Before(s.EndTime) {
+			return &models.ValidationError{Field: "end_time", Message: "start time must be before end time"}
+		}
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockCarTx(tx, carID); err != nil {
+			return err
+		}
+		var current []slotRow
+		if err := tx.Where("car_id = ?", carID).Find(&current).Error; err != nil {
+			return err
+		}
+
+		remove := make(map[string]bool, len(removeIDs))
+		for _, id := range removeIDs {
+			remove[id] = true
+		}
+		var cov coverage
+		for _, s := range current {
+			if remove[s.ID] {
+				delete(remove, s.ID)
+				continue
+			}
+			cov.add(fromNanos(s.StartTime), fromNanos(s.EndTime))
+		}
+		for id := range remove {
+			return &models.NotFoundError{Entity: "availability slot", ID: id}
+		}
+		for _, s := range add {
+			cov.add(s.StartTime, s.EndTime)
+		}
+
+		if !force {
+			rules, err := rulesTx(tx, carID)
+			if err != nil {
+				return err
+			}
+			if err := checkStrandedTx(tx, carID, &cov, rules); err != nil {
+				return err
+			}
+		}
+
+		if len(removeIDs) > 0 {
+			if err := tx.Where("id IN ?", removeIDs).Delete(&slotRow{}).Error; err != nil {
+				return err
+			}
+		}
+		for _, s := range add {
+			r.ensureID(&s.ID)
+			if err := tx.Create(toSlotRow(s)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// checkStrandedTx fails with ErrStrandedBooking if an open booking for the
+// car isn't covered by cov and rules.
+func checkStrandedTx(tx *gorm.DB, carID string, cov *coverage, rules []*models.RecurrenceRule) error {
+	var open []bookingRow
+	if err := tx.Where("car_id = ? AND status IN ?", carID, openStatuses).Find(&open).Error; err != nil {
+		return err
+	}
+	for _, b := range open {
+		if !coversWithRules(cov, rules, fromNanos(b.StartTime), fromNanos(b.EndTime)) {
+			return &models.ConflictError{Err: ErrStrandedBooking, BookingID: b.ID}
+		}
+	}
+	return nil
+}
+func (r *SQLRepo) HasAvailabilitySlot(ctx context.Context, carID string, start, end time.Time) (bool, error) {
+	return hasSlotTx(r.db.WithContext(ctx), carID, start, end)
+}
+
+// filterBatch keeps each FilterAvailable query under SQLite's bound
+// parameter limit.
+const filterBatch = 500
+
+// FilterAvailable checks many cars with three queries per batch, instead of
+// one HasAvailabilitySlot/HasOverlappingBooking pair per car.
+func (r *SQLRepo) FilterAvailable(ctx context.Context, carIDs []string, start, end time.Time) (map[string]bool, error) {
+	db := r.db.WithContext(ctx)
+	res := make(map[string]bool, len(carIDs))
+	for lo := 0; lo < len(carIDs); lo += filterBatch {
+		batch := carIDs[lo:min(lo+filterBatch, len(carIDs))]
+
+		var busy []string
+		err := db.Model(&bookingRow{}).Distinct("car_id").
+			Where("car_id IN ? AND status NOT IN ? AND start_time < ? AND end_time > ?", batch, releasedStatuses, toNanos(end), toNanos(start)).
+			Pluck("car_id", &busy).Error
+		if err != nil {
+			return nil, err
+		}
+		var slots []slotRow
+		err = db.Where("car_id IN ? AND start_time <= ? AND end_time >= ?", batch, toNanos(end), toNanos(start)).Find(&slots).Error
+		if err != nil {
+			return nil, err
+		}
+		var rules []ruleRow
+		if err := db.Where("car_id IN ?", batch).Find(&rules).Error; err != nil {
+			return nil, err
+		}
+
+		isBusy := make(map[string]bool, len(busy))
+		for _, id := range busy {
+			isBusy[id] = true
+		}
+		covs := make(map[string]*coverage)
+		for _, s := range slots {
+			if covs[s.CarID] == nil {
+				covs[s.CarID] = &coverage{}
+			}
+			covs[s.CarID].add(fromNanos(s.StartTime), fromNanos(s.EndTime))
+		}
+		carRules := make(map[string][]*models.RecurrenceRule)
+		for i := range rules {
+			carRules[rules[i].CarID] = append(carRules[rules[i].CarID], rules[i].toModel())
+		}
+		for _, id := range batch {
+			if isBusy[id] {
+				continue
+			}
+			cov := covs[id]
+			if cov == nil {
+				cov = &coverage{}
+			}
+			if coversWithRules(cov, carRules[id], start, end) {
+				res[id] = true
+			}
+		}
+	}
+	return res, nil
+}
+
+func toRuleRow(rule *models.RecurrenceRule) *ruleRow {
+	return &ruleRow{
+		ID:         rule.ID,
+		CarID:      rule.CarID,
+		Frequency:  rule.Frequency,
+		Interval:   rule.Interval,
+		ByWeekday:  rule.ByWeekday,
+		StartOfDay: rule.StartOfDay,
+		EndOfDay:   rule.EndOfDay,
+		StartDate:  rule.StartDate,
+		Until:      rule.Until,
+		Exceptions: rule.Exceptions,
+		TimeZone:   rule.TimeZone,
+		CreatedAt:  rule.CreatedAt,
+	}
+}
+
+func (row *ruleRow) toModel() *models.RecurrenceRule {
+	return &models.RecurrenceRule{
+		ID:         row.ID,
+		CarID:      row.CarID,
+		Frequency:  row.Frequency,
+		Interval:   row.Interval,
+		ByWeekday:  row.ByWeekday,
+		StartOfDay: row.StartOfDay,
+		EndOfDay:   row.EndOfDay,
+		StartDate:  row.StartDate,
+		Until:      row.Until,
+		Exceptions: row.Exceptions,
+		TimeZone:   row.TimeZone,
+		CreatedAt:  row.CreatedAt,
+	}
+}
+
+func rulesTx(tx *gorm.DB, carID string) ([]*models.RecurrenceRule, error) {
+	var rows []ruleRow
+	if err := tx.Where("car_id = ?", carID).Order("created_at, id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	res := make([]*models.RecurrenceRule, 0, len(rows))
+	for i := range rows {
+		res = append(res, rows[i].toModel())
+	}
+	return res, nil
+}
+
+func (r *SQLRepo) AddRecurrenceRule(ctx context.Context, rule *models.RecurrenceRule) error {
+	r.ensureID(&rule.ID)
+	return r.db.WithContext(ctx).Create(toRuleRow(rule)).Error
+}
+func (r *SQLRepo) GetRecurrenceRules(ctx context.Context, carID string) ([]*models.RecurrenceRule, error) {
+	return rulesTx(r.db.WithContext(ctx), carID)
+}
+
+func (r *SQLRepo) GetRecurrenceRule(ctx context.Context, ruleID string) (*models.RecurrenceRule, error) {
+	var row ruleRow
+	if err := r.db.WithContext(ctx).First(&row, "id = ?", ruleID).Error; err != nil {
+		return nil, notFound(err, "recurrence rule", ruleID)
+	}
+	return row.toModel(), nil
+}
+
+// RemoveRecurrenceRule deletes a rule under the car's row lock. Unless force
+// is set it fails with ErrStrandedBooking if an open booking relied on it.
+func (r *SQLRepo) RemoveRecurrenceRule(ctx context.Context, ruleID string, force bool) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rule ruleRow
+		if err := tx.First(&rule, "id = ?", ruleID).Error; err != nil {
+			return notFound(err, "recurrence rule", ruleID)
+		}
+		if err := lockCarTx(tx, rule.CarID); err != nil {
+			return err
+		}
+		if !force {
+			rules, err := rulesTx(tx, rule.CarID)
+			if err != nil {
+				return err
+			}
+			var rest []*models.RecurrenceRule
+			for _, other := range rules {
+				if other.ID != ruleID {
+					rest = append(rest, other)
+				}
+			}
+			var slots []slotRow
+			if err := tx.Where("car_id = ?", rule.CarID).Find(&slots).Error; err != nil {
+				return err
+			}
+			var cov coverage
+			for _, s := range slots {
+				cov.add(fromNanos(s.StartTime), fromNanos(s.EndTime))
+			}
+			if err := checkStrandedTx(tx, rule.CarID, &cov, rest); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&ruleRow{}, "id = ?", ruleID).Error
+	})
+}
+
+func toReviewRow(rv *models.Review) *reviewRow {
+	return &reviewRow{
+		ID:          rv.ID,
+		BookingID:   rv.BookingID,
+		Role:        rv.Role,
+		CarID:       rv.CarID,
+		HostID:      rv.HostID,
+		UserID:      rv.UserID,
+		Rating:      rv.Rating,
+		Comment:     rv.Comment,
+		CreatedAt:   rv.CreatedAt,
+		RevealAt:    toNanos(rv.RevealAt),
+		PublishedAt: rv.PublishedAt,
+	}
+}
+
+func (row *reviewRow) toModel() *models.Review {
+	return &models.Review{
+		ID:          row.ID,
+		BookingID:   row.BookingID,
+		Role:        row.Role,
+		CarID:       row.CarID,
+		HostID:      row.HostID,
+		UserID:      row.UserID,
+		Rating:      row.Rating,
+		Comment:     row.Comment,
+		CreatedAt:   row.CreatedAt,
+		RevealAt:    fromNanos(row.RevealAt),
+		PublishedAt: row.PublishedAt,
+	}
+}
+
+func (r *SQLRepo) CreateReview(ctx context.Context, review *models.Review) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&reviewRow{}).Where("booking_id = ? AND role = ?", review.BookingID, review.Role).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return &models.ConflictError{Err: ErrDuplicateReview, BookingID: review.BookingID}
+		}
+		r.ensureID(&review.ID)
+		return tx.Create(toReviewRow(review)).Error
+	})
+}
+func (r *SQLRepo) GetReviewsForBooking(ctx context.Context, bookingID string) ([]*models.Review, error) {
+	return r.findReviews(ctx, "booking_id = ?", bookingID)
+}
+func (r *SQLRepo) GetReviewsForCar(ctx context.Context, carID string) ([]*models.Review, error) {
+	return r.findReviews(ctx, "car_id = ? AND role = ?", carID, models.ReviewerRenter)
+}
+func (r *SQLRepo) GetReviewsForUser(ctx context.Context, userID string) ([]*models.Review, error) {
+	return r.findReviews(ctx, "user_id = ? AND role = ?", userID, models.ReviewerHost)
+}
+func (r *SQLRepo) ListDueReviews(ctx context.Context, t time.Time) ([]*models.Review, error) {
+	return r.findReviews(ctx, "published_at = ? AND reveal_at <= ?", time.Time{}, toNanos(t))
+}
+
+func (r *SQLRepo) findReviews(ctx context.Context, query string, args ...any) ([]*models.Review, error) {
+	var rows []reviewRow
+	if err := r.db.WithContext(ctx).Where(query, args...).Order("created_at DESC, id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	var res []*models.Review
+	for i := range rows {
+		res = append(res, rows[i].toModel())
+	}
+	return res, nil
+}
+
+// rateTx folds score into the rating columns of model's row id. SQLite
+// evaluates every SET expression against the old row.
+func rateTx(tx *gorm.DB, model any, id string, score int) error {
+	return tx.Model(model).Where("id = ?", id).Updates(map[string]any{
+		"rating_average": gorm.Expr("(rating_average * rating_count + ?) / (rating_count + 1)", score),
+		"rating_count":   gorm.Expr("rating_count + 1"),
+	}).Error
+}
+
+func (r *SQLRepo) PublishReviews(ctx context.Context, ids []string, at time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []reviewRow
+		if err := tx.Where("id IN ?", ids).Find(&rows).Error; err != nil {
+			return err
+		}
+		found := make(map[string]bool, len(rows))
+		for _, row := range rows {
+			found[row.ID] = true
+		}
+		for _, id := range ids {
+			if !found[id] {
+				return &models.NotFoundError{Entity: "review", ID: id}
+			}
+		}
+		for _, row := range rows {
+			// The conditional update keeps a concurrent publish from
+			// counting the same review twice.
+			res := tx.Model(&reviewRow{}).Where("id = ? AND published_at = ?", row.ID, time.Time{}).Update("published_at", at)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				continue
+			}
+			switch row.Role {
+			case models.ReviewerRenter:
+				if err := rateTx(tx, &carRow{}, row.CarID, row.Rating); err != nil {
+					return err
+				}
+				if err := rateTx(tx, &hostRow{}, row.HostID, row.Rating); err != nil {
+					return err
+				}
+			case models.ReviewerHost:
+				if err := rateTx(tx, &userRow{}, row.UserID, row.Rating); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
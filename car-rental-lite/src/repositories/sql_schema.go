@@ -0,0 +1,242 @@
+package repositories
+
+import (
+	"car-rental-lite/src/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Rows that take part in range queries store their window as Unix
+// nanoseconds, so SQL comparisons are numeric rather than on SQLite's text
+// timestamps. Times are read back in UTC.
+
+type carRow struct {
+	ID           string `gorm:"primaryKey"`
+	HostID       string `gorm:"index;not null"`
+	Make         string
+	Model        string
+	Year         int
+	Type         models.CarType
+	Latitude     float64 `gorm:"index:idx_cars_lat_lng"`
+	Longitude    float64 `gorm:"index:idx_cars_lat_lng"`
+	Address      string
+	City         string
+	ZipCode      string
+	TimeZone     string
+	PricePerDay  float64
+	PricePerHour float64
+	LicensePlate string
+	Amenities    []models.Amenity `gorm:"serializer:json"`
+	IsActive     bool
+	// RatingAverage and RatingCount hold models.Rating.
+	RatingAverage float64
+	RatingCount   int
+	// Version is bumped at the start of every transaction that reserves the
+	// car or changes its availability, which takes the database's write lock
+	// on the car before anything is checked.
+	Version int64 `gorm:"not null;default:0"`
+}
+
+func (carRow) TableName() string { return "cars" }
+
+type bookingRow struct {
+	ID             string               `gorm:"primaryKey"`
+	CarID          string               `gorm:"not null;index:idx_bookings_car_window"`
+	UserID         string               `gorm:"index"`
+	StartTime      int64                `gorm:"not null;index:idx_bookings_car_window"`
+	EndTime        int64                `gorm:"not null"`
+	Status         models.BookingStatus `gorm:"not null;index"`
+	TotalPrice     float64
+	PriceBreakdown []models.PriceLineItem       `gorm:"serializer:json"`
+	Adjustments    []models.PriceLineItem       `gorm:"serializer:json"`
+	Modifications  []models.BookingModification `gorm:"serializer:json"`
+	HoldExpiresAt  time.Time
+	ConfirmedAt    time.Time
+	PickedUpAt     time.Time
+	ReturnedAt     time.Time
+	CancelledBy    models.CancellationActor
+	CancelledAt    time.Time
+	CreatedAt      time.Time
+}
+
+func (bookingRow) TableName() string { return "bookings" }
+
+type refundRow struct {
+	ID          string `gorm:"primaryKey"`
+	BookingID   string `gorm:"not null;index"`
+	UserID      string
+	Amount      float64
+	Percent     float64
+	CancelledBy models.CancellationActor
+	Policy      models.CancellationPolicy
+	Reason      string
+	CreatedAt   time.Time
+}
+
+func (refundRow) TableName() string { return "refunds" }
+
+type slotRow struct {
+	ID        string `gorm:"primaryKey"`
+	CarID     string `gorm:"not null;index:idx_slots_car_window"`
+	StartTime int64  `gorm:"not null;index:idx_slots_car_window"`
+	EndTime   int64  `gorm:"not null"`
+}
+
+func (slotRow) TableName() string { return "availability_slots" }
+
+type ruleRow struct {
+	ID         string `gorm:"primaryKey"`
+	CarID      string `gorm:"not null;index"`
+	Frequency  models.Frequency
+	Interval   int
+	ByWeekday  []time.Weekday `gorm:"serializer:json"`
+	StartOfDay time.Duration
+	EndOfDay   time.Duration
+	StartDate  time.Time
+	Until      time.Time
+	Exceptions []time.Time `gorm:"serializer:json"`
+	TimeZone   string
+	CreatedAt  time.Time
+}
+
+func (ruleRow) TableName() string { return "recurrence_rules" }
+
+type userRow struct {
+	ID               string `gorm:"primaryKey"`
+	Name             string
+	Email            string
+	PhoneNumber      string
+	DriverLicense    string
+	LicenseExpiresAt time.Time
+	DateOfBirth      time.Time
+	Banned           bool
+	RatingAverage    float64
+	RatingCount      int
+	CreatedAt        time.Time
+}
+
+func (userRow) TableName() string { return "users" }
+
+type hostRow struct {
+	ID                 string `gorm:"primaryKey"`
+	Name               string
+	Email              string
+	PhoneNumber        string
+	CancellationPolicy models.CancellationPolicy
+	RatingAverage      float64
+	RatingCount        int
+	CreatedAt          time.Time
+}
+
+func (hostRow) TableName() string { return "hosts" }
+
+type reviewRow struct {
+	ID          string              `gorm:"primaryKey"`
+	BookingID   string              `gorm:"not null;uniqueIndex:idx_reviews_booking_role"`
+	Role        models.ReviewerRole `gorm:"not null;uniqueIndex:idx_reviews_booking_role"`
+	CarID       string              `gorm:"index"`
+	HostID      string
+	UserID      string `gorm:"index"`
+	Rating      int
+	Comment     string
+	CreatedAt   time.Time
+	RevealAt    int64 `gorm:"not null;index"`
+	PublishedAt time.Time
+}
+
+func (reviewRow) TableName() string { return "reviews" }
+
+// migration is one schema change. Migrations are applied in order and
+// recorded in schema_migrations, so each runs once per database; append new
+// ones rather than editing old ones. Migration 1 creates tables from the
+// current row structs, so later migrations must tolerate their change
+// already being in place on a fresh database.
+type migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+}
+
+var migrations = []migration{
+	{1, "create tables", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&userRow{}, &hostRow{}, &carRow{}, &bookingRow{}, &refundRow{}, &slotRow{}, &ruleRow{})
+	}},
+	{2, "add bookings.confirmed_at", func(tx *gorm.DB) error {
+		return addColumns(tx, &bookingRow{}, "ConfirmedAt")
+	}},
+	{3, "add reviews and ratings", func(tx *gorm.DB) error {
+		for _, model := range []any{&carRow{}, &hostRow{}, &userRow{}} {
+			if err := addColumns(tx, model, "RatingAverage", "RatingCount"); err != nil {
+				return err
+			}
+		}
+		return tx.AutoMigrate(&reviewRow{})
+	}},
+	{4, "add driver eligibility fields to users", func(tx *gorm.DB) error {
+		return addColumns(tx, &userRow{}, "LicenseExpiresAt", "DateOfBirth", "Banned")
+	}},
+	{5, "add bookings.modifications", func(tx *gorm.DB) error {
+		return addColumns(tx, &bookingRow{}, "Modifications")
+	}},
+	{6, "add cars.time_zone", func(tx *gorm.DB) error {
+		return addColumns(tx, &carRow{}, "TimeZone")
+	}},
+}
+
+// addColumns adds the named fields of model that its table lacks.
+func addColumns(tx *gorm.DB, model any, fields ...string) error {
+	m := tx.Migrator()
+	for _, f := range fields {
+		if m.HasColumn(model, f) {
+			continue
+		}
+		if err := m.AddColumn(model, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// Migrate brings the schema up to date, applying each pending migration in
+// its own transaction.
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return err
+	}
+	var applied []schemaMigration
+	if err := db.Find(&applied).Error; err != nil {
+		return err
+	}
+	done := make(map[int]bool, len(applied))
+	for _, m := range applied {
+		done[m.Version] = true
+	}
+	for _, m := range migrations {
+		if done[m.Version] {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Another process may have applied it since the list was read.
+			var n int64
+			if err := tx.Model(&schemaMigration{}).Where("version = ?", m.Version).Count(&n).Error; err != nil || n > 0 {
+				return err
+			}
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
@@ -1,10 +1,12 @@
 package services
 
 import (
+	"car-rental-lite/src/idgen"
 	"car-rental-lite/src/models"
 	"car-rental-lite/src/repositories"
 	"context"
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -12,20 +14,85 @@ type BookingService struct {
 	bookingRepo   repositories.BookingRepository
 	inventoryRepo repositories.InventoryRepository
 	carRepo       repositories.CarRepository
+	userRepo      repositories.UserRepository
+	ids           idgen.IDGenerator
+	pricing       PricingEngine
+	eligibility   *EligibilityChain
+	events        EventPublisher
+	holdTTL       time.Duration
+	now           func() time.Time
 }
 
-func NewBookingService(bRepo repositories.BookingRepository, iRepo repositories.InventoryRepository, cRepo repositories.CarRepository) *BookingService {
+// DefaultHoldTTL is how long a PENDING booking blocks the car while waiting
+// for payment.
+const DefaultHoldTTL = 15 * time.Minute
+
+var (
+	// ErrHoldExpired is returned, wrapped in a *models.ConflictError, when
+	// confirming a booking whose hold lapsed.
+	ErrHoldExpired = errors.New("booking hold has expired")
+	// ErrCarInactive is returned, wrapped in a *models.ConflictError, when
+	// booking a car its host has deactivated.
+	ErrCarInactive = repositories.ErrCarInactive
+)
+
+func NewBookingService(bRepo repositories.BookingRepository, iRepo repositories.InventoryRepository, cRepo repositories.CarRepository, uRepo repositories.UserRepository) *BookingService {
 	return &BookingService{
 		bookingRepo:   bRepo,
 		inventoryRepo: iRepo,
 		carRepo:       cRepo,
+		userRepo:      uRepo,
+		ids:           idgen.NewULIDGenerator(),
+		pricing:       NewDefaultPricingEngine(),
+		eligibility:   NewDefaultEligibilityChain(bRepo),
+		events:        nopPublisher{},
+		holdTTL:       DefaultHoldTTL,
+		now:           time.Now,
+	}
+}
+
+func (s *BookingService) SetHoldTTL(ttl time.Duration) {
+	s.holdTTL = ttl
+}
+
+// SetClock replaces time.Now, e.g. with a fake clock in tests.
+func (s *BookingService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+func (s *BookingService) SetPricingEngine(pricing PricingEngine) {
+	s.pricing = pricing
+}
+
+func (s *BookingService) SetEligibilityChain(eligibility *EligibilityChain) {
+	s.eligibility = eligibility
+}
+
+// SetEventPublisher sets where the service's domain events go. By default
+// they're discarded.
+func (s *BookingService) SetEventPublisher(events EventPublisher) {
+	s.events = events
+}
+
+// QuotePrice prices a prospective booking without reserving anything.
+func (s *BookingService) QuotePrice(ctx context.Context, carID string, start, end time.Time) (*PriceQuote, error) {
+	car, err := s.carRepo.GetCar(ctx, carID)
+	if err != nil {
+		return nil, err
 	}
+	return s.pricing.Quote(ctx, car, start, end)
+}
+
+// SetIDGenerator replaces the default ULID generator, e.g. with a
+// deterministic sequence in tests.
+func (s *BookingService) SetIDGenerator(ids idgen.IDGenerator) {
+	s.ids = ids
 }
 
 func (s *BookingService) CreateBooking(ctx context.Context, userID, carID string, start, end time.Time) (*models.Booking, error) {
 	// 1. Validate times
-	if start.After(end) {
-		return nil, errors.New("invalid booking duration")
+	if !start.Before(end) {
+		return nil, &models.ValidationError{Field: "end_time", Message: "invalid booking duration"}
 	}
 
 	// 2. Check Car existence
@@ -34,68 +101,220 @@ func (s *BookingService) CreateBooking(ctx context.Context, userID, carID string
 		return nil, err
 	}
 	if !car.IsActive {
-		return nil, errors.New("car is not currently active")
+		return nil, &models.ConflictError{Err: ErrCarInactive}
 	}
 
-	// 3. Check Availability
-	// a. Host created a slot?
-	hasSlot, err := s.inventoryRepo.HasAvailabilitySlot(ctx, carID, start, end)
+	// 3. Check the renter may drive it
+	user, err := s.userRepo.GetUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	if !hasSlot {
-		return nil, errors.New("host has not made the car available for these dates")
+	check := &EligibilityCheck{User: user, Car: car, Start: start, End: end, Now: s.now()}
+	if err := s.eligibility.Check(ctx, check); err != nil {
+		return nil, err
 	}
 
-	// b. No overlapping booking?
-	hasOverlap, err := s.bookingRepo.HasOverlappingBooking(ctx, carID, start, end)
+	// 4. Calculate Price
+	quote, err := s.pricing.Quote(ctx, car, start, end)
 	if err != nil {
 		return nil, err
 	}
-	if hasOverlap {
-		return nil, errors.New("car is already booked for these dates")
+
+	// 5. Reserve Booking
+	// Availability, overlap and the renter's concurrent-bookings cap are
+	// checked by the repository in the same critical section as the insert,
+	// so concurrent requests can't both win.
+	// The booking starts as a PENDING hold until payment confirms it.
+	now := s.now()
+	booking := &models.Booking{
+		ID:             s.ids.NewID(),
+		UserID:         userID,
+		CarID:          carID,
+		StartTime:      start,
+		EndTime:        end,
+		Status:         models.BookingStatusPending,
+		TotalPrice:     quote.Total,
+		PriceBreakdown: quote.Items,
+		HoldExpiresAt:  now.Add(s.holdTTL),
+		CreatedAt:      now,
 	}
 
-	// 4. Calculate Price
-	days := end.Sub(start).Hours() / 24.0
-	// Ceiling or partial day logic usually applies, simple multiply for now
-	if days < 1 {
-		days = 1
+	if err := s.bookingRepo.ReserveBooking(ctx, booking, s.eligibility.BookingsCheck(check)); err != nil {
+		return nil, err
 	}
-	totalPrice := days * car.PricePerDay
 
-	// 5. Create Booking
-	booking := &models.Booking{
-		// ID generated in repo or here
+	publish(ctx, s.events, models.BookingCreated{
+		BookingID:  booking.ID,
 		UserID:     userID,
 		CarID:      carID,
 		StartTime:  start,
 		EndTime:    end,
-		Status:     models.BookingStatusConfirmed, // Or Pending if payment is separate
-		TotalPrice: totalPrice,
-		CreatedAt:  time.Now(),
+		TotalPrice: booking.TotalPrice,
+		OccurredAt: now,
+	})
+	return booking, nil
+}
+
+// ModifyBooking moves a booking to [start, end), e.g. to extend or shorten
+// it. The renter is re-checked for eligibility and the booking re-priced; the
+// change and the price difference are added to its Modifications. Bookings
+// that haven't started may move freely; a trip in progress keeps its pickup
+// time. The car stays held throughout, so the renter can't lose it to a
+// rebooking race.
+func (s *BookingService) ModifyBooking(ctx context.Context, bookingID string, start, end time.Time) (*models.Booking, error) {
+	if !start.Before(end) {
+		return nil, &models.ValidationError{Field: "end_time", Message: "invalid booking duration"}
+	}
+	booking, err := s.bookingRepo.GetBooking(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if booking.Status == models.BookingStatusPending && now.After(booking.HoldExpiresAt) {
+		return nil, &models.ConflictError{Err: ErrHoldExpired, BookingID: bookingID}
+	}
+	if start.Equal(booking.StartTime) && end.Equal(booking.EndTime) {
+		return nil, &models.ValidationError{Field: "end_time", Message: "booking already has these dates"}
+	}
+	if booking.Status == models.BookingStatusActive {
+		if !end.After(now) {
+			return nil, &models.ValidationError{Field: "end_time", Message: "end time must be in the future"}
+		}
+	} else if !start.After(now) {
+		return nil, &models.ValidationError{Field: "start_time", Message: "start time must be in the future"}
 	}
 
-	if err := s.bookingRepo.CreateBooking(ctx, booking); err != nil {
+	car, err := s.carRepo.GetCar(ctx, booking.CarID)
+	if err != nil {
+		return nil, err
+	}
+	extends := start.Before(booking.StartTime) || end.After(booking.EndTime)
+	if extends && !car.IsActive {
+		return nil, &models.ConflictError{Err: ErrCarInactive, BookingID: bookingID}
+	}
+	user, err := s.userRepo.GetUser(ctx, booking.UserID)
+	if err != nil {
+		return nil, err
+	}
+	check := &EligibilityCheck{User: user, Car: car, Start: start, End: end, Now: now, BookingID: bookingID}
+	if err := s.eligibility.Check(ctx, check); err != nil {
+		return nil, err
+	}
+	quote, err := s.pricing.Quote(ctx, car, start, end)
+	if err != nil {
 		return nil, err
 	}
 
-	return booking, nil
+	mod := &models.BookingModification{ModifiedAt: now, NewStart: start, NewEnd: end, NewPrice: quote.Total}
+	if err := s.bookingRepo.ModifyBooking(ctx, bookingID, mod, quote.Items, s.eligibility.BookingsCheck(check)); err != nil {
+		return nil, err
+	}
+	return s.bookingRepo.GetBooking(ctx, bookingID)
+}
+
+// ConfirmBooking marks a PENDING booking as paid. A hold past its TTL is
+// expired instead and ErrHoldExpired is returned.
+func (s *BookingService) ConfirmBooking(ctx context.Context, bookingID string) (*models.Booking, error) {
+	booking, err := s.bookingRepo.GetBooking(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status == models.BookingStatusPending && s.now().After(booking.HoldExpiresAt) {
+		if err := s.bookingRepo.UpdateBookingStatus(ctx, bookingID, models.BookingStatusExpired); err != nil {
+			return nil, err
+		}
+		return nil, &models.ConflictError{Err: ErrHoldExpired, BookingID: bookingID}
+	}
+	if err := s.bookingRepo.MarkConfirmed(ctx, bookingID, s.now()); err != nil {
+		return nil, err
+	}
+	return s.bookingRepo.GetBooking(ctx, bookingID)
+}
+
+// ExpireHolds moves every PENDING booking whose hold has lapsed to EXPIRED,
+// releasing its dates. It returns the number of holds expired.
+func (s *BookingService) ExpireHolds(ctx context.Context) (int, error) {
+	pending, err := s.bookingRepo.ListBookingsByStatus(ctx, models.BookingStatusPending)
+	if err != nil {
+		return 0, err
+	}
+	now := s.now()
+	expired := 0
+	for _, b := range pending {
+		if !now.After(b.HoldExpiresAt) {
+			continue
+		}
+		err := s.bookingRepo.UpdateBookingStatus(ctx, b.ID, models.BookingStatusExpired)
+		if errors.Is(err, repositories.ErrInvalidTransition) {
+			// Confirmed or cancelled since we listed it.
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
 }
 
-func (s *BookingService) CancelBooking(ctx context.Context, bookingID string) error {
+// CancelBooking cancels a booking on behalf of the renter or the car's host
+// and records the resulting refund. actorID must be the booking's user for a
+// renter cancellation, or the car's host for a host cancellation.
+func (s *BookingService) CancelBooking(ctx context.Context, bookingID string, by models.CancellationActor, actorID string) (*models.Refund, error) {
 	booking, err := s.bookingRepo.GetBooking(ctx, bookingID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if booking.Status == models.BookingStatusCompleted || booking.Status == models.BookingStatusCancelled {
-		return errors.New("booking cannot be cancelled")
+	if !booking.Status.CanTransitionTo(models.BookingStatusCancelled) {
+		return nil, &models.ConflictError{Err: fmt.Errorf("%w: %s bookings cannot be cancelled", repositories.ErrInvalidTransition, booking.Status), BookingID: booking.ID}
 	}
 
-	if time.Until(booking.StartTime) < 24*time.Hour {
-		// return errors.New("cannot cancel within 24 hours")
+	car, err := s.carRepo.GetCar(ctx, booking.CarID)
+	if err != nil {
+		return nil, err
+	}
+	switch by {
+	case models.CancelledByRenter:
+		if actorID != booking.UserID {
+			return nil, &models.ValidationError{Field: "actor_id", Message: "only the renter can cancel this booking"}
+		}
+	case models.CancelledByHost:
+		if actorID != car.HostID {
+			return nil, &models.ValidationError{Field: "actor_id", Message: "only the car's host can cancel this booking"}
+		}
+	default:
+		return nil, &models.ValidationError{Field: "actor", Message: "unknown cancellation actor"}
 	}
 
-	return s.bookingRepo.UpdateBookingStatus(ctx, bookingID, models.BookingStatusCancelled)
+	host, err := s.userRepo.GetHost(ctx, car.HostID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The refund is worked out from the booking as the repository sees it
+	// when cancelling, not from the copy read above, which a concurrent
+	// confirmation or modification may have outdated.
+	now := s.now()
+	var refund *models.Refund
+	err = s.bookingRepo.CancelBooking(ctx, bookingID, by, func(current *models.Booking) *models.Refund {
+		booking = current
+		refund = computeRefund(current, host.CancellationPolicy, by, now)
+		refund.ID = s.ids.NewID()
+		return refund
+	})
+	if err != nil {
+		return nil, err
+	}
+	publish(ctx, s.events, models.BookingCancelled{
+		BookingID:    booking.ID,
+		UserID:       booking.UserID,
+		CarID:        booking.CarID,
+		StartTime:    booking.StartTime,
+		EndTime:      booking.EndTime,
+		CancelledBy:  by,
+		RefundAmount: refund.Amount,
+		OccurredAt:   refund.CreatedAt,
+	})
+	return refund, nil
 }
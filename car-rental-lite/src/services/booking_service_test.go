@@ -0,0 +1,428 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type bookingFixture struct {
+	repo     *repositories.InMemoryRepo
+	inv      *InventoryService
+	bookings *BookingService
+	car      *models.Car
+	now      time.Time
+}
+
+// newBookingFixture registers one active car at 100/day from a host with a
+// MODERATE cancellation policy, available for all of January 2026, and two
+// licensed renters, user1 and user2. It pins the booking service clock to
+// Dec 20 2025.
+func newBookingFixture(t *testing.T) *bookingFixture {
+	t.Helper()
+	ctx := context.Background()
+	f := &bookingFixture{repo: repositories.NewInMemoryRepo()}
+	f.inv = NewInventoryService(f.repo, f.repo, f.repo, f.repo)
+	f.bookings = NewBookingService(f.repo, f.repo, f.repo, f.repo)
+	f.now = time.Date(2025, 12, 20, 12, 0, 0, 0, time.UTC)
+	f.bookings.SetClock(func() time.Time { return f.now })
+	f.inv.SetClock(func() time.Time { return f.now })
+
+	if err := f.repo.CreateHost(ctx, &models.Host{ID: "host1", CancellationPolicy: models.CancellationPolicyModerate}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"user1", "user2"} {
+		if err := f.repo.CreateUser(ctx, licensedRenter(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.car = &models.Car{HostID: "host1", PricePerDay: 100, IsActive: true}
+	if err := f.inv.RegisterCar(ctx, f.car); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.inv.AddAvailability(ctx, f.car.ID, jan(1), jan(31)); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// licensedRenter is a renter born in 1990 whose license is valid through 2030.
+func licensedRenter(id string) *models.User {
+	return &models.User{
+		ID:               id,
+		DriverLicense:    "DL-" + id,
+		LicenseExpiresAt: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		DateOfBirth:      time.Date(1990, 6, 15, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func jan(d int) time.Time {
+	return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestBookingStateMachine(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(2), jan(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Status != models.BookingStatusPending {
+		t.Fatalf("Expected new booking to be PENDING, got %s", b.Status)
+	}
+
+	// PENDING -> ACTIVE skips payment.
+	if err := f.repo.UpdateBookingStatus(ctx, b.ID, models.BookingStatusActive); !errors.Is(err, repositories.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition for PENDING -> ACTIVE, got %v", err)
+	}
+	if b, err = f.bookings.ConfirmBooking(ctx, b.ID); err != nil || b.Status != models.BookingStatusConfirmed {
+		t.Fatalf("Expected CONFIRMED, got %v, %v", b, err)
+	}
+	for _, step := range []models.BookingStatus{models.BookingStatusActive, models.BookingStatusCompleted} {
+		if err := f.repo.UpdateBookingStatus(ctx, b.ID, step); err != nil {
+			t.Fatalf("Expected transition to %s, got %v", step, err)
+		}
+	}
+	if _, err := f.bookings.CancelBooking(ctx, b.ID, models.CancelledByRenter, "user1"); err == nil {
+		t.Error("Expected cancelling a COMPLETED booking to fail")
+	}
+}
+
+func TestPendingHoldExpires(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	f.bookings.SetHoldTTL(10 * time.Minute)
+
+	held, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(2), jan(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.bookings.CreateBooking(ctx, "user2", f.car.ID, jan(3), jan(5)); !errors.Is(err, repositories.ErrOverlap) {
+		t.Fatalf("Expected the hold to block the dates, got %v", err)
+	}
+
+	f.now = f.now.Add(5 * time.Minute)
+	if n, err := f.bookings.ExpireHolds(ctx); err != nil || n != 0 {
+		t.Fatalf("Expected no holds expired before the TTL, got %d, %v", n, err)
+	}
+
+	f.now = f.now.Add(6 * time.Minute)
+	if n, err := f.bookings.ExpireHolds(ctx); err != nil || n != 1 {
+		t.Fatalf("Expected 1 hold expired, got %d, %v", n, err)
+	}
+	if _, err := f.bookings.ConfirmBooking(ctx, held.ID); !errors.Is(err, repositories.ErrInvalidTransition) {
+		t.Errorf("Expected confirming an expired hold to fail, got %v", err)
+	}
+	if _, err := f.bookings.CreateBooking(ctx, "user2", f.car.ID, jan(3), jan(5)); err != nil {
+		t.Errorf("Expected the dates to be released, got %v", err)
+	}
+}
+
+func TestConfirmAfterTTLExpiresHold(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(2), jan(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.now = f.now.Add(DefaultHoldTTL + time.Second)
+	if _, err := f.bookings.ConfirmBooking(ctx, b.ID); !errors.Is(err, ErrHoldExpired) {
+		t.Fatalf("Expected ErrHoldExpired, got %v", err)
+	}
+	if got, _ := f.repo.GetBooking(ctx, b.ID); got.Status != models.BookingStatusExpired {
+		t.Errorf("Expected EXPIRED, got %s", got.Status)
+	}
+}
+
+func TestHoldSweeperExpiresInBackground(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := newBookingFixture(t)
+
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(2), jan(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.bookings.SetClock(func() time.Time { return b.HoldExpiresAt.Add(time.Second) })
+	NewHoldSweeper(f.bookings, time.Millisecond).Start(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, _ := f.repo.GetBooking(ctx, b.ID); got.Status == models.BookingStatusExpired {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("Expected the sweeper to expire the hold")
+}
+
+func TestCancellationRefunds(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		by          models.CancellationActor
+		actorID     string
+		noticeHours int
+		wantPercent float64
+		wantAmount  float64
+	}{
+		{"renter with 6 days notice", models.CancelledByRenter, "user1", 6 * 24, 100, 200},
+		{"renter with 2 days notice", models.CancelledByRenter, "user1", 2 * 24, 50, 100},
+		{"renter with 3 hours notice", models.CancelledByRenter, "user1", 3, 0, 0},
+		{"host with 3 hours notice", models.CancelledByHost, "host1", 3, 100, 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newBookingFixture(t)
+			b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(10), jan(12))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.bookings.ConfirmBooking(ctx, b.ID); err != nil {
+				t.Fatal(err)
+			}
+
+			f.now = b.StartTime.Add(-time.Duration(tt.noticeHours) * time.Hour)
+			refund, err := f.bookings.CancelBooking(ctx, b.ID, tt.by, tt.actorID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if refund.Percent != tt.wantPercent || refund.Amount != tt.wantAmount {
+				t.Errorf("Expected %.0f%% / %.2f refund, got %.0f%% / %.2f", tt.wantPercent, tt.wantAmount, refund.Percent, refund.Amount)
+			}
+
+			stored, _ := f.repo.GetRefundsForBooking(ctx, b.ID)
+			if len(stored) != 1 || stored[0].Amount != tt.wantAmount {
+				t.Errorf("Expected one stored refund of %.2f, got %v", tt.wantAmount, stored)
+			}
+			if _, err := f.bookings.CancelBooking(ctx, b.ID, tt.by, tt.actorID); err == nil {
+				t.Error("Expected a second cancellation to fail")
+			}
+		})
+	}
+}
+
+// confirmAfterRead confirms the booking right after the first GetBooking,
+// as a payment landing while a cancellation is being worked out would.
+type confirmAfterRead struct {
+	repositories.BookingRepository
+	confirmed bool
+	at        time.Time
+}
+
+func (r *confirmAfterRead) GetBooking(ctx context.Context, id string) (*models.Booking, error) {
+	b, err := r.BookingRepository.GetBooking(ctx, id)
+	if err == nil && !r.confirmed {
+		r.confirmed = true
+		if err := r.BookingRepository.MarkConfirmed(ctx, id, r.at); err != nil {
+			return nil, err
+		}
+	}
+	return b, err
+}
+
+func TestCancellationRefundsCurrentStatus(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(10), jan(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bookings := NewBookingService(&confirmAfterRead{BookingRepository: f.repo, at: f.now}, f.repo, f.repo, f.repo)
+	bookings.SetClock(func() time.Time { return f.now })
+
+	// Read as a PENDING hold, but paid by the time it's cancelled: the
+	// renter gets the policy's refund, not the hold's nothing.
+	refund, err := bookings.CancelBooking(ctx, b.ID, models.CancelledByRenter, "user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Percent != 100 || refund.Amount != 200 {
+		t.Errorf("Expected a full refund of the paid booking, got %.0f%% / %.2f", refund.Percent, refund.Amount)
+	}
+}
+
+func TestCancellationRequiresParty(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(10), jan(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.bookings.CancelBooking(ctx, b.ID, models.CancelledByRenter, "someone-else"); err == nil {
+		t.Error("Expected a stranger to be refused")
+	}
+	if _, err := f.bookings.CancelBooking(ctx, b.ID, models.CancelledByHost, "user1"); err == nil {
+		t.Error("Expected the renter to be refused as host")
+	}
+
+	refund, err := f.bookings.CancelBooking(ctx, b.ID, models.CancelledByRenter, "user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Amount != 0 {
+		t.Errorf("Expected no refund for an unpaid hold, got %.2f", refund.Amount)
+	}
+}
+
+func TestBookingErrorsAreTyped(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(10), jan(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nf *models.NotFoundError
+	_, err = f.bookings.CreateBooking(ctx, "user1", "nope", jan(10), jan(12))
+	if !errors.As(err, &nf) || nf.Entity != "car" || nf.ID != "nope" {
+		t.Errorf("Expected NotFoundError for car nope, got %v", err)
+	}
+
+	var conflict *models.ConflictError
+	_, err = f.bookings.CreateBooking(ctx, "user2", f.car.ID, jan(11), jan(13))
+	if !errors.As(err, &conflict) || conflict.BookingID != b.ID || !errors.Is(err, repositories.ErrOverlap) {
+		t.Errorf("Expected a conflict with booking %s, got %v", b.ID, err)
+	}
+
+	var invalid *models.ValidationError
+	_, err = f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(12), jan(10))
+	if !errors.As(err, &invalid) || invalid.Field != "end_time" {
+		t.Errorf("Expected a ValidationError on end_time, got %v", err)
+	}
+	_, err = f.bookings.CancelBooking(ctx, b.ID, models.CancelledByRenter, "someone-else")
+	if !errors.Is(err, models.ErrValidation) || !errors.As(err, &invalid) || invalid.Field != "actor_id" {
+		t.Errorf("Expected a ValidationError on actor_id, got %v", err)
+	}
+
+	f.now = b.HoldExpiresAt.Add(time.Second)
+	_, err = f.bookings.ConfirmBooking(ctx, b.ID)
+	if !errors.Is(err, ErrHoldExpired) || !errors.As(err, &conflict) || conflict.BookingID != b.ID {
+		t.Errorf("Expected an ErrHoldExpired conflict for booking %s, got %v", b.ID, err)
+	}
+}
+
+func TestModifyBooking(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(10), jan(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err = f.bookings.ConfirmBooking(ctx, b.ID); err != nil {
+		t.Fatal(err)
+	}
+	next, err := f.bookings.CreateBooking(ctx, "user2", f.car.ID, jan(15), jan(17))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Extending over its own dates is fine; into the next booking is not.
+	extended, err := f.bookings.ModifyBooking(ctx, b.ID, jan(10), jan(15))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extended.TotalPrice != 500 || extended.Status != models.BookingStatusConfirmed || len(extended.Modifications) != 1 {
+		t.Fatalf("Expected a CONFIRMED booking at 500 with 1 modification, got %s at %v with %d", extended.Status, extended.TotalPrice, len(extended.Modifications))
+	}
+	if mod := extended.Modifications[0]; !mod.PreviousEnd.Equal(jan(12)) || mod.PreviousPrice != 200 || mod.PriceDifference != 300 {
+		t.Errorf("Expected the change from Jan 12 at 200 (+300) recorded, got %+v", mod)
+	}
+	var conflict *models.ConflictError
+	_, err = f.bookings.ModifyBooking(ctx, b.ID, jan(10), jan(16))
+	if !errors.Is(err, repositories.ErrOverlap) || !errors.As(err, &conflict) || conflict.BookingID != next.ID {
+		t.Errorf("Expected an overlap with booking %s, got %v", next.ID, err)
+	}
+	if _, err := f.bookings.ModifyBooking(ctx, b.ID, jan(29), jan(32)); !errors.Is(err, repositories.ErrNoAvailability) {
+		t.Errorf("Expected moving past the availability to fail, got %v", err)
+	}
+
+	moved, err := f.bookings.ModifyBooking(ctx, b.ID, jan(20), jan(21))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved.Modifications) != 2 || moved.Modifications[1].PriceDifference != -400 || moved.TotalPrice != 100 {
+		t.Errorf("Expected a second modification refunding 400, got %+v", moved.Modifications)
+	}
+	// The old dates are free again.
+	if _, err := f.bookings.CreateBooking(ctx, "user2", f.car.ID, jan(10), jan(12)); err != nil {
+		t.Errorf("Expected the vacated dates to be bookable, got %v", err)
+	}
+
+	if err := f.repo.RecordTripInspection(ctx, &models.InspectionReport{BookingID: b.ID, Type: models.InspectionTypePickup, CreatedAt: jan(20)}, nil); err != nil {
+		t.Fatal(err)
+	}
+	f.now = jan(20).Add(time.Hour)
+	if _, err := f.bookings.ModifyBooking(ctx, b.ID, jan(19), jan(22)); !errors.Is(err, repositories.ErrInvalidTransition) {
+		t.Errorf("Expected moving the start of an ACTIVE trip to fail, got %v", err)
+	}
+	if active, err := f.bookings.ModifyBooking(ctx, b.ID, jan(20), jan(22)); err != nil || active.TotalPrice != 200 {
+		t.Errorf("Expected an ACTIVE trip to be extended to 200, got %v", err)
+	}
+
+	if _, err := f.bookings.CancelBooking(ctx, next.ID, models.CancelledByRenter, "user2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.bookings.ModifyBooking(ctx, next.ID, jan(25), jan(27)); !errors.Is(err, repositories.ErrInvalidTransition) {
+		t.Errorf("Expected a CANCELLED booking to be unmodifiable, got %v", err)
+	}
+}
+
+func TestBookingsAcrossDSTUseCarLocalDays(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(m time.Month, d, h int) time.Time { return time.Date(2026, m, d, h, 0, 0, 0, ny).UTC() }
+
+	car := &models.Car{HostID: "host1", PricePerDay: 100, PricePerHour: 15, IsActive: true, Location: models.Location{City: "New York", TimeZone: "America/New_York"}}
+	if err := f.inv.RegisterCar(ctx, car); err != nil {
+		t.Fatal(err)
+	}
+	// All day, every day through Nov 30 in the car's zone.
+	rule, err := f.inv.AddRecurringAvailability(ctx, &models.RecurrenceRule{
+		CarID:     car.ID,
+		Frequency: models.FrequencyDaily,
+		EndOfDay:  24 * time.Hour,
+		StartDate: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		Until:     time.Date(2026, 11, 30, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.TimeZone != "America/New_York" {
+		t.Errorf("Expected the rule to take the car's time zone, got %q", rule.TimeZone)
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       float64
+	}{
+		{"spring forward, 23 hours", at(3, 7, 10), at(3, 8, 10), 100},
+		{"fall back, 25 hours", at(10, 31, 10), at(11, 1, 10), 100},
+		// Nov 30 ends at local midnight, 05:00 UTC on Dec 1.
+		{"last local evening", at(11, 30, 20), at(12, 1, 0), 60},
+	}
+	for _, tt := range tests {
+		b, err := f.bookings.CreateBooking(ctx, "user1", car.ID, tt.start, tt.end)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if b.TotalPrice != tt.want {
+			t.Errorf("%s: expected %.2f, got %.2f", tt.name, tt.want, b.TotalPrice)
+		}
+	}
+	if _, err := f.bookings.CreateBooking(ctx, "user1", car.ID, at(12, 1, 0), at(12, 1, 2)); !errors.Is(err, repositories.ErrNoAvailability) {
+		t.Errorf("Expected the rule to end at local midnight, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"fmt"
+	"time"
+)
+
+// RefundTier refunds Percent of the booking price when the renter cancels at
+// least MinNotice before pickup.
+type RefundTier struct {
+	MinNotice time.Duration
+	Percent   float64
+}
+
+// refundTiers are checked in order; the first tier whose notice is met wins.
+var refundTiers = map[models.CancellationPolicy][]RefundTier{
+	models.CancellationPolicyFlexible: {
+		{MinNotice: 24 * time.Hour, Percent: 100},
+		{MinNotice: 0, Percent: 50},
+	},
+	models.CancellationPolicyModerate: {
+		{MinNotice: 5 * 24 * time.Hour, Percent: 100},
+		{MinNotice: 24 * time.Hour, Percent: 50},
+	},
+	models.CancellationPolicyStrict: {
+		{MinNotice: 14 * 24 * time.Hour, Percent: 100},
+		{MinNotice: 7 * 24 * time.Hour, Percent: 50},
+	},
+}
+
+// computeRefund applies the cancellation rules:
+//   - nothing has been paid on a PENDING hold, so there's nothing to refund;
+//   - a host cancellation always refunds the renter in full;
+//   - a renter cancellation is refunded per the host's policy and the notice
+//     given before pickup.
+func computeRefund(booking *models.Booking, policy models.CancellationPolicy, by models.CancellationActor, now time.Time) *models.Refund {
+	if policy == "" {
+		policy = models.CancellationPolicyFlexible
+	}
+	refund := &models.Refund{
+		BookingID:   booking.ID,
+		UserID:      booking.UserID,
+		CancelledBy: by,
+		Policy:      policy,
+		CreatedAt:   now,
+	}
+
+	switch {
+	case booking.Status == models.BookingStatusPending:
+		refund.Reason = "hold released before payment"
+		return refund
+	case by == models.CancelledByHost:
+		refund.Percent = 100
+		refund.Reason = "cancelled by host"
+	default:
+		notice := booking.StartTime.Sub(now)
+		for _, tier := range refundTiers[policy] {
+			if notice >= tier.MinNotice {
+				refund.Percent = tier.Percent
+				break
+			}
+		}
+		if notice > 0 {
+			refund.Reason = fmt.Sprintf("cancelled by renter %s before pickup under %s policy", notice.Truncate(time.Minute), policy)
+		} else {
+			refund.Reason = fmt.Sprintf("cancelled by renter after scheduled pickup under %s policy", policy)
+		}
+	}
+	refund.Amount = roundCents(booking.TotalPrice * refund.Percent / 100)
+	return refund
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"car-rental-lite/src/idgen"
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"errors"
+	"time"
+)
+
+// ClaimService handles damage claims. Damage is only attributable to a trip
+// when it's bracketed by pickup and dropoff reports, so a claim needs both.
+type ClaimService struct {
+	inspectionRepo repositories.InspectionRepository
+	bookingRepo    repositories.BookingRepository
+	carRepo        repositories.CarRepository
+	ids            idgen.IDGenerator
+	now            func() time.Time
+	events         EventPublisher
+}
+
+func NewClaimService(insRepo repositories.InspectionRepository, bRepo repositories.BookingRepository, cRepo repositories.CarRepository) *ClaimService {
+	return &ClaimService{
+		inspectionRepo: insRepo,
+		bookingRepo:    bRepo,
+		carRepo:        cRepo,
+		ids:            idgen.NewULIDGenerator(),
+		now:            time.Now,
+		events:         nopPublisher{},
+	}
+}
+
+func (s *ClaimService) SetIDGenerator(ids idgen.IDGenerator) {
+	s.ids = ids
+}
+
+// SetClock replaces time.Now, e.g. with a fake clock in tests.
+func (s *ClaimService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// SetEventPublisher sets where the service's domain events go. By default
+// they're discarded.
+func (s *ClaimService) SetEventPublisher(events EventPublisher) {
+	s.events = events
+}
+
+// FileClaim submits a host's damage claim for a booking that has both a
+// pickup and a dropoff inspection.
+func (s *ClaimService) FileClaim(ctx context.Context, claim *models.DamageClaim) (*models.DamageClaim, error) {
+	if claim.Description == "" {
+		return nil, &models.ValidationError{Field: "description", Message: "claim description is required"}
+	}
+	if len(claim.EvidenceImageURLs) == 0 {
+		return nil, &models.ValidationError{Field: "evidence_image_urls", Message: "claim requires evidence images"}
+	}
+	if claim.EstimatedCost <= 0 {
+		return nil, &models.ValidationError{Field: "estimated_cost", Message: "estimated cost must be positive"}
+	}
+
+	booking, err := s.bookingRepo.GetBooking(ctx, claim.BookingID)
+	if err != nil {
+		return nil, err
+	}
+	car, err := s.carRepo.GetCar(ctx, booking.CarID)
+	if err != nil {
+		return nil, err
+	}
+	if claim.ClaimantID != car.HostID {
+		return nil, &models.ValidationError{Field: "claimant_id", Message: "only the car's host can file a claim"}
+	}
+
+	reports, err := s.inspectionRepo.GetInspectionsForBooking(ctx, booking.ID)
+	if err != nil {
+		return nil, err
+	}
+	if findReport(reports, models.InspectionTypePickup) == nil || findReport(reports, models.InspectionTypeDropoff) == nil {
+		return nil, &models.ConflictError{Err: errors.New("claims require both pickup and dropoff inspections"), BookingID: claim.BookingID}
+	}
+
+	now := s.now()
+	claim.ID = s.ids.NewID()
+	claim.Status = models.ClaimStatusSubmitted
+	claim.CreatedAt = now
+	claim.UpdatedAt = now
+	if err := s.inspectionRepo.CreateClaim(ctx, claim); err != nil {
+		return nil, err
+	}
+	publish(ctx, s.events, models.ClaimFiled{
+		ClaimID:       claim.ID,
+		BookingID:     claim.BookingID,
+		ClaimantID:    claim.ClaimantID,
+		EstimatedCost: claim.EstimatedCost,
+		OccurredAt:    now,
+	})
+	return claim, nil
+}
+
+// StartReview moves a SUBMITTED claim to UNDER_REVIEW.
+func (s *ClaimService) StartReview(ctx context.Context, claimID string) (*models.DamageClaim, error) {
+	return s.transition(ctx, claimID, models.ClaimStatusUnderReview)
+}
+
+// Approve moves a claim under review to APPROVED.
+func (s *ClaimService) Approve(ctx context.Context, claimID string) (*models.DamageClaim, error) {
+	return s.transition(ctx, claimID, models.ClaimStatusApproved)
+}
+
+// Reject moves a claim under review to REJECTED.
+func (s *ClaimService) Reject(ctx context.Context, claimID string) (*models.DamageClaim, error) {
+	return s.transition(ctx, claimID, models.ClaimStatusRejected)
+}
+
+func (s *ClaimService) transition(ctx context.Context, claimID string, status models.ClaimStatus) (*models.DamageClaim, error) {
+	if err := s.inspectionRepo.UpdateClaimStatus(ctx, claimID, status, s.now()); err != nil {
+		return nil, err
+	}
+	return s.inspectionRepo.GetClaim(ctx, claimID)
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Defaults for NewDefaultEligibilityChain.
+const (
+	DefaultMinimumAge            = 21
+	DefaultLuxuryMinimumAge      = 25
+	DefaultMaxConcurrentBookings = 2
+)
+
+// EligibilityCheck is the prospective booking the policies judge.
+type EligibilityCheck struct {
+	User  *models.User
+	Car   *models.Car
+	Start time.Time
+	End   time.Time
+	Now   time.Time
+	// BookingID is the booking being modified, if any. It doesn't count
+	// against the renter's limits.
+	BookingID string
+}
+
+// EligibilityPolicy is one rule a renter must pass before booking. Check
+// returns nil if the renter passes and the reason if not; an error means the
+// policy couldn't decide.
+type EligibilityPolicy interface {
+	Check(ctx context.Context, c *EligibilityCheck) (*models.EligibilityReason, error)
+}
+
+// BookingsPolicy is an EligibilityPolicy whose answer depends on the renter's
+// other bookings, which a concurrent booking can change between Check and the
+// write. The chain hands CheckBookings to the repository to re-run in the
+// same critical section as the reservation.
+type BookingsPolicy interface {
+	EligibilityPolicy
+	// CheckBookings is Check against others, the renter's bookings
+	// overlapping the requested dates.
+	CheckBookings(c *EligibilityCheck, others []*models.Booking) *models.EligibilityReason
+}
+
+type EligibilityChain struct {
+	policies []EligibilityPolicy
+}
+
+// NewEligibilityChain runs every policy, so a rejection lists all the reasons
+// rather than only the first.
+func NewEligibilityChain(policies ...EligibilityPolicy) *EligibilityChain {
+	return &EligibilityChain{policies: policies}
+}
+
+// NewDefaultEligibilityChain requires an unexpired license, no ban, the
+// default minimum ages and at most DefaultMaxConcurrentBookings overlapping
+// open bookings.
+func NewDefaultEligibilityChain(bRepo repositories.BookingRepository) *EligibilityChain {
+	return NewEligibilityChain(
+		&BannedUserPolicy{},
+		&DriverLicensePolicy{},
+		&MinimumAgePolicy{Default: DefaultMinimumAge, ByCarType: map[models.CarType]int{models.CarTypeLuxury: DefaultLuxuryMinimumAge}},
+		&ConcurrentBookingsPolicy{Bookings: bRepo, Max: DefaultMaxConcurrentBookings},
+	)
+}
+
+// Check returns a *models.IneligibleError listing every policy the renter
+// fails, or nil.
+func (e *EligibilityChain) Check(ctx context.Context, c *EligibilityCheck) error {
+	var reasons []models.EligibilityReason
+	for _, p := range e.policies {
+		reason, err := p.Check(ctx, c)
+		if err != nil {
+			return err
+		}
+		if reason != nil {
+			reasons = append(reasons, *reason)
+		}
+	}
+	if len(reasons) > 0 {
+		return &models.IneligibleError{UserID: c.User.ID, Reasons: reasons}
+	}
+	return nil
+}
+
+// BookingsCheck returns the chain's BookingsPolicies as a check for the
+// repository to run when it writes the booking, or nil if there are none.
+// It fails with a *models.IneligibleError as Check does.
+func (e *EligibilityChain) BookingsCheck(c *EligibilityCheck) repositories.BookingsCheck {
+	var policies []BookingsPolicy
+	for _, p := range e.policies {
+		if bp, ok := p.(BookingsPolicy); ok {
+			policies = append(policies, bp)
+		}
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+	return func(others []*models.Booking) error {
+		var reasons []models.EligibilityReason
+		for _, p := range policies {
+			if reason := p.CheckBookings(c, others); reason != nil {
+				reasons = append(reasons, *reason)
+			}
+		}
+		if len(reasons) > 0 {
+			return &models.IneligibleError{UserID: c.User.ID, Reasons: reasons}
+		}
+		return nil
+	}
+}
+
+// MinimumAgePolicy requires the renter to be old enough for the car's type
+// on the first day of the booking.
+type MinimumAgePolicy struct {
+	Default   int
+	ByCarType map[models.CarType]int // overrides Default
+}
+
+func (p *MinimumAgePolicy) Check(ctx context.Context, c *EligibilityCheck) (*models.EligibilityReason, error) {
+	minAge, ok := p.ByCarType[c.Car.Type]
+	if !ok {
+		minAge = p.Default
+	}
+	if minAge <= 0 {
+		return nil, nil
+	}
+	if c.User.DateOfBirth.IsZero() {
+		return &models.EligibilityReason{Code: models.EligibilityUnderage, Message: "date of birth is required to check the minimum age"}, nil
+	}
+	if age := c.User.AgeOn(c.Start); age < minAge {
+		return &models.EligibilityReason{Code: models.EligibilityUnderage, Message: fmt.Sprintf("renters of this car must be at least %d, renter will be %d", minAge, age)}, nil
+	}
+	return nil, nil
+}
+
+// DriverLicensePolicy requires a license on file that is valid until the
+// car is returned.
+type DriverLicensePolicy struct{}
+
+func (p *DriverLicensePolicy) Check(ctx context.Context, c *EligibilityCheck) (*models.EligibilityReason, error) {
+	if c.User.DriverLicense == "" || c.User.LicenseExpiresAt.IsZero() {
+		return &models.EligibilityReason{Code: models.EligibilityLicenseMissing, Message: "a driver license and its expiry date are required"}, nil
+	}
+	if c.User.LicenseExpiresAt.Before(c.End) {
+		return &models.EligibilityReason{Code: models.EligibilityLicenseExpired, Message: "driver license expires before the booking ends"}, nil
+	}
+	return nil, nil
+}
+
+// BannedUserPolicy refuses renters who have been banned from the platform.
+type BannedUserPolicy struct{}
+
+func (p *BannedUserPolicy) Check(ctx context.Context, c *EligibilityCheck) (*models.EligibilityReason, error) {
+	if c.User.Banned {
+		return &models.EligibilityReason{Code: models.EligibilityBanned, Message: "renter is banned"}, nil
+	}
+	return nil, nil
+}
+
+// ConcurrentBookingsPolicy caps how many open bookings a renter may have
+// overlapping the requested dates. Lapsed holds don't count. It's a
+// BookingsPolicy, so the cap is enforced again as the booking is written.
+type ConcurrentBookingsPolicy struct {
+	Bookings repositories.BookingRepository
+	Max      int
+}
+
+func (p *ConcurrentBookingsPolicy) Check(ctx context.Context, c *EligibilityCheck) (*models.EligibilityReason, error) {
+	bookings, err := p.Bookings.GetBookingsForUser(ctx, c.User.ID, c.Start, c.End)
+	if err != nil {
+		return nil, err
+	}
+	return p.CheckBookings(c, bookings), nil
+}
+
+func (p *ConcurrentBookingsPolicy) CheckBookings(c *EligibilityCheck, others []*models.Booking) *models.EligibilityReason {
+	open := 0
+	for _, b := range others {
+		if b.ID != c.BookingID && b.Status.IsOpen() && !(b.Status == models.BookingStatusPending && c.Now.After(b.HoldExpiresAt)) {
+			open++
+		}
+	}
+	if open >= p.Max {
+		return &models.EligibilityReason{Code: models.EligibilityTooManyBookings, Message: fmt.Sprintf("renter already has %d bookings over these dates, the limit is %d", open, p.Max)}
+	}
+	return nil
+}
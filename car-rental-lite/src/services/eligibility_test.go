@@ -0,0 +1,161 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEligibilityPolicies(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	luxury := &models.Car{HostID: "host1", Type: models.CarTypeLuxury, PricePerDay: 300, IsActive: true}
+	if err := f.inv.RegisterCar(ctx, luxury); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.inv.AddAvailability(ctx, luxury.ID, jan(1), jan(31)); err != nil {
+		t.Fatal(err)
+	}
+	// Turns 25 on Jan 10 2026.
+	born := time.Date(2001, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		user  func(u *models.User)
+		car   *models.Car
+		start time.Time
+		want  []models.EligibilityCode
+	}{
+		{"licensed adult", func(u *models.User) {}, luxury, jan(2), nil},
+		{"24 on a luxury car", func(u *models.User) { u.DateOfBirth = born }, luxury, jan(9), []models.EligibilityCode{models.EligibilityUnderage}},
+		{"25 on the first day", func(u *models.User) { u.DateOfBirth = born }, luxury, jan(10), nil},
+		{"24 on a sedan", func(u *models.User) { u.DateOfBirth = born }, f.car, jan(9), nil},
+		{"no date of birth", func(u *models.User) { u.DateOfBirth = time.Time{} }, f.car, jan(2), []models.EligibilityCode{models.EligibilityUnderage}},
+		{"no license", func(u *models.User) { u.DriverLicense = "" }, f.car, jan(2), []models.EligibilityCode{models.EligibilityLicenseMissing}},
+		{"license expires mid-trip", func(u *models.User) { u.LicenseExpiresAt = jan(3) }, f.car, jan(2), []models.EligibilityCode{models.EligibilityLicenseExpired}},
+		{"banned and unlicensed", func(u *models.User) { u.Banned, u.DriverLicense = true, "" }, f.car, jan(2),
+			[]models.EligibilityCode{models.EligibilityBanned, models.EligibilityLicenseMissing}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := licensedRenter(fmt.Sprintf("renter%d", i))
+			tt.user(user)
+			if err := f.repo.CreateUser(ctx, user); err != nil {
+				t.Fatal(err)
+			}
+			_, err := f.bookings.CreateBooking(ctx, user.ID, tt.car.ID, tt.start, tt.start.Add(48*time.Hour))
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Expected the booking to succeed, got %v", err)
+				}
+				return
+			}
+			var inel *models.IneligibleError
+			if !errors.Is(err, models.ErrIneligible) || !errors.As(err, &inel) {
+				t.Fatalf("Expected an IneligibleError, got %v", err)
+			}
+			var got []models.EligibilityCode
+			for _, r := range inel.Reasons {
+				got = append(got, r.Code)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected reasons %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEligibilityLimitsConcurrentBookings(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	f.bookings.SetEligibilityChain(NewEligibilityChain(&ConcurrentBookingsPolicy{Bookings: f.repo, Max: 1}))
+	other := &models.Car{HostID: "host1", PricePerDay: 100, IsActive: true}
+	if err := f.inv.RegisterCar(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.inv.AddAvailability(ctx, other.ID, jan(1), jan(31)); err != nil {
+		t.Fatal(err)
+	}
+
+	held, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(2), jan(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.bookings.CreateBooking(ctx, "user1", other.ID, jan(3), jan(5)); !errors.Is(err, models.ErrIneligible) {
+		t.Errorf("Expected a second car over the same dates to be refused, got %v", err)
+	}
+	if _, err := f.bookings.CreateBooking(ctx, "user1", other.ID, jan(4), jan(6)); err != nil {
+		t.Errorf("Expected back-to-back bookings to be allowed, got %v", err)
+	}
+
+	// A lapsed hold no longer counts, even before the sweeper expires it.
+	f.now = held.HoldExpiresAt.Add(time.Second)
+	if _, err := f.bookings.CreateBooking(ctx, "user1", other.ID, jan(2), jan(3)); err != nil {
+		t.Errorf("Expected the lapsed hold to be ignored, got %v", err)
+	}
+}
+
+// userBookingsBarrier makes every GetBookingsForUser call wait until wg's
+// count of calls have arrived.
+type userBookingsBarrier struct {
+	repositories.BookingRepository
+	wg sync.WaitGroup
+}
+
+func (r *userBookingsBarrier) GetBookingsForUser(ctx context.Context, userID string, from, to time.Time) ([]*models.Booking, error) {
+	bookings, err := r.BookingRepository.GetBookingsForUser(ctx, userID, from, to)
+	r.wg.Done()
+	r.wg.Wait()
+	return bookings, err
+}
+
+func TestEligibilityLimitsConcurrentBookingsUnderContention(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	cars := []string{f.car.ID}
+	for i := 0; i < 9; i++ {
+		car := &models.Car{HostID: "host1", PricePerDay: 100, IsActive: true}
+		if err := f.inv.RegisterCar(ctx, car); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.inv.AddAvailability(ctx, car.ID, jan(1), jan(31)); err != nil {
+			t.Fatal(err)
+		}
+		cars = append(cars, car.ID)
+	}
+
+	// The renter books every car at once. The barrier holds each request
+	// until all have passed the up-front check, so only the check made as
+	// the booking is written can hold the cap.
+	barrier := &userBookingsBarrier{BookingRepository: f.repo}
+	barrier.wg.Add(len(cars))
+	bookings := NewBookingService(barrier, f.repo, f.repo, f.repo)
+	bookings.SetClock(func() time.Time { return f.now })
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	booked := 0
+	for _, carID := range cars {
+		wg.Add(1)
+		go func(carID string) {
+			defer wg.Done()
+			_, err := bookings.CreateBooking(ctx, "user1", carID, jan(2), jan(4))
+			switch {
+			case err == nil:
+				mu.Lock()
+				booked++
+				mu.Unlock()
+			case !errors.Is(err, models.ErrIneligible):
+				t.Errorf("Expected an IneligibleError, got %v", err)
+			}
+		}(carID)
+	}
+	wg.Wait()
+	if booked != DefaultMaxConcurrentBookings {
+		t.Fatalf("Expected %d bookings, got %d", DefaultMaxConcurrentBookings, booked)
+	}
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// EventRelay delivers an EventBus's events to its subscribers. It dispatches
+// as soon as events are published and retries failed deliveries every
+// interval.
+type EventRelay struct {
+	bus      *EventBus
+	interval time.Duration
+}
+
+func NewEventRelay(bus *EventBus, interval time.Duration) *EventRelay {
+	return &EventRelay{bus: bus, interval: interval}
+}
+
+// Start runs the relay in a goroutine until ctx is done.
+func (r *EventRelay) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.bus.wake:
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			if _, err := r.bus.Dispatch(ctx); err != nil {
+				fmt.Printf("[event-relay] dispatching events: %v\n", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// EventPublisher receives the services' domain events. Services publish an
+// event only after the repository write it describes has committed, so a
+// refused or failed operation never emits anything. Publishing isn't part of
+// the write: if the process dies in between, the write stands and the event
+// is lost. A publish error is logged rather than failing the operation,
+// which has already happened.
+type EventPublisher interface {
+	Publish(ctx context.Context, events ...models.Event) error
+}
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(ctx context.Context, events ...models.Event) error { return nil }
+
+func publish(ctx context.Context, p EventPublisher, events ...models.Event) {
+	if err := p.Publish(ctx, events...); err != nil {
+		fmt.Printf("[events] publishing %d events: %v\n", len(events), err)
+	}
+}
+
+// EventHandler consumes a delivered event. Returning an error leaves the
+// event queued to be retried.
+type EventHandler func(ctx context.Context, event models.Event) error
+
+type subscription struct {
+	types   map[models.EventType]bool // nil for every type
+	handler EventHandler
+}
+
+type busEntry struct {
+	event models.Event
+	// subs is how many subscribers there were when the event was published;
+	// later subscribers don't receive it.
+	subs int
+	// next is the index of the first subscriber not yet delivered to.
+	next int
+}
+
+// EventBus is an in-process, after-commit EventPublisher: Publish queues
+// events in memory, and an EventRelay delivers them to subscribers
+// afterwards, off the request path. It is not a transactional outbox. The
+// queue isn't written with the repository change, so an event published
+// just before a crash, or still queued at a restart, is lost. Within a
+// process, delivery is in publish order and at least once per subscriber:
+// an event stays queued until every subscriber has accepted it, and a
+// subscriber that fails blocks the events behind it until it recovers.
+type EventBus struct {
+	mu      sync.Mutex
+	pending []*busEntry
+	subs    []subscription
+	wake    chan struct{}
+	// dispatching serialises Dispatch so events are never delivered out of
+	// order or twice concurrently.
+	dispatching sync.Mutex
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{wake: make(chan struct{}, 1)}
+}
+
+// Subscribe registers handler for the given event types, or for every event
+// if none are given. Only events published after subscribing are delivered.
+func (b *EventBus) Subscribe(handler EventHandler, types ...models.EventType) {
+	sub := subscription{handler: handler}
+	if len(types) > 0 {
+		sub.types = make(map[models.EventType]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, sub)
+}
+
+func (b *EventBus) Publish(ctx context.Context, events ...models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	b.mu.Lock()
+	for _, ev := range events {
+		b.pending = append(b.pending, &busEntry{event: ev, subs: len(b.subs)})
+	}
+	b.mu.Unlock()
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Pending returns the number of events not yet delivered to every
+// subscriber.
+func (b *EventBus) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pending)
+}
+
+// Dispatch delivers pending events in order until the queue is empty or a
+// subscriber fails. It returns the number of events fully delivered and the
+// first handler error, if any; the failed event is retried by the next call.
+func (b *EventBus) Dispatch(ctx context.Context) (int, error) {
+	b.dispatching.Lock()
+	defer b.dispatching.Unlock()
+
+	delivered := 0
+	for {
+		b.mu.Lock()
+		if len(b.pending) == 0 {
+			b.mu.Unlock()
+			return delivered, nil
+		}
+		entry := b.pending[0]
+		subs := b.subs
+		b.mu.Unlock()
+
+		// entry.next is only touched here, under b.dispatching.
+		for ; entry.next < entry.subs; entry.next++ {
+			sub := subs[entry.next]
+			if sub.types == nil || sub.types[entry.event.EventType()] {
+				if err := sub.handler(ctx, entry.event); err != nil {
+					return delivered, fmt.Errorf("delivering %s: %w", entry.event.EventType(), err)
+				}
+			}
+		}
+
+		b.mu.Lock()
+		b.pending = b.pending[1:]
+		b.mu.Unlock()
+		delivered++
+	}
+}
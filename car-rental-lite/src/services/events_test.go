@@ -0,0 +1,160 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// recordEvents subscribes to every event on b and returns the delivered
+// events' types.
+func recordEvents(b *EventBus) *[]models.EventType {
+	var got []models.EventType
+	b.Subscribe(func(ctx context.Context, ev models.Event) error {
+		got = append(got, ev.EventType())
+		return nil
+	})
+	return &got
+}
+
+func TestServicesPublishEventsAfterWrites(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	bus := NewEventBus()
+	got := recordEvents(bus)
+	f.bookings.SetEventPublisher(bus)
+	f.inv.SetEventPublisher(bus)
+
+	slot, err := f.inv.AddAvailability(ctx, f.car.ID, jan(31), jan(31).AddDate(0, 0, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.inv.BlockDates(ctx, f.car.ID, jan(20), jan(22), false); err != nil {
+		t.Fatal(err)
+	}
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(2), jan(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Refused writes publish nothing.
+	if _, err := f.bookings.CreateBooking(ctx, "user2", f.car.ID, jan(3), jan(5)); err == nil {
+		t.Fatal("Expected the overlapping booking to fail")
+	}
+	if err := f.inv.BlockDates(ctx, f.car.ID, jan(1), jan(10), false); err == nil {
+		t.Fatal("Expected blocking over the booking to fail")
+	}
+	if _, err := f.bookings.CancelBooking(ctx, b.ID, models.CancelledByRenter, "user1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.inv.RemoveAvailability(ctx, slot.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*got) != 0 {
+		t.Fatalf("Expected nothing delivered before dispatch, got %v", *got)
+	}
+	if n, err := bus.Dispatch(ctx); err != nil || n != 5 {
+		t.Fatalf("Expected 5 events dispatched, got %d, %v", n, err)
+	}
+	want := []models.EventType{models.EventAvailabilityChanged, models.EventAvailabilityChanged,
+		models.EventBookingCreated, models.EventBookingCancelled, models.EventAvailabilityChanged}
+	if fmt.Sprint(*got) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, *got)
+	}
+}
+
+func TestTripAndClaimPublishEvents(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	bus := NewEventBus()
+	var completed models.BookingCompleted
+	bus.Subscribe(func(ctx context.Context, ev models.Event) error {
+		completed = ev.(models.BookingCompleted)
+		return nil
+	}, models.EventBookingCompleted)
+	claimed := 0
+	bus.Subscribe(func(ctx context.Context, ev models.Event) error {
+		claimed++
+		return nil
+	}, models.EventClaimFiled)
+
+	// completeBooking's services aren't wired to the bus.
+	b := completeBooking(t, f, jan(2), jan(4))
+	if bus.Pending() != 0 {
+		t.Fatal("Expected no events from unwired services")
+	}
+	claims := NewClaimService(f.repo, f.repo, f.repo)
+	claims.SetEventPublisher(bus)
+	if _, err := claims.FileClaim(ctx, &models.DamageClaim{BookingID: b.ID, ClaimantID: "host1", Description: "Dent", EvidenceImageURLs: []string{"dent.jpg"}, EstimatedCost: 150}); err != nil {
+		t.Fatal(err)
+	}
+
+	inspections := NewInspectionService(f.repo, f.repo, f.repo)
+	trips := NewTripService(f.repo, inspections)
+	trips.SetEventPublisher(bus)
+	second, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(6), jan(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.bookings.ConfirmBooking(ctx, second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trips.StartTrip(ctx, &models.InspectionReport{BookingID: second.ID, InspectorID: "host1", Type: models.InspectionTypePickup, FuelLevel: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trips.EndTrip(ctx, &models.InspectionReport{BookingID: second.ID, InspectorID: "host1", Type: models.InspectionTypeDropoff, FuelLevel: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := bus.Dispatch(ctx); err != nil || n != 2 {
+		t.Fatalf("Expected 2 events dispatched, got %d, %v", n, err)
+	}
+	if claimed != 1 || completed.BookingID != second.ID || completed.UserID != "user1" {
+		t.Errorf("Expected a claim and the second booking completed, got %d and %+v", claimed, completed)
+	}
+}
+
+func TestEventBusRetriesFailedDeliveryInOrder(t *testing.T) {
+	ctx := context.Background()
+	bus := NewEventBus()
+	got := recordEvents(bus)
+	fail := true
+	flaky := 0
+	bus.Subscribe(func(ctx context.Context, ev models.Event) error {
+		flaky++
+		if fail {
+			return errors.New("downstream unavailable")
+		}
+		return nil
+	}, models.EventBookingCreated)
+
+	bus.Publish(ctx, models.ClaimFiled{ClaimID: "c1"}, models.BookingCreated{BookingID: "b1"}, models.BookingCancelled{BookingID: "b1"})
+	// Subscribers added later don't see earlier events.
+	late := recordEvents(bus)
+
+	if n, err := bus.Dispatch(ctx); err == nil || n != 1 {
+		t.Fatalf("Expected 1 event dispatched before the failure, got %d, %v", n, err)
+	}
+	if bus.Pending() != 2 {
+		t.Errorf("Expected 2 events left pending, got %d", bus.Pending())
+	}
+
+	fail = false
+	if n, err := bus.Dispatch(ctx); err != nil || n != 2 {
+		t.Fatalf("Expected the rest dispatched, got %d, %v", n, err)
+	}
+	// The first subscriber accepted BookingCreated before the failure, so
+	// it isn't delivered to it again.
+	want := []models.EventType{models.EventClaimFiled, models.EventBookingCreated, models.EventBookingCancelled}
+	if fmt.Sprint(*got) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, *got)
+	}
+	if flaky != 2 {
+		t.Errorf("Expected BookingCreated attempted twice, got %d", flaky)
+	}
+	if len(*late) != 0 {
+		t.Errorf("Expected the late subscriber to see nothing, got %v", *late)
+	}
+}
@@ -0,0 +1,572 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FleetService manages a host's cars in bulk: importing cars and
+// availability, exporting the fleet, and switching cars on and off.
+type FleetService struct {
+	userRepo      repositories.UserRepository
+	carRepo       repositories.CarRepository
+	inventoryRepo repositories.InventoryRepository
+	bookingRepo   repositories.BookingRepository
+	inventory     *InventoryService
+	bookings      *BookingService
+	now           func() time.Time
+}
+
+func NewFleetService(uRepo repositories.UserRepository, cRepo repositories.CarRepository, iRepo repositories.InventoryRepository, bRepo repositories.BookingRepository, inventory *InventoryService, bookings *BookingService) *FleetService {
+	return &FleetService{
+		userRepo:      uRepo,
+		carRepo:       cRepo,
+		inventoryRepo: iRepo,
+		bookingRepo:   bRepo,
+		inventory:     inventory,
+		bookings:      bookings,
+		now:           time.Now,
+	}
+}
+
+// SetClock replaces time.Now, e.g. with a fake clock in tests.
+func (s *FleetService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// FleetImport is a batch of cars and availability windows for one host.
+// Availability rows may refer to a car by an ID given on a car row of the
+// same import.
+type FleetImport struct {
+	Cars         []ImportCar          `json:"cars"`
+	Availability []ImportAvailability `json:"availability"`
+}
+
+// ImportCar is a Car whose is_active defaults to true when omitted. The ID
+// is optional and the host is always the importing one.
+type ImportCar struct {
+	models.Car
+	IsActive *bool `json:"is_active"`
+	// problems are cells that didn't parse, e.g. in a CSV upload.
+	problems []ImportProblem
+}
+
+type ImportAvailability struct {
+	CarID     string    `json:"car_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	problems  []ImportProblem
+}
+
+// ImportProblem is why one field of an import row was rejected.
+type ImportProblem struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ImportRowResult reports one import row. Row is 1-based within its section.
+// ID is the car or availability slot created; it's empty in a dry run
+// unless the row set it.
+type ImportRowResult struct {
+	Row      int             `json:"row"`
+	OK       bool            `json:"ok"`
+	ID       string          `json:"id,omitempty"`
+	Problems []ImportProblem `json:"problems,omitempty"`
+}
+
+type ImportResult struct {
+	DryRun       bool              `json:"dry_run"`
+	Cars         []ImportRowResult `json:"cars"`
+	Availability []ImportRowResult `json:"availability"`
+	// Imported counts the rows written, or that would be in a dry run.
+	Imported int `json:"imported"`
+	Failed   int `json:"failed"`
+	// Stopped is set when a row couldn't be written and the import ended
+	// there. The rows before it were written; it and the rest were not.
+	Stopped bool `json:"stopped,omitempty"`
+}
+
+func (r *ImportResult) add(rows *[]ImportRowResult, res ImportRowResult) {
+	res.OK = len(res.Problems) == 0
+	if res.OK {
+		r.Imported++
+	} else {
+		r.Failed++
+	}
+	*rows = append(*rows, res)
+}
+
+// stop ends an import at a row that couldn't be written, row being 1-based
+// within section ("cars" or "availability"). It reports that row and every
+// row not yet reached as not imported, and returns r.
+func (r *ImportResult) stop(batch *FleetImport, section string, row int) *ImportResult {
+	r.Stopped = true
+	rows := &r.Availability
+	if section == "cars" {
+		rows = &r.Cars
+	}
+	r.add(rows, ImportRowResult{Row: row, Problems: []ImportProblem{{Field: "row", Message: "could not be written"}}})
+	skipped := []ImportProblem{{Field: "row", Message: fmt.Sprintf("was not imported because the import stopped at %s row %d", section, row)}}
+	for i := len(r.Cars); i < len(batch.Cars); i++ {
+		r.add(&r.Cars, ImportRowResult{Row: i + 1, Problems: skipped})
+	}
+	for i := len(r.Availability); i < len(batch.Availability); i++ {
+		r.add(&r.Availability, ImportRowResult{Row: i + 1, Problems: skipped})
+	}
+	return r
+}
+
+// Import validates every row and writes the valid ones; invalid rows are
+// reported and skipped, and don't stop the rest. With dryRun nothing is
+// written, so the result shows what a real import would do.
+//
+// Rows are written one at a time. If a write fails, Import stops and
+// returns the error together with the result so far, marked Stopped, so the
+// caller can tell which rows were written.
+func (s *FleetService) Import(ctx context.Context, hostID string, batch *FleetImport, dryRun bool) (*ImportResult, error) {
+	if _, err := s.userRepo.GetHost(ctx, hostID); err != nil {
+		return nil, err
+	}
+	fleet, err := s.carRepo.GetCarsByHost(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+	// owned holds the cars availability rows may refer to.
+	owned := make(map[string]bool, len(fleet))
+	for _, c := range fleet {
+		owned[c.ID] = true
+	}
+
+	res := &ImportResult{DryRun: dryRun, Cars: []ImportRowResult{}, Availability: []ImportRowResult{}}
+	seen := make(map[string]bool)
+	for i, row := range batch.Cars {
+		problems, err := s.checkImportCar(ctx, hostID, &row, seen)
+		if err != nil {
+			return res.stop(batch, "cars", i+1), fmt.Errorf("checking cars row %d: %w", i+1, err)
+		}
+		rr := ImportRowResult{Row: i + 1, ID: row.ID, Problems: problems}
+		if len(problems) == 0 {
+			car := row.Car
+			car.HostID = hostID
+			car.IsActive = row.IsActive == nil || *row.IsActive
+			if !dryRun {
+				if err := s.inventory.RegisterCar(ctx, &car); err != nil {
+					return res.stop(batch, "cars", i+1), fmt.Errorf("importing cars row %d: %w", i+1, err)
+				}
+				rr.ID = car.ID
+			}
+			if car.ID != "" {
+				owned[car.ID] = true
+			}
+		}
+		res.add(&res.Cars, rr)
+	}
+
+	for i, row := range batch.Availability {
+		problems := append([]ImportProblem(nil), row.problems...)
+		switch {
+		case row.CarID == "":
+			problems = append(problems, ImportProblem{Field: "car_id", Message: "is required"})
+		case !owned[row.CarID]:
+			problems = append(problems, ImportProblem{Field: "car_id", Message: "is not one of the host's cars or a car imported with it"})
+		}
+		if row.StartTime.IsZero() || row.EndTime.IsZero() {
+			problems = append(problems, ImportProblem{Field: "start_time", Message: "start and end times are required"})
+		} else if !row.StartTime.Before(row.EndTime) {
+			problems = append(problems, ImportProblem{Field: "end_time", Message: "start time must be before end time"})
+		}
+		rr := ImportRowResult{Row: i + 1, Problems: problems}
+		if len(problems) == 0 && !dryRun {
+			slot, err := s.inventory.AddAvailability(ctx, row.CarID, row.StartTime, row.EndTime)
+			if err != nil {
+				return res.stop(batch, "availability", i+1), fmt.Errorf("importing availability row %d: %w", i+1, err)
+			}
+			rr.ID = slot.ID
+		}
+		res.add(&res.Availability, rr)
+	}
+	return res, nil
+}
+
+// checkImportCar validates a car row as the API validates a new car, and
+// records its ID in seen.
+func (s *FleetService) checkImportCar(ctx context.Context, hostID string, row *ImportCar, seen map[string]bool) ([]ImportProblem, error) {
+	problems := append([]ImportProblem(nil), row.problems...)
+	check := func(ok bool, field, message string) {
+		if !ok {
+			problems = append(problems, ImportProblem{Field: field, Message: message})
+		}
+	}
+	car := &row.Car
+	if car.ID != "" {
+		check(!seen[car.ID], "id", "is repeated in this import")
+		seen[car.ID] = true
+		_, err := s.carRepo.GetCar(ctx, car.ID)
+		if err != nil && !errors.Is(err, models.ErrNotFound) {
+			return nil, err
+		}
+		check(err != nil, "id", "is already taken")
+	}
+	check(car.HostID == "" || car.HostID == hostID, "host_id", "must be empty or the importing host")
+	check(car.Rating == (models.Rating{}), "rating", "is computed from reviews")
+	check(car.Make != "", "make", "is required")
+	check(car.Model != "", "model", "is required")
+	check(car.Year >= 1900 && car.Year <= s.now().Year()+1, "year", "is out of range")
+	check(slices.Contains(models.CarTypes, car.Type), "type", "must be one of SEDAN, SUV, COMPACT, LUXURY, VAN")
+	check(car.PricePerDay > 0, "price_per_day", "must be positive")
+	check(car.PricePerHour >= 0, "price_per_hour", "must not be negative")
+	check(car.Location.Latitude >= -90 && car.Location.Latitude <= 90, "latitude", "must be between -90 and 90")
+	check(car.Location.Longitude >= -180 && car.Location.Longitude <= 180, "longitude", "must be between -180 and 180")
+	_, err := car.Location.TimeLocation()
+	check(err == nil, "time_zone", "is not a known IANA time zone")
+	for _, a := range car.Amenities {
+		check(slices.Contains(models.Amenities, a), "amenities", fmt.Sprintf("%q is not a known amenity", a))
+	}
+	return problems, nil
+}
+
+// Columns of the fleet CSV files, in export order. Imports match columns by
+// header name, in any order, and may leave any out.
+var (
+	carCSVColumns = []string{"id", "make", "model", "year", "type", "price_per_day", "price_per_hour", "license_plate",
+		"latitude", "longitude", "address", "city", "zip_code", "time_zone", "amenities", "is_active"}
+	availabilityCSVColumns = []string{"car_id", "start_time", "end_time"}
+	bookingCSVColumns      = []string{"id", "car_id", "user_id", "status", "start_time", "end_time", "total_price", "created_at"}
+)
+
+// csvRow is one data row of an import, looked up by column name.
+type csvRow struct {
+	header   map[string]int
+	cells    []string
+	problems []ImportProblem
+}
+
+func (r *csvRow) get(col string) string {
+	if i, ok := r.header[col]; ok && i < len(r.cells) {
+		return strings.TrimSpace(r.cells[i])
+	}
+	return ""
+}
+
+func (r *csvRow) float(col string) float64 {
+	v := r.get(col)
+	if v == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		r.problems = append(r.problems, ImportProblem{Field: col, Message: "is not a number"})
+	}
+	return f
+}
+
+func (r *csvRow) time(col string) time.Time {
+	v := r.get(col)
+	if v == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		r.problems = append(r.problems, ImportProblem{Field: col, Message: "must be an RFC 3339 time"})
+	}
+	return t
+}
+
+// readCSV reads a header row naming only known columns, then the data rows.
+// A structural problem fails the whole file with a *models.ValidationError;
+// a row with the wrong number of cells is reported against the row.
+func readCSV(r io.Reader, known []string) ([]*csvRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, &models.ValidationError{Field: "csv", Message: err.Error()}
+	}
+	if len(records) == 0 {
+		return nil, &models.ValidationError{Field: "csv", Message: "header row is required"}
+	}
+	header := make(map[string]int, len(records[0]))
+	for i, col := range records[0] {
+		col = strings.ToLower(strings.TrimSpace(col))
+		if !slices.Contains(known, col) {
+			return nil, &models.ValidationError{Field: "csv", Message: fmt.Sprintf("unknown column %q", col)}
+		}
+		if _, dup := header[col]; dup {
+			return nil, &models.ValidationError{Field: "csv", Message: fmt.Sprintf("column %q appears twice", col)}
+		}
+		header[col] = i
+	}
+	rows := make([]*csvRow, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		row := &csvRow{header: header, cells: rec}
+		if len(rec) != len(header) {
+			row.problems = append(row.problems, ImportProblem{Field: "csv", Message: fmt.Sprintf("row has %d cells, header has %d", len(rec), len(header))})
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// ReadCarsCSV parses cars in the format WriteCarsCSV produces. Amenities are
+// separated by semicolons and an empty is_active means true. Cells that
+// don't parse are reported against their row by Import.
+func ReadCarsCSV(r io.Reader) ([]ImportCar, error) {
+	rows, err := readCSV(r, carCSVColumns)
+	if err != nil {
+		return nil, err
+	}
+	cars := make([]ImportCar, 0, len(rows))
+	for _, row := range rows {
+		var c ImportCar
+		c.ID = row.get("id")
+		c.Make = row.get("make")
+		c.Model = row.get("model")
+		if v := row.get("year"); v != "" {
+			year, err := strconv.Atoi(v)
+			if err != nil {
+				row.problems = append(row.problems, ImportProblem{Field: "year", Message: "is not a number"})
+			}
+			c.Year = year
+		}
+		c.Type = models.CarType(strings.ToUpper(row.get("type")))
+		c.PricePerDay = row.float("price_per_day")
+		c.PricePerHour = row.float("price_per_hour")
+		c.LicensePlate = row.get("license_plate")
+		c.Location = models.Location{
+			Latitude:  row.float("latitude"),
+			Longitude: row.float("longitude"),
+			Address:   row.get("address"),
+			City:      row.get("city"),
+			ZipCode:   row.get("zip_code"),
+			TimeZone:  row.get("time_zone"),
+		}
+		for _, a := range strings.Split(row.get("amenities"), ";") {
+			if a = strings.TrimSpace(a); a != "" {
+				c.Amenities = append(c.Amenities, models.Amenity(strings.ToUpper(a)))
+			}
+		}
+		if v := row.get("is_active"); v != "" {
+			active, err := strconv.ParseBool(v)
+			if err != nil {
+				row.problems = append(row.problems, ImportProblem{Field: "is_active", Message: "must be true or false"})
+			}
+			c.IsActive = &active
+		}
+		c.problems = row.problems
+		cars = append(cars, c)
+	}
+	return cars, nil
+}
+
+// ReadAvailabilityCSV parses availability windows in the format
+// WriteAvailabilityCSV produces.
+func ReadAvailabilityCSV(r io.Reader) ([]ImportAvailability, error) {
+	rows, err := readCSV(r, availabilityCSVColumns)
+	if err != nil {
+		return nil, err
+	}
+	windows := make([]ImportAvailability, 0, len(rows))
+	for _, row := range rows {
+		w := ImportAvailability{CarID: row.get("car_id"), StartTime: row.time("start_time"), EndTime: row.time("end_time")}
+		w.problems = row.problems
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// FleetExport is a host's cars with the availability slots and bookings
+// that overlap [From, To), and their recurrence rules.
+type FleetExport struct {
+	HostID          string                     `json:"host_id"`
+	From            time.Time                  `json:"from"`
+	To              time.Time                  `json:"to"`
+	Cars            []*models.Car              `json:"cars"`
+	Availability    []*models.AvailabilitySlot `json:"availability"`
+	RecurrenceRules []*models.RecurrenceRule   `json:"recurrence_rules"`
+	Bookings        []*models.Booking          `json:"bookings"`
+}
+
+func (s *FleetService) Export(ctx context.Context, hostID string, from, to time.Time) (*FleetExport, error) {
+	if _, err := s.userRepo.GetHost(ctx, hostID); err != nil {
+		return nil, err
+	}
+	cars, err := s.carRepo.GetCarsByHost(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+	export := &FleetExport{
+		HostID:          hostID,
+		From:            from,
+		To:              to,
+		Cars:            append([]*models.Car{}, cars...),
+		Availability:    []*models.AvailabilitySlot{},
+		RecurrenceRules: []*models.RecurrenceRule{},
+		Bookings:        []*models.Booking{},
+	}
+	for _, car := range cars {
+		slots, err := s.inventoryRepo.GetAvailability(ctx, car.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		rules, err := s.inventoryRepo.GetRecurrenceRules(ctx, car.ID)
+		if err != nil {
+			return nil, err
+		}
+		bookings, err := s.bookingRepo.GetBookingsForCar(ctx, car.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		export.Availability = append(export.Availability, slots...)
+		export.RecurrenceRules = append(export.RecurrenceRules, rules...)
+		export.Bookings = append(export.Bookings, bookings...)
+	}
+	return export, nil
+}
+
+// WriteCarsCSV writes one row per car, in the format ReadCarsCSV reads.
+func (e *FleetExport) WriteCarsCSV(w io.Writer) error {
+	records := [][]string{carCSVColumns}
+	for _, c := range e.Cars {
+		amenities := make([]string, len(c.Amenities))
+		for i, a := range c.Amenities {
+			amenities[i] = string(a)
+		}
+		records = append(records, []string{c.ID, c.Make, c.Model, strconv.Itoa(c.Year), string(c.Type), formatFloat(c.PricePerDay), formatFloat(c.PricePerHour), c.LicensePlate,
+			formatFloat(c.Location.Latitude), formatFloat(c.Location.Longitude), c.Location.Address, c.Location.City, c.Location.ZipCode, c.Location.TimeZone,
+			strings.Join(amenities, ";"), strconv.FormatBool(c.IsActive)})
+	}
+	return writeCSV(w, records)
+}
+
+// WriteAvailabilityCSV writes one row per explicit availability slot, in the
+// format ReadAvailabilityCSV reads. Recurrence rules are only in the JSON
+// export.
+func (e *FleetExport) WriteAvailabilityCSV(w io.Writer) error {
+	records := [][]string{availabilityCSVColumns}
+	for _, s := range e.Availability {
+		records = append(records, []string{s.CarID, s.StartTime.Format(time.RFC3339), s.EndTime.Format(time.RFC3339)})
+	}
+	return writeCSV(w, records)
+}
+
+// WriteBookingsCSV writes one row per booking, with RFC 3339 times.
+func (e *FleetExport) WriteBookingsCSV(w io.Writer) error {
+	records := [][]string{bookingCSVColumns}
+	for _, b := range e.Bookings {
+		records = append(records, []string{b.ID, b.CarID, b.UserID, string(b.Status), b.StartTime.Format(time.RFC3339), b.EndTime.Format(time.RFC3339),
+			formatFloat(b.TotalPrice), b.CreatedAt.Format(time.RFC3339)})
+	}
+	return writeCSV(w, records)
+}
+
+// ActivationResult reports one car of a bulk activation.
+type ActivationResult struct {
+	CarID string `json:"car_id"`
+	// IsActive is the car's state afterwards.
+	IsActive bool `json:"is_active"`
+	// Conflicts are upcoming bookings that kept the car from being
+	// deactivated.
+	Conflicts []string `json:"conflicts,omitempty"`
+	// Cancelled are upcoming bookings the host cancelled so the car could be
+	// deactivated.
+	Cancelled []string `json:"cancelled,omitempty"`
+	// Error is why the car was skipped, e.g. it isn't the host's.
+	Error string `json:"error,omitempty"`
+}
+
+// SetActive switches the host's cars on or off for new bookings, one car at
+// a time. If a car can't be updated SetActive stops there and returns the
+// error together with the results so far, the failed car and the ones not
+// reached reporting an Error. A car
+// can't be deactivated while it has upcoming bookings (confirmed, or held
+// and not yet lapsed): unless cancelBookings is set it stays active and the
+// bookings are listed as conflicts. With cancelBookings the host cancels
+// them, which refunds the renters in full, and the car is deactivated. Trips
+// already in progress are left alone either way.
+func (s *FleetService) SetActive(ctx context.Context, hostID string, carIDs []string, active, cancelBookings bool) ([]ActivationResult, error) {
+	if len(carIDs) == 0 {
+		return nil, &models.ValidationError{Field: "car_ids", Message: "at least one car is required"}
+	}
+	if _, err := s.userRepo.GetHost(ctx, hostID); err != nil {
+		return nil, err
+	}
+	results := make([]ActivationResult, 0, len(carIDs))
+	for i, carID := range carIDs {
+		res, err := s.setCarActive(ctx, hostID, carID, active, cancelBookings)
+		if err != nil {
+			// The cars before this one were already switched, and this one's
+			// bookings may have been cancelled, so report them with the error.
+			res.Error = "could not be updated"
+			results = append(results, res)
+			for _, rest := range carIDs[i+1:] {
+				results = append(results, ActivationResult{CarID: rest, Error: fmt.Sprintf("was not updated because the batch stopped at car %s", carID)})
+			}
+			return results, fmt.Errorf("updating car %s: %w", carID, err)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func (s *FleetService) setCarActive(ctx context.Context, hostID, carID string, active, cancelBookings bool) (ActivationResult, error) {
+	res := ActivationResult{CarID: carID}
+	car, err := s.carRepo.GetCar(ctx, carID)
+	if errors.Is(err, models.ErrNotFound) {
+		res.Error = err.Error()
+		return res, nil
+	}
+	if err != nil {
+		return res, err
+	}
+	res.IsActive = car.IsActive
+	if car.HostID != hostID {
+		res.Error = "car belongs to another host"
+		return res, nil
+	}
+
+	if !active && cancelBookings {
+		upcoming, err := s.bookingRepo.GetUpcomingBookingsForCar(ctx, carID, s.now())
+		if err != nil {
+			return res, err
+		}
+		for _, b := range upcoming {
+			_, err := s.bookings.CancelBooking(ctx, b.ID, models.CancelledByHost, hostID)
+			if errors.Is(err, models.ErrConflict) {
+				// Picked up or otherwise closed since we listed it.
+				continue
+			}
+			if err != nil {
+				return res, err
+			}
+			res.Cancelled = append(res.Cancelled, b.ID)
+		}
+	}
+
+	err = s.carRepo.SetCarActive(ctx, carID, active, s.now())
+	if errors.Is(err, repositories.ErrCarHasBookings) {
+		// Report every booking in the way, not only the one the repository
+		// tripped over. One may also have been reserved since we cancelled.
+		upcoming, err := s.bookingRepo.GetUpcomingBookingsForCar(ctx, carID, s.now())
+		if err != nil {
+			return res, err
+		}
+		for _, b := range upcoming {
+			res.Conflicts = append(res.Conflicts, b.ID)
+		}
+		return res, nil
+	}
+	if err != nil {
+		return res, err
+	}
+	res.IsActive = active
+	return res, nil
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"bytes"
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFleetService(f *bookingFixture) *FleetService {
+	fleet := NewFleetService(f.repo, f.repo, f.repo, f.repo, f.inv, f.bookings)
+	fleet.SetClock(func() time.Time { return f.now })
+	return fleet
+}
+
+func TestFleetImport(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	fleet := newFleetService(f)
+
+	cars := "id,make,model,year,type,price_per_day,amenities\n" +
+		"van1,Ford,Transit,2023,van,120,gps; bluetooth\n" +
+		",Honda,,2021,SEDAN,abc,\n" +
+		"van1,Ford,Transit,2023,VAN,120,\n"
+	windows := "car_id,start_time,end_time\n" +
+		"van1,2026-01-01T00:00:00Z,2026-02-01T00:00:00Z\n" +
+		f.car.ID + ",2026-02-01T00:00:00Z,2026-03-01T00:00:00Z\n" +
+		"someone-elses-car,2026-01-01T00:00:00Z,2026-02-01T00:00:00Z\n" +
+		"van1,2026-02-01T00:00:00Z,2026-01-01T00:00:00Z\n"
+	batch := &FleetImport{}
+	var err error
+	if batch.Cars, err = ReadCarsCSV(strings.NewReader(cars)); err != nil {
+		t.Fatal(err)
+	}
+	if batch.Availability, err = ReadAvailabilityCSV(strings.NewReader(windows)); err != nil {
+		t.Fatal(err)
+	}
+
+	dry, err := fleet.Import(ctx, "host1", batch, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dry.Imported != 3 || dry.Failed != 4 {
+		t.Errorf("Expected 3 rows to pass and 4 to fail, got %d and %d", dry.Imported, dry.Failed)
+	}
+	if _, err := f.repo.GetCar(ctx, "van1"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected a dry run to write nothing, got %v", err)
+	}
+	fields := func(r ImportRowResult) string {
+		var names []string
+		for _, p := range r.Problems {
+			names = append(names, p.Field)
+		}
+		return strings.Join(names, ",")
+	}
+	if got := fields(dry.Cars[1]); got != "price_per_day,model,price_per_day" {
+		t.Errorf("Expected the Honda row to fail on price and model, got %s", got)
+	}
+	if got := fields(dry.Cars[2]); got != "id" {
+		t.Errorf("Expected the repeated van1 to fail on id, got %s", got)
+	}
+	if got := fields(dry.Availability[2]) + "|" + fields(dry.Availability[3]); got != "car_id|end_time" {
+		t.Errorf("Expected the unknown car and reversed window to fail, got %s", got)
+	}
+
+	res, err := fleet.Import(ctx, "host1", batch, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Imported != 3 || res.Cars[0].ID != "van1" || res.Availability[0].ID == "" {
+		t.Errorf("Expected the same 3 rows imported, got %+v", res)
+	}
+	van, err := f.repo.GetCar(ctx, "van1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if van.HostID != "host1" || !van.IsActive || van.Type != models.CarTypeVan || len(van.Amenities) != 2 {
+		t.Errorf("Expected an active van with 2 amenities for host1, got %+v", van)
+	}
+	if ok, _ := f.repo.HasAvailabilitySlot(ctx, "van1", jan(10), jan(12)); !ok {
+		t.Error("Expected the imported van to be available in January")
+	}
+
+	if _, err := ReadCarsCSV(strings.NewReader("id,colour\nx,red\n")); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected an unknown column to fail the file, got %v", err)
+	}
+}
+
+// failingCars refuses to store or switch the car with ID failID.
+type failingCars struct {
+	repositories.CarRepository
+	failID string
+}
+
+func (r failingCars) SetCarActive(ctx context.Context, carID string, active bool, at time.Time) error {
+	if carID == r.failID {
+		return errors.New("disk full")
+	}
+	return r.CarRepository.SetCarActive(ctx, carID, active, at)
+}
+
+func (r failingCars) CreateCar(ctx context.Context, car *models.Car) error {
+	if car.ID == r.failID {
+		return errors.New("disk full")
+	}
+	return r.CarRepository.CreateCar(ctx, car)
+}
+
+func TestFleetImportReportsRowsWrittenBeforeAFailure(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	inv := NewInventoryService(failingCars{f.repo, "van2"}, f.repo, f.repo, f.repo)
+	fleet := NewFleetService(f.repo, f.repo, f.repo, f.repo, inv, f.bookings)
+	fleet.SetClock(func() time.Time { return f.now })
+
+	car := func(id string) ImportCar {
+		return ImportCar{Car: models.Car{ID: id, Make: "Ford", Model: "Transit", Year: 2023, Type: models.CarTypeVan, PricePerDay: 120}}
+	}
+	batch := &FleetImport{
+		Cars:         []ImportCar{car("van1"), car("van2"), car("van3")},
+		Availability: []ImportAvailability{{CarID: "van1", StartTime: jan(1), EndTime: jan(31)}},
+	}
+	res, err := fleet.Import(ctx, "host1", batch, false)
+	if err == nil {
+		t.Fatal("Expected the failed write to be returned")
+	}
+	if res == nil || !res.Stopped {
+		t.Fatalf("Expected a stopped result, got %+v", res)
+	}
+	if res.Imported != 1 || res.Failed != 3 || len(res.Cars) != 3 || len(res.Availability) != 1 {
+		t.Fatalf("Expected 1 row imported and 3 not, got %+v", res)
+	}
+	if !res.Cars[0].OK || res.Cars[0].ID != "van1" {
+		t.Errorf("Expected van1 reported as written, got %+v", res.Cars[0])
+	}
+	for _, rr := range []ImportRowResult{res.Cars[1], res.Cars[2], res.Availability[0]} {
+		if rr.OK || len(rr.Problems) != 1 {
+			t.Errorf("Expected row %d reported as not imported, got %+v", rr.Row, rr)
+		}
+	}
+	if _, err := f.repo.GetCar(ctx, "van1"); err != nil {
+		t.Errorf("Expected van1 to have been written, got %v", err)
+	}
+	if _, err := f.repo.GetCar(ctx, "van3"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected van3 not to be written, got %v", err)
+	}
+}
+
+func TestFleetExportRoundTrips(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	fleet := newFleetService(f)
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(2), jan(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	export, err := fleet.Export(ctx, "host1", jan(1), jan(31))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(export.Cars) != 1 || len(export.Availability) != 1 || len(export.Bookings) != 1 || export.Bookings[0].ID != b.ID {
+		t.Fatalf("Expected the car, its slot and its booking, got %+v", export)
+	}
+
+	var buf bytes.Buffer
+	if err := export.WriteCarsCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	cars, err := ReadCarsCSV(&buf)
+	if err != nil || len(cars) != 1 || len(cars[0].problems) != 0 {
+		t.Fatalf("Expected the exported car to read back cleanly, got %+v, %v", cars, err)
+	}
+	if cars[0].ID != f.car.ID || cars[0].PricePerDay != 100 || cars[0].IsActive == nil || !*cars[0].IsActive {
+		t.Errorf("Expected the fixture car back, got %+v", cars[0])
+	}
+
+	buf.Reset()
+	if err := export.WriteAvailabilityCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	windows, err := ReadAvailabilityCSV(&buf)
+	if err != nil || len(windows) != 1 || !windows[0].StartTime.Equal(jan(1)) || !windows[0].EndTime.Equal(jan(31)) {
+		t.Errorf("Expected the January slot back, got %+v, %v", windows, err)
+	}
+}
+
+func TestFleetDeactivationFlagsUpcomingBookings(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	fleet := newFleetService(f)
+	spare := &models.Car{ID: "spare", HostID: "host1", PricePerDay: 100, IsActive: true}
+	if err := f.inv.RegisterCar(ctx, spare); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.inv.AddAvailability(ctx, spare.ID, jan(1), jan(31)); err != nil {
+		t.Fatal(err)
+	}
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(2), jan(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.bookings.ConfirmBooking(ctx, b.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := fleet.SetActive(ctx, "host1", []string{f.car.ID, spare.ID, "missing"}, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results[0]; !r.IsActive || len(r.Conflicts) != 1 || r.Conflicts[0] != b.ID {
+		t.Errorf("Expected the booked car to stay active with a conflict, got %+v", r)
+	}
+	if r := results[1]; r.IsActive || r.Error != "" {
+		t.Errorf("Expected the spare car deactivated, got %+v", r)
+	}
+	if results[2].Error == "" {
+		t.Error("Expected an error for the unknown car")
+	}
+	if _, err := f.bookings.CreateBooking(ctx, "user2", spare.ID, jan(10), jan(12)); !errors.Is(err, ErrCarInactive) {
+		t.Errorf("Expected the deactivated car to refuse bookings, got %v", err)
+	}
+
+	results, err = fleet.SetActive(ctx, "host1", []string{f.car.ID}, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results[0]; r.IsActive || len(r.Cancelled) != 1 || len(r.Conflicts) != 0 {
+		t.Errorf("Expected the booking cancelled and the car deactivated, got %+v", r)
+	}
+	refunds, _ := f.repo.GetRefundsForBooking(ctx, b.ID)
+	if len(refunds) != 1 || refunds[0].CancelledBy != models.CancelledByHost || refunds[0].Amount != b.TotalPrice {
+		t.Errorf("Expected a full host refund, got %+v", refunds)
+	}
+
+	if results, _ := fleet.SetActive(ctx, "host1", []string{spare.ID}, true, false); !results[0].IsActive {
+		t.Errorf("Expected the spare car reactivated, got %+v", results[0])
+	}
+}
+
+func TestFleetDeactivationReportsCarsSwitchedBeforeAFailure(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	fleet := NewFleetService(f.repo, failingCars{f.repo, "broken"}, f.repo, f.repo, f.inv, f.bookings)
+	fleet.SetClock(func() time.Time { return f.now })
+	for _, id := range []string{"broken", "spare"} {
+		if err := f.inv.RegisterCar(ctx, &models.Car{ID: id, HostID: "host1", PricePerDay: 100, IsActive: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(2), jan(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := fleet.SetActive(ctx, "host1", []string{f.car.ID, "broken", "spare"}, false, true)
+	if err == nil {
+		t.Fatal("Expected the failed update to be returned")
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected a result for each car, got %+v", results)
+	}
+	if r := results[0]; r.IsActive || r.Error != "" || len(r.Cancelled) != 1 || r.Cancelled[0] != b.ID {
+		t.Errorf("Expected the first car deactivated with its booking cancelled, got %+v", r)
+	}
+	if results[1].Error == "" || results[2].Error == "" {
+		t.Errorf("Expected the failed car and the one after it to report errors, got %+v and %+v", results[1], results[2])
+	}
+	if spare, err := f.repo.GetCar(ctx, "spare"); err != nil || !spare.IsActive {
+		t.Errorf("Expected the car after the failure to be left active, got %+v, %v", spare, err)
+	}
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// HoldSweeper periodically expires PENDING bookings whose payment never
+// arrived, so their dates become bookable again.
+type HoldSweeper struct {
+	bookings *BookingService
+	interval time.Duration
+}
+
+func NewHoldSweeper(bookings *BookingService, interval time.Duration) *HoldSweeper {
+	return &HoldSweeper{bookings: bookings, interval: interval}
+}
+
+// Start runs the sweeper in a goroutine until ctx is done.
+func (s *HoldSweeper) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := s.bookings.ExpireHolds(ctx); err != nil {
+					fmt.Printf("[hold-sweeper] expiring holds: %v\n", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
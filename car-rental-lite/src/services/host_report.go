@@ -0,0 +1,393 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultCommissionRate is the platform's share of what a host earns.
+const DefaultCommissionRate = 0.15
+
+type CalendarState string
+
+const (
+	CalendarAvailable CalendarState = "AVAILABLE"
+	CalendarBooked    CalendarState = "BOOKED"
+	CalendarBlocked   CalendarState = "BLOCKED" // not offered by the host
+)
+
+// CalendarEntry is a maximal run of one state. Booked entries name the
+// booking holding the car.
+type CalendarEntry struct {
+	Start     time.Time            `json:"start"`
+	End       time.Time            `json:"end"`
+	State     CalendarState        `json:"state"`
+	BookingID string               `json:"booking_id,omitempty"`
+	Status    models.BookingStatus `json:"status,omitempty"`
+}
+
+type CarCalendar struct {
+	CarID   string          `json:"car_id"`
+	Entries []CalendarEntry `json:"entries"`
+}
+
+// CalendarReport covers [From, To) for every car of a host, ordered by car ID.
+type CalendarReport struct {
+	HostID string        `json:"host_id"`
+	From   time.Time     `json:"from"`
+	To     time.Time     `json:"to"`
+	Cars   []CarCalendar `json:"cars"`
+}
+
+// CarOccupancy compares the hours a car was booked with the hours it was
+// offered (available or booked). Unpaid holds count as offered, not booked.
+type CarOccupancy struct {
+	CarID        string  `json:"car_id"`
+	OfferedHours float64 `json:"offered_hours"`
+	BookedHours  float64 `json:"booked_hours"`
+	Rate         float64 `json:"rate"` // BookedHours / OfferedHours, 0 when nothing was offered
+}
+
+type OccupancyReport struct {
+	HostID string         `json:"host_id"`
+	From   time.Time      `json:"from"`
+	To     time.Time      `json:"to"`
+	Cars   []CarOccupancy `json:"cars"`
+	Fleet  CarOccupancy   `json:"fleet"`
+}
+
+// EarningsRow totals the paid bookings of one car starting in one month
+// (UTC). Gross includes post-trip adjustments; Net is what the host keeps
+// after refunds and commission.
+type EarningsRow struct {
+	CarID      string  `json:"car_id"`
+	Month      string  `json:"month"` // "2006-01"
+	Bookings   int     `json:"bookings"`
+	Gross      float64 `json:"gross"`
+	Refunds    float64 `json:"refunds"`
+	Commission float64 `json:"commission"`
+	Net        float64 `json:"net"`
+}
+
+type EarningsReport struct {
+	HostID         string        `json:"host_id"`
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"`
+	CommissionRate float64       `json:"commission_rate"`
+	Rows           []EarningsRow `json:"rows"` // ordered by car, then month
+	Total          EarningsRow   `json:"total"`
+}
+
+// HostReportService aggregates a host's cars, availability and bookings into
+// calendar, occupancy and earnings reports.
+type HostReportService struct {
+	carRepo        repositories.CarRepository
+	bookingRepo    repositories.BookingRepository
+	inventoryRepo  repositories.InventoryRepository
+	commissionRate float64
+}
+
+func NewHostReportService(cRepo repositories.CarRepository, bRepo repositories.BookingRepository, iRepo repositories.InventoryRepository) *HostReportService {
+	return &HostReportService{
+		carRepo:        cRepo,
+		bookingRepo:    bRepo,
+		inventoryRepo:  iRepo,
+		commissionRate: DefaultCommissionRate,
+	}
+}
+
+// SetCommissionRate replaces DefaultCommissionRate, e.g. 0.2 for 20%.
+func (s *HostReportService) SetCommissionRate(rate float64) {
+	s.commissionRate = rate
+}
+
+func checkPeriod(from, to time.Time) error {
+	if !from.Before(to) {
+		return &models.ValidationError{Field: "to", Message: "report period must end after it starts"}
+	}
+	return nil
+}
+
+func (s *HostReportService) hostCars(ctx context.Context, hostID string) ([]*models.Car, error) {
+	cars, err := s.carRepo.GetCarsByHost(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(cars, func(i, j int) bool { return cars[i].ID < cars[j].ID })
+	return cars, nil
+}
+
+// Calendar splits [from, to) for each of the host's cars into booked,
+// available and blocked runs. Booked wins over availability, so a booking
+// left outside a shrunk slot still shows.
+func (s *HostReportService) Calendar(ctx context.Context, hostID string, from, to time.Time) (*CalendarReport, error) {
+	if err := checkPeriod(from, to); err != nil {
+		return nil, err
+	}
+	cars, err := s.hostCars(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+	report := &CalendarReport{HostID: hostID, From: from, To: to, Cars: []CarCalendar{}}
+	for _, car := range cars {
+		entries, err := s.carCalendar(ctx, car.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		report.Cars = append(report.Cars, CarCalendar{CarID: car.ID, Entries: entries})
+	}
+	return report, nil
+}
+
+func (s *HostReportService) carCalendar(ctx context.Context, carID string, from, to time.Time) ([]CalendarEntry, error) {
+	var available []models.TimeWindow
+	slots, err := s.inventoryRepo.GetAvailability(ctx, carID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, slot := range slots {
+		available = append(available, models.TimeWindow{Start: slot.StartTime, End: slot.EndTime})
+	}
+	rules, err := s.inventoryRepo.GetRecurrenceRules(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		windows, err := rule.Occurrences(from, to)
+		if err != nil {
+			return nil, err
+		}
+		available = append(available, windows...)
+	}
+	bookings, err := s.bookingRepo.GetBookingsForCar(ctx, carID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var held []*models.Booking
+	for _, b := range bookings {
+		if b.Status.HoldsCar() {
+			held = append(held, b)
+		}
+	}
+
+	// Every edge inside the period starts a new elementary range whose state
+	// is constant throughout.
+	edges := []time.Time{from, to}
+	addEdge := func(t time.Time) {
+		if t.After(from) && t.Before(to) {
+			edges = append(edges, t)
+		}
+	}
+	for _, w := range available {
+		addEdge(w.Start)
+		addEdge(w.End)
+	}
+	for _, b := range held {
+		addEdge(b.StartTime)
+		addEdge(b.EndTime)
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].Before(edges[j]) })
+
+	var entries []CalendarEntry
+	for i := 0; i+1 < len(edges); i++ {
+		start, end := edges[i], edges[i+1]
+		if !start.Before(end) {
+			continue
+		}
+		e := CalendarEntry{Start: start, End: end, State: CalendarBlocked}
+		if b := bookingAt(held, start); b != nil {
+			e.State, e.BookingID, e.Status = CalendarBooked, b.ID, b.Status
+		} else if windowAt(available, start) {
+			e.State = CalendarAvailable
+		}
+		if n := len(entries); n > 0 && entries[n-1].State == e.State && entries[n-1].BookingID == e.BookingID {
+			entries[n-1].End = end
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func bookingAt(bookings []*models.Booking, t time.Time) *models.Booking {
+	for _, b := range bookings {
+		if !t.Before(b.StartTime) && t.Before(b.EndTime) {
+			return b
+		}
+	}
+	return nil
+}
+
+func windowAt(windows []models.TimeWindow, t time.Time) bool {
+	for _, w := range windows {
+		if !t.Before(w.Start) && t.Before(w.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// Occupancy reports, per car and for the whole fleet, how much of the
+// offered time in [from, to) was taken by paid bookings.
+func (s *HostReportService) Occupancy(ctx context.Context, hostID string, from, to time.Time) (*OccupancyReport, error) {
+	calendar, err := s.Calendar(ctx, hostID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	report := &OccupancyReport{HostID: hostID, From: from, To: to, Cars: []CarOccupancy{}}
+	for _, car := range calendar.Cars {
+		occ := CarOccupancy{CarID: car.CarID}
+		for _, e := range car.Entries {
+			hours := e.End.Sub(e.Start).Hours()
+			switch {
+			case e.State == CalendarBooked && e.Status != models.BookingStatusPending:
+				occ.BookedHours += hours
+				occ.OfferedHours += hours
+			case e.State != CalendarBlocked:
+				occ.OfferedHours += hours
+			}
+		}
+		occ.Rate = occupancyRate(occ.BookedHours, occ.OfferedHours)
+		report.Cars = append(report.Cars, occ)
+		report.Fleet.BookedHours += occ.BookedHours
+		report.Fleet.OfferedHours += occ.OfferedHours
+	}
+	report.Fleet.Rate = occupancyRate(report.Fleet.BookedHours, report.Fleet.OfferedHours)
+	return report, nil
+}
+
+func occupancyRate(booked, offered float64) float64 {
+	if offered == 0 {
+		return 0
+	}
+	return booked / offered
+}
+
+// paid reports whether money changed hands for the booking: it's been
+// confirmed, even if it was cancelled afterwards.
+func paid(b *models.Booking) bool {
+	switch b.Status {
+	case models.BookingStatusConfirmed, models.BookingStatusActive, models.BookingStatusCompleted:
+		return true
+	}
+	return !b.ConfirmedAt.IsZero()
+}
+
+// Earnings totals the host's paid bookings that start in [from, to), per car
+// and calendar month. Refunds are subtracted before commission is taken.
+func (s *HostReportService) Earnings(ctx context.Context, hostID string, from, to time.Time) (*EarningsReport, error) {
+	if err := checkPeriod(from, to); err != nil {
+		return nil, err
+	}
+	cars, err := s.hostCars(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+	report := &EarningsReport{HostID: hostID, From: from, To: to, CommissionRate: s.commissionRate, Rows: []EarningsRow{}}
+	for _, car := range cars {
+		bookings, err := s.bookingRepo.GetBookingsForCar(ctx, car.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		months := map[string]*EarningsRow{}
+		var order []string
+		for _, b := range bookings {
+			if b.StartTime.Before(from) || !paid(b) {
+				continue
+			}
+			month := b.StartTime.UTC().Format("2006-01")
+			row, ok := months[month]
+			if !ok {
+				row = &EarningsRow{CarID: car.ID, Month: month}
+				months[month] = row
+				order = append(order, month)
+			}
+			refunds, err := s.bookingRepo.GetRefundsForBooking(ctx, b.ID)
+			if err != nil {
+				return nil, err
+			}
+			row.Bookings++
+			row.Gross += b.TotalPrice
+			for _, r := range refunds {
+				row.Refunds += r.Amount
+			}
+		}
+		sort.Strings(order)
+		for _, month := range order {
+			row := months[month]
+			row.Gross = roundCents(row.Gross)
+			row.Refunds = roundCents(row.Refunds)
+			row.Commission = roundCents((row.Gross - row.Refunds) * s.commissionRate)
+			row.Net = roundCents(row.Gross - row.Refunds - row.Commission)
+			report.Rows = append(report.Rows, *row)
+
+			report.Total.Bookings += row.Bookings
+			report.Total.Gross += row.Gross
+			report.Total.Refunds += row.Refunds
+			report.Total.Commission += row.Commission
+			report.Total.Net += row.Net
+		}
+	}
+	report.Total.Gross = roundCents(report.Total.Gross)
+	report.Total.Refunds = roundCents(report.Total.Refunds)
+	report.Total.Commission = roundCents(report.Total.Commission)
+	report.Total.Net = roundCents(report.Total.Net)
+	return report, nil
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func writeCSV(w io.Writer, records [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(records); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// WriteCSV writes one row per calendar entry, with RFC 3339 times.
+func (r *CalendarReport) WriteCSV(w io.Writer) error {
+	records := [][]string{{"car_id", "start", "end", "state", "booking_id", "booking_status"}}
+	for _, car := range r.Cars {
+		for _, e := range car.Entries {
+			records = append(records, []string{car.CarID, e.Start.Format(time.RFC3339), e.End.Format(time.RFC3339), string(e.State), e.BookingID, string(e.Status)})
+		}
+	}
+	return writeCSV(w, records)
+}
+
+// WriteCSV writes one row per car followed by a TOTAL row for the fleet.
+func (r *OccupancyReport) WriteCSV(w io.Writer) error {
+	records := [][]string{{"car_id", "offered_hours", "booked_hours", "rate"}}
+	row := func(id string, o CarOccupancy) []string {
+		return []string{id, formatFloat(o.OfferedHours), formatFloat(o.BookedHours), strconv.FormatFloat(o.Rate, 'f', 4, 64)}
+	}
+	for _, car := range r.Cars {
+		records = append(records, row(car.CarID, car))
+	}
+	records = append(records, row("TOTAL", r.Fleet))
+	return writeCSV(w, records)
+}
+
+// WriteCSV writes one row per car and month followed by a TOTAL row.
+func (r *EarningsReport) WriteCSV(w io.Writer) error {
+	records := [][]string{{"car_id", "month", "bookings", "gross", "refunds", "commission", "net"}}
+	row := func(e EarningsRow) []string {
+		return []string{e.CarID, e.Month, strconv.Itoa(e.Bookings), formatFloat(e.Gross), formatFloat(e.Refunds), formatFloat(e.Commission), formatFloat(e.Net)}
+	}
+	for _, e := range r.Rows {
+		records = append(records, row(e))
+	}
+	total := r.Total
+	total.CarID = "TOTAL"
+	records = append(records, row(total))
+	return writeCSV(w, records)
+}
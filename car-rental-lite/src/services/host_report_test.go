@@ -0,0 +1,118 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newReportFixture books the fixture car for Jan 2-4 (paid), Jan 10-12
+// (unpaid hold) and Feb 2-3 (paid), books and then cancels Jan 20-21 with a
+// 50% refund, and blocks Jan 25.
+func newReportFixture(t *testing.T) (*bookingFixture, *HostReportService) {
+	t.Helper()
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	feb := func(d int) time.Time { return jan(31 + d) }
+	if _, err := f.inv.AddAvailability(ctx, f.car.ID, feb(1), feb(10)); err != nil {
+		t.Fatal(err)
+	}
+	book := func(start, end time.Time, confirm bool) *models.Booking {
+		b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, start, end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if confirm {
+			if b, err = f.bookings.ConfirmBooking(ctx, b.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return b
+	}
+	book(jan(2), jan(4), true)
+	book(jan(10), jan(12), false)
+	book(feb(2), feb(3), true)
+	cancelled := book(jan(20), jan(21), true)
+	if err := f.inv.BlockDates(ctx, f.car.ID, jan(25), jan(26), false); err != nil {
+		t.Fatal(err)
+	}
+	f.now = jan(18)
+	if _, err := f.bookings.CancelBooking(ctx, cancelled.ID, models.CancelledByRenter, "user1"); err != nil {
+		t.Fatal(err)
+	}
+	return f, NewHostReportService(f.repo, f.repo, f.repo)
+}
+
+func TestHostCalendarAndOccupancy(t *testing.T) {
+	ctx := context.Background()
+	_, reports := newReportFixture(t)
+
+	calendar, err := reports.Calendar(ctx, "host1", jan(1), jan(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calendar.Cars) != 1 {
+		t.Fatalf("Expected 1 car, got %d", len(calendar.Cars))
+	}
+	var got []string
+	for _, e := range calendar.Cars[0].Entries {
+		got = append(got, e.Start.Format("02")+"-"+e.End.Format("02")+" "+string(e.State)+" "+string(e.Status))
+	}
+	want := []string{
+		"01-02 AVAILABLE ", "02-04 BOOKED CONFIRMED", "04-10 AVAILABLE ", "10-12 BOOKED PENDING",
+		"12-25 AVAILABLE ", "25-26 BLOCKED ", "26-31 AVAILABLE ", "31-01 BLOCKED ",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected calendar\n%v\ngot\n%v", want, got)
+	}
+
+	occ, err := reports.Occupancy(ctx, "host1", jan(1), jan(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 29 offered days, of which the paid Jan 2-4 booking takes 2; the unpaid
+	// hold counts as offered.
+	if occ.Fleet.OfferedHours != 29*24 || occ.Fleet.BookedHours != 48 {
+		t.Errorf("Expected 696 offered and 48 booked hours, got %v and %v", occ.Fleet.OfferedHours, occ.Fleet.BookedHours)
+	}
+	if occ.Cars[0].Rate != 48.0/696 {
+		t.Errorf("Expected rate %v, got %v", 48.0/696, occ.Cars[0].Rate)
+	}
+
+	if _, err := reports.Occupancy(ctx, "host1", jan(5), jan(5)); err == nil {
+		t.Error("Expected an empty period to be rejected")
+	}
+}
+
+func TestHostEarnings(t *testing.T) {
+	ctx := context.Background()
+	f, reports := newReportFixture(t)
+
+	report, err := reports.Earnings(ctx, "host1", jan(1), jan(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// January: 200 + the cancelled 100 less its 50 refund; 15% commission.
+	var csv strings.Builder
+	if err := report.WriteCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	want := "car_id,month,bookings,gross,refunds,commission,net\n" +
+		f.car.ID + ",2026-01,2,300,50,37.5,212.5\n" +
+		f.car.ID + ",2026-02,1,100,0,15,85\n" +
+		"TOTAL,,3,400,50,52.5,297.5\n"
+	if csv.String() != want {
+		t.Errorf("Expected CSV\n%s\ngot\n%s", want, csv.String())
+	}
+
+	reports.SetCommissionRate(0)
+	feb, err := reports.Earnings(ctx, "host1", jan(32), jan(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feb.Rows) != 1 || feb.Total.Net != 100 {
+		t.Errorf("Expected only February at 100 net without commission, got %+v", feb.Total)
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"car-rental-lite/src/idgen"
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"errors"
+	"time"
+)
+
+// InspectionService records the condition reports taken at pickup and
+// dropoff, in the same step that moves the booking along.
+type InspectionService struct {
+	inspectionRepo repositories.InspectionRepository
+	bookingRepo    repositories.BookingRepository
+	carRepo        repositories.CarRepository
+	ids            idgen.IDGenerator
+	now            func() time.Time
+}
+
+func NewInspectionService(insRepo repositories.InspectionRepository, bRepo repositories.BookingRepository, cRepo repositories.CarRepository) *InspectionService {
+	return &InspectionService{
+		inspectionRepo: insRepo,
+		bookingRepo:    bRepo,
+		carRepo:        cRepo,
+		ids:            idgen.NewULIDGenerator(),
+		now:            time.Now,
+	}
+}
+
+func (s *InspectionService) SetIDGenerator(ids idgen.IDGenerator) {
+	s.ids = ids
+}
+
+// SetClock replaces time.Now, e.g. with a fake clock in tests.
+func (s *InspectionService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// DropoffCharges prices a dropoff report; the line items are added to the
+// booking's total as it completes.
+type DropoffCharges func(car *models.Car, booking *models.Booking, dropoff *models.InspectionReport) []models.PriceLineItem
+
+// RecordInspection validates a PICKUP or DROPOFF report and stores it
+// together with the booking's move to ACTIVE or COMPLETED, so a refused
+// transition leaves no report behind. A dropoff needs an earlier pickup,
+// can't show a lower odometer reading, and gets its fuel and mileage deltas
+// computed from the pickup; charges, if set, prices it.
+func (s *InspectionService) RecordInspection(ctx context.Context, report *models.InspectionReport, charges DropoffCharges) (*models.InspectionReport, error) {
+	// 1. Validate readings
+	if report.FuelLevel < 0 || report.FuelLevel > 100 {
+		return nil, &models.ValidationError{Field: "fuel_level", Message: "fuel level must be between 0 and 100"}
+	}
+	if report.Odometer < 0 {
+		return nil, &models.ValidationError{Field: "odometer", Message: "odometer must not be negative"}
+	}
+
+	// 2. Check the booking and who is inspecting
+	booking, err := s.bookingRepo.GetBooking(ctx, report.BookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status != models.BookingStatusConfirmed && booking.Status != models.BookingStatusActive {
+		return nil, &models.ConflictError{Err: errors.New("inspections can only be recorded for confirmed or active bookings"), BookingID: booking.ID}
+	}
+	car, err := s.carRepo.GetCar(ctx, booking.CarID)
+	if err != nil {
+		return nil, err
+	}
+	if report.InspectorID != booking.UserID && report.InspectorID != car.HostID {
+		return nil, &models.ValidationError{Field: "inspector_id", Message: "inspector must be the renter or the car's host"}
+	}
+
+	// 3. Order against the existing reports
+	existing, err := s.inspectionRepo.GetInspectionsForBooking(ctx, booking.ID)
+	if err != nil {
+		return nil, err
+	}
+	pickup := findReport(existing, models.InspectionTypePickup)
+
+	report.CreatedAt = s.now()
+	switch report.Type {
+	case models.InspectionTypePickup:
+	case models.InspectionTypeDropoff:
+		if pickup == nil {
+			return nil, &models.ConflictError{Err: errors.New("dropoff requires a pickup inspection"), BookingID: booking.ID}
+		}
+		if !report.CreatedAt.After(pickup.CreatedAt) {
+			return nil, &models.ValidationError{Field: "created_at", Message: "dropoff must be recorded after pickup"}
+		}
+		if report.Odometer < pickup.Odometer {
+			return nil, &models.ValidationError{Field: "odometer", Message: "odometer reading is lower than at pickup"}
+		}
+		report.FuelDelta = report.FuelLevel - pickup.FuelLevel
+		report.Mileage = report.Odometer - pickup.Odometer
+	default:
+		return nil, &models.ValidationError{Field: "type", Message: "unknown inspection type"}
+	}
+
+	if findReport(existing, report.Type) != nil {
+		return nil, &models.ConflictError{Err: repositories.ErrDuplicateInspection, BookingID: booking.ID}
+	}
+	report.ID = s.ids.NewID()
+
+	// 4. Store the report with the status change
+	var adjustments []models.PriceLineItem
+	if report.Type == models.InspectionTypeDropoff && charges != nil {
+		adjustments = charges(car, booking, report)
+	}
+	if err := s.inspectionRepo.RecordTripInspection(ctx, report, adjustments); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// GetInspections returns the booking's pickup and dropoff reports; either may
+// be nil if not yet recorded.
+func (s *InspectionService) GetInspections(ctx context.Context, bookingID string) (pickup, dropoff *models.InspectionReport, err error) {
+	reports, err := s.inspectionRepo.GetInspectionsForBooking(ctx, bookingID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return findReport(reports, models.InspectionTypePickup), findReport(reports, models.InspectionTypeDropoff), nil
+}
+
+func findReport(reports []*models.InspectionReport, t models.InspectionType) *models.InspectionReport {
+	for _, r := range reports {
+		if r.Type == t {
+			return r
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInspectionsAndClaims(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	now := jan(2)
+	inspections := NewInspectionService(f.repo, f.repo, f.repo)
+	inspections.SetClock(func() time.Time { return now })
+	claims := NewClaimService(f.repo, f.repo, f.repo)
+	claims.SetClock(func() time.Time { return now })
+
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(2), jan(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inspections.RecordInspection(ctx, &models.InspectionReport{BookingID: b.ID, InspectorID: "user1", Type: models.InspectionTypePickup}, nil); err == nil {
+		t.Error("Expected inspections on an unpaid hold to be refused")
+	}
+	if _, err := f.bookings.ConfirmBooking(ctx, b.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	claim := &models.DamageClaim{BookingID: b.ID, ClaimantID: "host1", Description: "Scratched bumper", EvidenceImageURLs: []string{"bumper.jpg"}, EstimatedCost: 300}
+	if _, err := claims.FileClaim(ctx, claim); err == nil {
+		t.Error("Expected a claim without inspections to be refused")
+	}
+
+	if _, err := inspections.RecordInspection(ctx, &models.InspectionReport{BookingID: b.ID, InspectorID: "user1", Type: models.InspectionTypeDropoff, FuelLevel: 50, Odometer: 1000}, nil); err == nil {
+		t.Error("Expected a dropoff before pickup to be refused")
+	}
+	if _, err := inspections.RecordInspection(ctx, &models.InspectionReport{BookingID: b.ID, InspectorID: "stranger", Type: models.InspectionTypePickup}, nil); err == nil {
+		t.Error("Expected an unrelated inspector to be refused")
+	}
+	if _, err := inspections.RecordInspection(ctx, &models.InspectionReport{BookingID: b.ID, InspectorID: "host1", Type: models.InspectionTypePickup, FuelLevel: 90, Odometer: 12000}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := f.repo.GetBooking(ctx, b.ID); active.Status != models.BookingStatusActive || !active.PickedUpAt.Equal(now) {
+		t.Errorf("Expected the pickup to activate the booking at %v, got %s at %v", now, active.Status, active.PickedUpAt)
+	}
+	if _, err := inspections.RecordInspection(ctx, &models.InspectionReport{BookingID: b.ID, InspectorID: "host1", Type: models.InspectionTypePickup, FuelLevel: 90, Odometer: 12000}, nil); !errors.Is(err, repositories.ErrDuplicateInspection) {
+		t.Errorf("Expected ErrDuplicateInspection, got %v", err)
+	}
+
+	now = jan(4)
+	// The charges see the dropoff with its deltas filled in.
+	charges := func(car *models.Car, booking *models.Booking, dropoff *models.InspectionReport) []models.PriceLineItem {
+		return []models.PriceLineItem{{Component: models.PriceComponentFuelRefill, Amount: -dropoff.FuelDelta}}
+	}
+	if _, err := inspections.RecordInspection(ctx, &models.InspectionReport{BookingID: b.ID, InspectorID: "host1", Type: models.InspectionTypeDropoff, FuelLevel: 40, Odometer: 11999}, charges); err == nil {
+		t.Error("Expected a lower odometer reading to be refused")
+	}
+	if _, err := inspections.RecordInspection(ctx, &models.InspectionReport{BookingID: b.ID, InspectorID: "host1", Type: models.InspectionTypeDropoff, FuelLevel: 40, Odometer: 12350}, charges); err != nil {
+		t.Fatal(err)
+	}
+	_, dropoff, err := inspections.GetInspections(ctx, b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropoff.FuelDelta != -50 || dropoff.Mileage != 350 {
+		t.Errorf("Expected fuel delta -50 and mileage 350, got %.0f and %d", dropoff.FuelDelta, dropoff.Mileage)
+	}
+	if done, _ := f.repo.GetBooking(ctx, b.ID); done.Status != models.BookingStatusCompleted || done.TotalPrice != 250 || !done.ReturnedAt.Equal(now) {
+		t.Errorf("Expected COMPLETED at 250 returned at %v, got %s at %.2f returned at %v", now, done.Status, done.TotalPrice, done.ReturnedAt)
+	}
+
+	if _, err := claims.FileClaim(ctx, &models.DamageClaim{BookingID: b.ID, ClaimantID: "user1", Description: "x", EvidenceImageURLs: []string{"x.jpg"}, EstimatedCost: 1}); err == nil {
+		t.Error("Expected a renter-filed claim to be refused")
+	}
+	claim, err = claims.FileClaim(ctx, claim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claim.Status != models.ClaimStatusSubmitted {
+		t.Fatalf("Expected SUBMITTED, got %s", claim.Status)
+	}
+	if _, err := claims.Approve(ctx, claim.ID); !errors.Is(err, repositories.ErrInvalidTransition) {
+		t.Errorf("Expected approving before review to fail, got %v", err)
+	}
+	if _, err := claims.StartReview(ctx, claim.ID); err != nil {
+		t.Fatal(err)
+	}
+	if claim, err = claims.Approve(ctx, claim.ID); err != nil || claim.Status != models.ClaimStatusApproved {
+		t.Fatalf("Expected APPROVED, got %v, %v", claim, err)
+	}
+	if _, err := claims.Reject(ctx, claim.ID); !errors.Is(err, repositories.ErrInvalidTransition) {
+		t.Errorf("Expected an approved claim to be final, got %v", err)
+	}
+}
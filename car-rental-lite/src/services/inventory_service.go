@@ -1,6 +1,7 @@
 package services
 
 import (
+	"car-rental-lite/src/idgen"
 	"car-rental-lite/src/models"
 	"car-rental-lite/src/repositories"
 	"context"
@@ -12,67 +13,301 @@ type InventoryService struct {
 	carRepo       repositories.CarRepository
 	inventoryRepo repositories.InventoryRepository
 	bookingRepo   repositories.BookingRepository
+	userRepo      repositories.UserRepository
+	ids           idgen.IDGenerator
+	events        EventPublisher
+	now           func() time.Time
 }
 
-func NewInventoryService(carRepo repositories.CarRepository, invRepo repositories.InventoryRepository, bookRepo repositories.BookingRepository) *InventoryService {
+func NewInventoryService(carRepo repositories.CarRepository, invRepo repositories.InventoryRepository, bookRepo repositories.BookingRepository, userRepo repositories.UserRepository) *InventoryService {
 	return &InventoryService{
 		carRepo:       carRepo,
 		inventoryRepo: invRepo,
 		bookingRepo:   bookRepo,
+		userRepo:      userRepo,
+		ids:           idgen.NewULIDGenerator(),
+		events:        nopPublisher{},
+		now:           time.Now,
 	}
 }
 
+// SetIDGenerator replaces the default ULID generator, e.g. with a
+// deterministic sequence in tests.
+func (s *InventoryService) SetIDGenerator(ids idgen.IDGenerator) {
+	s.ids = ids
+}
+
+// SetClock replaces time.Now, e.g. with a fake clock in tests.
+func (s *InventoryService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// SetEventPublisher sets where the service's domain events go. By default
+// they're discarded.
+func (s *InventoryService) SetEventPublisher(events EventPublisher) {
+	s.events = events
+}
+
 func (s *InventoryService) RegisterCar(ctx context.Context, car *models.Car) error {
 	if car.HostID == "" {
-		return errors.New("host ID is required")
+		return &models.ValidationError{Field: "host_id", Message: "host ID is required"}
+	}
+	if _, err := car.Location.TimeLocation(); err != nil {
+		return &models.ValidationError{Field: "location.time_zone", Message: "unknown time zone"}
+	}
+	if car.ID == "" {
+		car.ID = s.ids.NewID()
 	}
-	// Basic validation could go here
 	return s.carRepo.CreateCar(ctx, car)
 }
 
-func (s *InventoryService) AddAvailability(ctx context.Context, carID string, start, end time.Time) error {
-	if start.After(end) {
-		return errors.New("start time must be before end time")
+func (s *InventoryService) AddAvailability(ctx context.Context, carID string, start, end time.Time) (*models.AvailabilitySlot, error) {
+	if !start.Before(end) {
+		return nil, &models.ValidationError{Field: "end_time", Message: "start time must be before end time"}
 	}
 
 	// Check if car exists
 	_, err := s.carRepo.GetCar(ctx, carID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	slot := &models.AvailabilitySlot{
-		// ID would normally be generated here or in repo
+		ID:        s.ids.NewID(),
 		CarID:     carID,
 		StartTime: start,
 		EndTime:   end,
 	}
-	return s.inventoryRepo.AddAvailability(ctx, slot)
+	if err := s.inventoryRepo.AddAvailability(ctx, slot); err != nil {
+		return nil, err
+	}
+	s.availabilityChanged(ctx, models.AvailabilityChanged{Change: models.AvailabilityAdded, CarID: carID, SlotID: slot.ID, StartTime: start, EndTime: end})
+	return slot, nil
+}
+
+// AddRecurringAvailability registers a recurring availability rule. Rules are
+// expanded lazily by availability checks, so an open-ended rule costs nothing
+// until someone searches or books. A rule without a time zone takes the
+// car's.
+func (s *InventoryService) AddRecurringAvailability(ctx context.Context, rule *models.RecurrenceRule) (*models.RecurrenceRule, error) {
+	car, err := s.carRepo.GetCar(ctx, rule.CarID)
+	if err != nil {
+		return nil, err
+	}
+	if rule.TimeZone == "" {
+		rule.TimeZone = car.Location.TimeZone
+	}
+	if err := validateRecurrenceRule(rule); err != nil {
+		return nil, err
+	}
+
+	if rule.ID == "" {
+		rule.ID = s.ids.NewID()
+	}
+	rule.CreatedAt = s.now()
+	if err := s.inventoryRepo.AddRecurrenceRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	s.ruleChanged(ctx, models.AvailabilityRuleAdded, rule)
+	return rule, nil
+}
+
+// RemoveRecurringAvailability deletes a rule. Unless force is set, it's
+// refused when an open booking relies on the rule.
+func (s *InventoryService) RemoveRecurringAvailability(ctx context.Context, ruleID string, force bool) error {
+	rule, err := s.inventoryRepo.GetRecurrenceRule(ctx, ruleID)
+	if err != nil {
+		return err
+	}
+	if err := s.inventoryRepo.RemoveRecurrenceRule(ctx, ruleID, force); err != nil {
+		return err
+	}
+	s.ruleChanged(ctx, models.AvailabilityRuleRemoved, rule)
+	return nil
+}
+
+// ruleChanged publishes the change over the range the rule's occurrences
+// fall in, which runs from StartDate to Until in the rule's zone rather
+// than UTC.
+func (s *InventoryService) ruleChanged(ctx context.Context, change models.AvailabilityChange, rule *models.RecurrenceRule) {
+	span, _ := rule.Span() // the zone was validated when the rule was added
+	s.availabilityChanged(ctx, models.AvailabilityChanged{Change: change, CarID: rule.CarID, RuleID: rule.ID, StartTime: span.Start, EndTime: span.End})
+}
+
+func (s *InventoryService) availabilityChanged(ctx context.Context, ev models.AvailabilityChanged) {
+	ev.OccurredAt = s.now()
+	publish(ctx, s.events, ev)
+}
+
+func validateRecurrenceRule(rule *models.RecurrenceRule) error {
+	switch rule.Frequency {
+	case models.FrequencyDaily:
+		if len(rule.ByWeekday) > 0 {
+			return &models.ValidationError{Field: "by_weekday", Message: "weekdays can only be set on weekly rules"}
+		}
+	case models.FrequencyWeekly:
+	default:
+		return &models.ValidationError{Field: "frequency", Message: "unknown recurrence frequency"}
+	}
+	if rule.Interval < 0 {
+		return &models.ValidationError{Field: "interval", Message: "recurrence interval must not be negative"}
+	}
+	if rule.StartOfDay < 0 || rule.EndOfDay > 24*time.Hour || rule.StartOfDay >= rule.EndOfDay {
+		return &models.ValidationError{Field: "end_of_day", Message: "daily window must satisfy 00:00 <= start < end <= 24:00"}
+	}
+	if rule.StartDate.IsZero() {
+		return &models.ValidationError{Field: "start_date", Message: "recurrence start date is required"}
+	}
+	if !rule.Until.IsZero() && rule.Until.Before(rule.StartDate) {
+		return &models.ValidationError{Field: "until", Message: "recurrence end date must not be before its start date"}
+	}
+	if _, err := rule.Location(); err != nil {
+		return &models.ValidationError{Field: "time_zone", Message: "unknown time zone"}
+	}
+	return nil
+}
+
+// RemoveAvailability deletes a slot. Unless force is set, it's refused when
+// an open booking relies on the slot.
+func (s *InventoryService) RemoveAvailability(ctx context.Context, slotID string, force bool) error {
+	slot, err := s.inventoryRepo.GetAvailabilitySlot(ctx, slotID)
+	if err != nil {
+		return err
+	}
+	if err := s.inventoryRepo.ReplaceAvailability(ctx, slot.CarID, []string{slot.ID}, nil, force); err != nil {
+		return err
+	}
+	s.availabilityChanged(ctx, models.AvailabilityChanged{Change: models.AvailabilityRemoved, CarID: slot.CarID, SlotID: slot.ID, StartTime: slot.StartTime, EndTime: slot.EndTime})
+	return nil
+}
+
+// UpdateAvailability moves a slot to [start, end), keeping its ID. Shrinking
+// is refused when it would strand an open booking, unless force is set.
+func (s *InventoryService) UpdateAvailability(ctx context.Context, slotID string, start, end time.Time, force bool) error {
+	if !start.Before(end) {
+		return &models.ValidationError{Field: "end_time", Message: "start time must be before end time"}
+	}
+	slot, err := s.inventoryRepo.GetAvailabilitySlot(ctx, slotID)
+	if err != nil {
+		return err
+	}
+	updated := &models.AvailabilitySlot{
+		ID:        slot.ID,
+		CarID:     slot.CarID,
+		StartTime: start,
+		EndTime:   end,
+	}
+	if err := s.inventoryRepo.ReplaceAvailability(ctx, slot.CarID, []string{slot.ID}, []*models.AvailabilitySlot{updated}, force); err != nil {
+		return err
+	}
+	// The affected range covers both the old and the new window.
+	ev := models.AvailabilityChanged{Change: models.AvailabilityUpdated, CarID: slot.CarID, SlotID: slot.ID, StartTime: start, EndTime: end}
+	if slot.StartTime.Before(ev.StartTime) {
+		ev.StartTime = slot.StartTime
+	}
+	if slot.EndTime.After(ev.EndTime) {
+		ev.EndTime = slot.EndTime
+	}
+	s.availabilityChanged(ctx, ev)
+	return nil
+}
+
+// BlockDates carves [start, end) out of the car's availability, trimming or
+// splitting every slot it overlaps. Unless force is set it's refused when an
+// open booking falls inside the blocked range.
+func (s *InventoryService) BlockDates(ctx context.Context, carID string, start, end time.Time, force bool) error {
+	if !start.Before(end) {
+		return &models.ValidationError{Field: "end_time", Message: "start time must be before end time"}
+	}
+	slots, err := s.inventoryRepo.GetAvailability(ctx, carID, start, end)
+	if err != nil {
+		return err
+	}
+
+	var removeIDs []string
+	var add []*models.AvailabilitySlot
+	for _, slot := range slots {
+		removeIDs = append(removeIDs, slot.ID)
+		if slot.StartTime.Before(start) {
+			add = append(add, &models.AvailabilitySlot{ID: s.ids.NewID(), CarID: carID, StartTime: slot.StartTime, EndTime: start})
+		}
+		if slot.EndTime.After(end) {
+			add = append(add, &models.AvailabilitySlot{ID: s.ids.NewID(), CarID: carID, StartTime: end, EndTime: slot.EndTime})
+		}
+	}
+	if len(removeIDs) == 0 {
+		return nil
+	}
+	if err := s.inventoryRepo.ReplaceAvailability(ctx, carID, removeIDs, add, force); err != nil {
+		return err
+	}
+	s.availabilityChanged(ctx, models.AvailabilityChanged{Change: models.AvailabilityBlocked, CarID: carID, StartTime: start, EndTime: end})
+	return nil
 }
 
-func (s *InventoryService) Search(ctx context.Context, location models.Location, radiusKm float64, start, end time.Time) ([]*models.Car, error) {
+// Search returns one page of cars matching q that are bookable for the whole
+// window, in the requested sort order.
+func (s *InventoryService) Search(ctx context.Context, q models.SearchQuery) (*models.SearchPage, error) {
+	if err := validateSearchQuery(&q); err != nil {
+		return nil, err
+	}
+
 	// 1. Find cars in location
-	cars, err := s.carRepo.SearchCars(ctx, location, radiusKm)
+	nearby, err := s.carRepo.SearchCars(ctx, q.Location, q.RadiusKm)
 	if err != nil {
 		return nil, err
 	}
 
-	// 2. Filter by availability
-	// This is a naive N+1 implementation. In a real system, the DB query would handle this join.
-	availableCars := make([]*models.Car, 0)
-	for _, car := range cars {
-		hasSlot, err := s.inventoryRepo.HasAvailabilitySlot(ctx, car.ID, start, end)
-		if err != nil || !hasSlot {
-			continue
+	// 2. Apply the cheap attribute filters before touching availability
+	matched := make([]*models.CarSearchResult, 0, len(nearby))
+	carIDs := make([]string, 0, len(nearby))
+	for _, r := range nearby {
+		if matchesQuery(r.Car, &q) {
+			matched = append(matched, r)
+			carIDs = append(carIDs, r.Car.ID)
 		}
+	}
 
-		hasOverlap, err := s.bookingRepo.HasOverlappingBooking(ctx, car.ID, start, end)
-		if err != nil || hasOverlap {
-			continue
+	// 3. Filter by availability in one batch rather than a lookup per car
+	available, err := s.inventoryRepo.FilterAvailable(ctx, carIDs, q.StartTime, q.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*models.CarSearchResult, 0, len(available))
+	for _, r := range matched {
+		if available[r.Car.ID] {
+			results = append(results, r)
 		}
+	}
 
-		availableCars = append(availableCars, car)
+	// 4. Look up the hosts' ratings if they decide the order
+	var hostRatings map[string]models.Rating
+	if q.SortBy == models.SearchSortHostRating {
+		if hostRatings, err = s.hostRatings(ctx, results); err != nil {
+			return nil, err
+		}
 	}
+	return paginate(results, &q, hostRatings)
+}
 
-	return availableCars, nil
+// hostRatings returns the rating of each host with a car in results, one
+// lookup per host. A host that can't be found counts as unrated.
+func (s *InventoryService) hostRatings(ctx context.Context, results []*models.CarSearchResult) (map[string]models.Rating, error) {
+	ratings := make(map[string]models.Rating)
+	for _, r := range results {
+		if _, seen := ratings[r.Car.HostID]; seen {
+			continue
+		}
+		host, err := s.userRepo.GetHost(ctx, r.Car.HostID)
+		if errors.Is(err, models.ErrNotFound) {
+			ratings[r.Car.HostID] = models.Rating{}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ratings[r.Car.HostID] = host.Rating
+	}
+	return ratings, nil
 }
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSearchFiltersSortsAndPaginates(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewInMemoryRepo()
+	inv := NewInventoryService(repo, repo, repo, repo)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 10; i++ {
+		car := &models.Car{
+			ID:          fmt.Sprintf("car%d", i),
+			HostID:      "host1",
+			Make:        "Toyota",
+			Year:        2015 + i,
+			Type:        models.CarTypeSedan,
+			PricePerDay: float64(100 - i*5),
+			Location:    models.Location{Latitude: 37.77 + float64(i)*0.01, Longitude: -122.42},
+			IsActive:    true,
+		}
+		if i%2 == 0 {
+			car.Amenities = []models.Amenity{models.AmenityGPS}
+		}
+		if err := inv.RegisterCar(ctx, car); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := inv.AddAvailability(ctx, car.ID, start, end); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	q := models.SearchQuery{
+		Location:  models.Location{Latitude: 37.77, Longitude: -122.42},
+		RadiusKm:  50,
+		StartTime: start.AddDate(0, 0, 2),
+		EndTime:   start.AddDate(0, 0, 4),
+		MaxPrice:  90,
+		Amenities: []models.Amenity{models.AmenityGPS},
+		SortBy:    models.SearchSortPrice,
+		Limit:     2,
+	}
+
+	var got []string
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("Pagination did not terminate")
+		}
+		page, err := inv.Search(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range page.Results {
+			got = append(got, r.Car.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+
+	// Even-numbered cars have GPS; car0 (100/day) is over the max price.
+	if fmt.Sprint(got) != "[car8 car6 car4 car2]" {
+		t.Errorf("Expected [car8 car6 car4 car2], got %v", got)
+	}
+
+	q.Cursor = ""
+	q.SortBy = models.SearchSortNewest
+	q.Limit = 1
+	page, err := inv.Search(ctx, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Results) != 1 || page.Results[0].Car.ID != "car8" {
+		t.Errorf("Expected newest car8 first, got %v", page.Results)
+	}
+}
+
+func TestAvailabilityEditsProtectConfirmedBookings(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewInMemoryRepo()
+	inv := NewInventoryService(repo, repo, repo, repo)
+	bookings := NewBookingService(repo, repo, repo, repo)
+	if err := repo.CreateUser(ctx, licensedRenter("user1")); err != nil {
+		t.Fatal(err)
+	}
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	car := &models.Car{HostID: "host1", PricePerDay: 50, IsActive: true}
+	if err := inv.RegisterCar(ctx, car); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inv.AddAvailability(ctx, car.ID, day(1), day(1)); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected an empty slot to be refused, got %v", err)
+	}
+	slot, err := inv.AddAvailability(ctx, car.ID, day(1), day(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bookings.CreateBooking(ctx, "user1", car.ID, day(5), day(8)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := inv.RemoveAvailability(ctx, slot.ID, false); !errors.Is(err, repositories.ErrStrandedBooking) {
+		t.Fatalf("Expected ErrStrandedBooking when removing a booked slot, got %v", err)
+	}
+	if err := inv.UpdateAvailability(ctx, slot.ID, day(1), day(6), false); !errors.Is(err, repositories.ErrStrandedBooking) {
+		t.Fatalf("Expected ErrStrandedBooking when shrinking over a booking, got %v", err)
+	}
+	if err := inv.BlockDates(ctx, car.ID, day(7), day(9), false); !errors.Is(err, repositories.ErrStrandedBooking) {
+		t.Fatalf("Expected ErrStrandedBooking when blocking booked dates, got %v", err)
+	}
+
+	// Blocking around the booking splits the slot into three pieces.
+	if err := inv.BlockDates(ctx, car.ID, day(10), day(12), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := inv.BlockDates(ctx, car.ID, day(2), day(4), false); err != nil {
+		t.Fatal(err)
+	}
+	slots, err := repo.GetAvailability(ctx, car.ID, day(1), day(31))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range slots {
+		got = append(got, fmt.Sprintf("%d-%d", s.StartTime.Day(), s.EndTime.Day()))
+	}
+	if fmt.Sprint(got) != "[1-2 4-10 12-20]" {
+		t.Errorf("Expected slots [1-2 4-10 12-20], got %v", got)
+	}
+	if ok, _ := repo.HasAvailabilitySlot(ctx, car.ID, day(11), day(13)); ok {
+		t.Error("Expected blocked dates to be unavailable")
+	}
+
+	// The host can still override.
+	if err := inv.UpdateAvailability(ctx, slots[1].ID, day(4), day(6), true); err != nil {
+		t.Fatalf("Expected forced update to succeed, got %v", err)
+	}
+}
+
+func TestRecurringAvailability(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewInMemoryRepo()
+	inv := NewInventoryService(repo, repo, repo, repo)
+	bookings := NewBookingService(repo, repo, repo, repo)
+	if err := repo.CreateUser(ctx, licensedRenter("user1")); err != nil {
+		t.Fatal(err)
+	}
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(d, h int) time.Time { return time.Date(2026, 1, d, h, 0, 0, 0, la) }
+	inv.SetClock(func() time.Time { return at(1, 9) })
+	bus := NewEventBus()
+	var changes []models.AvailabilityChanged
+	bus.Subscribe(func(ctx context.Context, ev models.Event) error {
+		changes = append(changes, ev.(models.AvailabilityChanged))
+		return nil
+	}, models.EventAvailabilityChanged)
+	inv.SetEventPublisher(bus)
+
+	weekdays := &models.Car{HostID: "host1", PricePerDay: 50, IsActive: true}
+	weekends := &models.Car{HostID: "host1", PricePerDay: 50, IsActive: true}
+	for _, c := range []*models.Car{weekdays, weekends} {
+		if err := inv.RegisterCar(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every weekday 08:00-20:00 until March, except Wed Jan 14.
+	weekdayRule, err := inv.AddRecurringAvailability(ctx, &models.RecurrenceRule{
+		CarID:      weekdays.ID,
+		Frequency:  models.FrequencyWeekly,
+		ByWeekday:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		StartOfDay: 8 * time.Hour,
+		EndOfDay:   20 * time.Hour,
+		StartDate:  at(1, 0),
+		Until:      time.Date(2026, 3, 1, 0, 0, 0, 0, la),
+		Exceptions: []time.Time{at(14, 0)},
+		TimeZone:   "America/Los_Angeles",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !weekdayRule.CreatedAt.Equal(at(1, 9)) {
+		t.Errorf("Expected the rule created at %v, got %v", at(1, 9), weekdayRule.CreatedAt)
+	}
+	if _, err := bus.Dispatch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].RuleID != weekdayRule.ID || !changes[0].OccurredAt.Equal(at(1, 9)) {
+		t.Errorf("Expected the rule's change published at %v, got %+v", at(1, 9), changes)
+	}
+	// Weekends only, all day.
+	if _, err := inv.AddRecurringAvailability(ctx, &models.RecurrenceRule{
+		CarID:     weekends.ID,
+		Frequency: models.FrequencyWeekly,
+		ByWeekday: []time.Weekday{time.Saturday, time.Sunday},
+		EndOfDay:  24 * time.Hour,
+		StartDate: at(1, 0),
+		TimeZone:  "America/Los_Angeles",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		carID      string
+		start, end time.Time
+		want       bool
+	}{
+		{"weekday within hours", weekdays.ID, at(7, 9), at(7, 17), true},
+		{"weekday before opening", weekdays.ID, at(7, 7), at(7, 17), false},
+		{"weekday overnight gap", weekdays.ID, at(7, 10), at(8, 10), false},
+		{"saturday", weekdays.ID, at(10, 9), at(10, 17), false},
+		{"exception date", weekdays.ID, at(14, 9), at(14, 17), false},
+		{"after until", weekdays.ID, time.Date(2026, 3, 2, 9, 0, 0, 0, la), time.Date(2026, 3, 2, 17, 0, 0, 0, la), false},
+		{"whole weekend", weekends.ID, at(10, 10), at(11, 18), true},
+		{"weekend into monday", weekends.ID, at(11, 10), at(12, 1), false},
+	}
+	for _, tt := range tests {
+		got, err := repo.HasAvailabilitySlot(ctx, tt.carID, tt.start, tt.end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	// Rules combine with explicit slots: the Tuesday night gap is filled.
+	if _, err := inv.AddAvailability(ctx, weekdays.ID, at(6, 20), at(7, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bookings.CreateBooking(ctx, "user1", weekdays.ID, at(6, 10), at(7, 12)); err != nil {
+		t.Fatalf("Expected rule plus slot to cover the booking, got %v", err)
+	}
+	if err := inv.RemoveRecurringAvailability(ctx, weekdayRule.ID, false); !errors.Is(err, repositories.ErrStrandedBooking) {
+		t.Errorf("Expected ErrStrandedBooking when removing a rule a booking relies on, got %v", err)
+	}
+
+	page, err := inv.Search(ctx, models.SearchQuery{StartTime: at(17, 9), EndTime: at(18, 9)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Results) != 1 || page.Results[0].Car.ID != weekends.ID {
+		t.Errorf("Expected search to expand rules and return only the weekend car, got %v", page.Results)
+	}
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PricingEngine quotes the price of renting a car for a window.
+type PricingEngine interface {
+	Quote(ctx context.Context, car *models.Car, start, end time.Time) (*PriceQuote, error)
+}
+
+// PricingRule is one composable step of a RuleBasedPricingEngine. Rules run
+// in order and see the line items added by earlier rules.
+type PricingRule interface {
+	Apply(ctx context.Context, car *models.Car, q *PriceQuote) error
+}
+
+// RentalDay is one day of a rental, starting at the pickup time of day. Days
+// follow the car's local calendar, so a day spanning a DST change is 23 or
+// 25 hours long, and Start is in the car's time zone. The last day is
+// Partial when the rental doesn't end on a whole day.
+type RentalDay struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Partial bool      `json:"partial"`
+	// Rate is the base charge for the day; seasonal pricing may replace it
+	// and surcharges are computed from it.
+	Rate float64 `json:"rate"`
+}
+
+func (d *RentalDay) Hours() float64 {
+	return d.End.Sub(d.Start).Hours()
+}
+
+type PriceQuote struct {
+	CarID string                 `json:"car_id"`
+	Start time.Time              `json:"start"`
+	End   time.Time              `json:"end"`
+	Days  []*RentalDay           `json:"days"`
+	Items []models.PriceLineItem `json:"items"`
+	Total float64                `json:"total"`
+}
+
+// Subtotal sums the line items added so far.
+func (q *PriceQuote) Subtotal() float64 {
+	var sum float64
+	for _, item := range q.Items {
+		sum += item.Amount
+	}
+	return sum
+}
+
+// FullDays counts the whole days in the rental.
+func (q *PriceQuote) FullDays() int {
+	n := 0
+	for _, d := range q.Days {
+		if !d.Partial {
+			n++
+		}
+	}
+	return n
+}
+
+func (q *PriceQuote) add(component models.PriceComponent, description string, amount float64) {
+	amount = roundCents(amount)
+	if amount == 0 {
+		return
+	}
+	q.Items = append(q.Items, models.PriceLineItem{Component: component, Description: description, Amount: amount})
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+type RuleBasedPricingEngine struct {
+	rules []PricingRule
+}
+
+// NewPricingEngine builds an engine from rules applied in order. The usual
+// order is base rate, seasonal prices, surcharges, discounts, fees, taxes.
+func NewPricingEngine(rules ...PricingRule) *RuleBasedPricingEngine {
+	return &RuleBasedPricingEngine{rules: rules}
+}
+
+// NewDefaultPricingEngine charges the car's base rates only.
+func NewDefaultPricingEngine() *RuleBasedPricingEngine {
+	return NewPricingEngine(&BaseRateRule{})
+}
+
+func (e *RuleBasedPricingEngine) Quote(ctx context.Context, car *models.Car, start, end time.Time) (*PriceQuote, error) {
+	if !start.Before(end) {
+		return nil, &models.ValidationError{Field: "end_time", Message: "invalid booking duration"}
+	}
+	loc, err := car.Location.TimeLocation()
+	if err != nil {
+		return nil, err
+	}
+	q := &PriceQuote{CarID: car.ID, Start: start, End: end}
+	for _, w := range rentalDays(start, end, loc) {
+		day := &RentalDay{Start: w.Start, End: w.End}
+		if w.End.After(end) {
+			day.End = end
+			day.Partial = true
+		}
+		q.Days = append(q.Days, day)
+	}
+
+	for _, rule := range e.rules {
+		if err := rule.Apply(ctx, car, q); err != nil {
+			return nil, err
+		}
+	}
+	q.Total = roundCents(q.Subtotal())
+	return q, nil
+}
+
+// rentalDays splits [start, end) into days of the local calendar in loc, each
+// starting at the pickup's local time of day. The last day may run past end.
+// Days are counted from the pickup rather than chained, so a pickup at a
+// time skipped by a DST change doesn't shift the days after it.
+func rentalDays(start, end time.Time, loc *time.Location) []models.TimeWindow {
+	local := start.In(loc)
+	y, m, d := local.Date()
+	hh, mm, ss := local.Clock()
+	dayAt := func(n int) time.Time {
+		return time.Date(y, m, d+n, hh, mm, ss, local.Nanosecond(), loc)
+	}
+	var days []models.TimeWindow
+	for n, dayStart := 0, local; dayStart.Before(end); n++ {
+		dayEnd := dayAt(n + 1)
+		days = append(days, models.TimeWindow{Start: dayStart, End: dayEnd})
+		dayStart = dayEnd
+	}
+	return days
+}
+
+// BaseRateRule charges PricePerDay for whole days. A trailing partial day is
+// charged per hour but never more than a full day.
+type BaseRateRule struct{}
+
+func (r *BaseRateRule) Apply(ctx context.Context, car *models.Car, q *PriceQuote) error {
+	var total float64
+	for _, d := range q.Days {
+		d.Rate = partialDayRate(car, d, car.PricePerDay)
+		total += d.Rate
+	}
+	q.add(models.PriceComponentRental, fmt.Sprintf("%d day(s) at %.2f", len(q.Days), car.PricePerDay), total)
+	return nil
+}
+
+// partialDayRate applies the hourly rate, capped at dailyRate, to partial
+// days. The hourly rate scales with dailyRate so seasonal prices carry over.
+func partialDayRate(car *models.Car, d *RentalDay, dailyRate float64) float64 {
+	if !d.Partial || car.PricePerHour <= 0 || car.PricePerDay <= 0 {
+		return dailyRate
+	}
+	hourly := car.PricePerHour * dailyRate / car.PricePerDay
+	return math.Min(math.Ceil(d.Hours())*hourly, dailyRate)
+}
+
+// SeasonalPricingRule replaces the daily rate on days inside a host-defined
+// season. Later seasons win when they overlap.
+type SeasonalPricingRule struct {
+	mu      sync.RWMutex
+	seasons []models.SeasonalPrice
+}
+
+func NewSeasonalPricingRule() *SeasonalPricingRule {
+	return &SeasonalPricingRule{}
+}
+
+func (r *SeasonalPricingRule) AddSeason(season models.SeasonalPrice) error {
+	if season.HostID == "" {
+		return &models.ValidationError{Field: "host_id", Message: "host ID is required"}
+	}
+	if season.EndDate.Before(season.StartDate) {
+		return &models.ValidationError{Field: "end_date", Message: "season must not end before it starts"}
+	}
+	if season.PricePerDay <= 0 {
+		return &models.ValidationError{Field: "price_per_day", Message: "seasonal price must be positive"}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seasons = append(r.seasons, season)
+	return nil
+}
+
+func (r *SeasonalPricingRule) seasonFor(car *models.Car, day time.Time) *models.SeasonalPrice {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := len(r.seasons) - 1; i >= 0; i-- {
+		s := &r.seasons[i]
+		if s.HostID != car.HostID || (s.CarID != "" && s.CarID != car.ID) {
+			continue
+		}
+		if !sameOrAfterDate(day, s.StartDate) || !sameOrAfterDate(s.EndDate, day) {
+			continue
+		}
+		return s
+	}
+	return nil
+}
+
+func (r *SeasonalPricingRule) Apply(ctx context.Context, car *models.Car, q *PriceQuote) error {
+	var delta float64
+	var names []string
+	for _, d := range q.Days {
+		season := r.seasonFor(car, d.Start)
+		if season == nil {
+			continue
+		}
+		rate := partialDayRate(car, d, season.PricePerDay)
+		delta += rate - d.Rate
+		d.Rate = rate
+		if len(names) == 0 || names[len(names)-1] != season.Name {
+			names = append(names, season.Name)
+		}
+	}
+	q.add(models.PriceComponentSeasonal, "Seasonal pricing: "+strings.Join(names, ", "), delta)
+	return nil
+}
+
+// SurchargeRule adds a percentage of the day rate on weekends and holidays.
+// A holiday that falls on a weekend is only charged the holiday surcharge.
+type SurchargeRule struct {
+	WeekendPercent float64
+	HolidayPercent float64
+	Holidays       []time.Time
+}
+
+func (r *SurchargeRule) isHoliday(day time.Time) bool {
+	for _, h := range r.Holidays {
+		if sameDate(day, h) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *SurchargeRule) Apply(ctx context.Context, car *models.Car, q *PriceQuote) error {
+	var weekend, holiday float64
+	for _, d := range q.Days {
+		switch {
+		case r.isHoliday(d.Start):
+			holiday += d.Rate * r.HolidayPercent / 100
+		case d.Start.Weekday() == time.Saturday || d.Start.Weekday() == time.Sunday:
+			weekend += d.Rate * r.WeekendPercent / 100
+		}
+	}
+	q.add(models.PriceComponentWeekendSurcharge, fmt.Sprintf("Weekend surcharge (%.0f%%)", r.WeekendPercent), weekend)
+	q.add(models.PriceComponentHolidaySurcharge, fmt.Sprintf("Holiday surcharge (%.0f%%)", r.HolidayPercent), holiday)
+	return nil
+}
+
+// DurationDiscountRule discounts the rental subtotal for long trips. Only the
+// best applicable discount is given.
+type DurationDiscountRule struct {
+	WeeklyPercent  float64 // 7+ full days
+	MonthlyPercent float64 // 28+ full days
+}
+
+func (r *DurationDiscountRule) Apply(ctx context.Context, car *models.Car, q *PriceQuote) error {
+	days := q.FullDays()
+	switch {
+	case days >= 28 && r.MonthlyPercent > 0:
+		q.add(models.PriceComponentDurationDiscount, fmt.Sprintf("Monthly discount (%.0f%%)", r.MonthlyPercent), -q.Subtotal()*r.MonthlyPercent/100)
+	case days >= 7 && r.WeeklyPercent > 0:
+		q.add(models.PriceComponentDurationDiscount, fmt.Sprintf("Weekly discount (%.0f%%)", r.WeeklyPercent), -q.Subtotal()*r.WeeklyPercent/100)
+	}
+	return nil
+}
+
+// FeeRule adds a flat cleaning fee and a service fee as a percentage of the
+// trip price before fees.
+type FeeRule struct {
+	CleaningFee       float64
+	ServiceFeePercent float64
+}
+
+func (r *FeeRule) Apply(ctx context.Context, car *models.Car, q *PriceQuote) error {
+	serviceFee := q.Subtotal() * r.ServiceFeePercent / 100
+	q.add(models.PriceComponentCleaningFee, "Cleaning fee", r.CleaningFee)
+	q.add(models.PriceComponentServiceFee, fmt.Sprintf("Service fee (%.0f%%)", r.ServiceFeePercent), serviceFee)
+	return nil
+}
+
+// TaxRule charges the pickup city's tax rate on everything before it.
+type TaxRule struct {
+	RatesByCity map[string]float64 // percent, keyed by city name (case-insensitive)
+	DefaultRate float64
+}
+
+func (r *TaxRule) rateFor(city string) float64 {
+	for c, rate := range r.RatesByCity {
+		if strings.EqualFold(c, city) {
+			return rate
+		}
+	}
+	return r.DefaultRate
+}
+
+func (r *TaxRule) Apply(ctx context.Context, car *models.Car, q *PriceQuote) error {
+	rate := r.rateFor(car.Location.City)
+	q.add(models.PriceComponentTax, fmt.Sprintf("Tax (%g%%)", rate), q.Subtotal()*rate/100)
+	return nil
+}
+
+func sameDate(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// sameOrAfterDate compares calendar dates, ignoring the time of day.
+func sameOrAfterDate(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return !time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC).Before(time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC))
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPricingEngineRules(t *testing.T) {
+	ctx := context.Background()
+	car := &models.Car{ID: "car1", HostID: "host1", PricePerDay: 100, PricePerHour: 15, Location: models.Location{City: "Austin"}}
+	// Mon Jan 5 2026 10:00
+	mon := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+
+	seasonal := NewSeasonalPricingRule()
+	if err := seasonal.AddSeason(models.SeasonalPrice{
+		HostID:      "host1",
+		Name:        "Festival",
+		StartDate:   time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC),
+		EndDate:     time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC),
+		PricePerDay: 200,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		rules      []PricingRule
+		start, end time.Time
+		want       float64
+		wantItems  map[models.PriceComponent]float64
+	}{
+		{
+			name:  "partial day charged hourly",
+			rules: []PricingRule{&BaseRateRule{}},
+			start: mon, end: mon.Add(3 * time.Hour),
+			want: 45,
+		},
+		{
+			name:  "partial day capped at daily rate",
+			rules: []PricingRule{&BaseRateRule{}},
+			start: mon, end: mon.Add(31 * time.Hour),
+			want: 200,
+		},
+		{
+			name:  "weekend and holiday surcharges",
+			rules: []PricingRule{&BaseRateRule{}, &SurchargeRule{WeekendPercent: 20, HolidayPercent: 50, Holidays: []time.Time{mon.AddDate(0, 0, 6)}}},
+			// Fri 10:00 to Mon 10:00: Fri, Sat (weekend), Sun (holiday)
+			start: mon.AddDate(0, 0, 4), end: mon.AddDate(0, 0, 7),
+			want:      370,
+			wantItems: map[models.PriceComponent]float64{models.PriceComponentWeekendSurcharge: 20, models.PriceComponentHolidaySurcharge: 50},
+		},
+		{
+			name:  "weekly discount",
+			rules: []PricingRule{&BaseRateRule{}, &DurationDiscountRule{WeeklyPercent: 10, MonthlyPercent: 30}},
+			start: mon, end: mon.AddDate(0, 0, 7),
+			want: 630,
+		},
+		{
+			name:  "monthly discount beats weekly",
+			rules: []PricingRule{&BaseRateRule{}, &DurationDiscountRule{WeeklyPercent: 10, MonthlyPercent: 30}},
+			start: mon, end: mon.AddDate(0, 0, 28),
+			want: 1960,
+		},
+		{
+			name:  "seasonal price replaces daily rate",
+			rules: []PricingRule{&BaseRateRule{}, seasonal},
+			start: time.Date(2026, 3, 9, 10, 0, 0, 0, time.UTC), end: time.Date(2026, 3, 12, 10, 0, 0, 0, time.UTC),
+			want:      500,
+			wantItems: map[models.PriceComponent]float64{models.PriceComponentSeasonal: 200},
+		},
+		{
+			name: "fees and city tax",
+			rules: []PricingRule{
+				&BaseRateRule{},
+				&FeeRule{CleaningFee: 30, ServiceFeePercent: 10},
+				&TaxRule{RatesByCity: map[string]float64{"austin": 8.25}, DefaultRate: 5},
+			},
+			start: mon, end: mon.AddDate(0, 0, 2),
+			// (200 + 30 + 20) * 1.0825
+			want:      270.63,
+			wantItems: map[models.PriceComponent]float64{models.PriceComponentServiceFee: 20, models.PriceComponentTax: 20.63},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := NewPricingEngine(tt.rules...).Quote(ctx, car, tt.start, tt.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.Total != tt.want {
+				t.Errorf("Expected total %.2f, got %.2f (%+v)", tt.want, q.Total, q.Items)
+			}
+			for component, want := range tt.wantItems {
+				var got float64
+				for _, item := range q.Items {
+					if item.Component == component {
+						got += item.Amount
+					}
+				}
+				if got != want {
+					t.Errorf("Expected %s of %.2f, got %.2f", component, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPricingFollowsCarLocalDays(t *testing.T) {
+	ctx := context.Background()
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork := &models.Car{ID: "car1", PricePerDay: 100, PricePerHour: 15, Location: models.Location{TimeZone: "America/New_York"}}
+	tokyo := &models.Car{ID: "car2", PricePerDay: 100, PricePerHour: 15, Location: models.Location{TimeZone: "Asia/Tokyo"}}
+
+	tests := []struct {
+		name       string
+		car        *models.Car
+		rules      []PricingRule
+		start, end time.Time
+		wantDays   int
+		want       float64
+	}{
+		{
+			// Sat 10:00 EST to Sun 10:00 EDT is 23 hours but one whole day.
+			name:  "spring forward day",
+			car:   newYork,
+			rules: []PricingRule{&BaseRateRule{}},
+			start: time.Date(2026, 3, 7, 10, 0, 0, 0, ny), end: time.Date(2026, 3, 8, 10, 0, 0, 0, ny),
+			wantDays: 1, want: 100,
+		},
+		{
+			// 01:00 to 04:00 on the spring-forward night is two hours.
+			name:  "across the skipped hour",
+			car:   newYork,
+			rules: []PricingRule{&BaseRateRule{}},
+			start: time.Date(2026, 3, 8, 1, 0, 0, 0, ny), end: time.Date(2026, 3, 8, 4, 0, 0, 0, ny),
+			wantDays: 0, want: 30,
+		},
+		{
+			// Sat 10:00 EDT to Sun 10:00 EST is 25 hours but one whole day,
+			// not a day and an hour.
+			name:  "fall back day",
+			car:   newYork,
+			rules: []PricingRule{&BaseRateRule{}},
+			start: time.Date(2026, 10, 31, 10, 0, 0, 0, ny), end: time.Date(2026, 11, 1, 10, 0, 0, 0, ny),
+			wantDays: 1, want: 100,
+		},
+		{
+			// 167 hours, but seven whole local days earn the weekly discount.
+			name:  "week across spring forward",
+			car:   newYork,
+			rules: []PricingRule{&BaseRateRule{}, &DurationDiscountRule{WeeklyPercent: 10}},
+			start: time.Date(2026, 3, 5, 10, 0, 0, 0, ny), end: time.Date(2026, 3, 12, 10, 0, 0, 0, ny),
+			wantDays: 7, want: 630,
+		},
+		{
+			// Friday 20:00 UTC is already Saturday morning in Tokyo.
+			name:  "weekend in the car's time zone",
+			car:   tokyo,
+			rules: []PricingRule{&BaseRateRule{}, &SurchargeRule{WeekendPercent: 20}},
+			start: time.Date(2026, 1, 9, 20, 0, 0, 0, time.UTC), end: time.Date(2026, 1, 10, 20, 0, 0, 0, time.UTC),
+			wantDays: 1, want: 120,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := NewPricingEngine(tt.rules...).Quote(ctx, tt.car, tt.start.UTC(), tt.end.UTC())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.FullDays() != tt.wantDays {
+				t.Errorf("Expected %d whole days, got %d of %d", tt.wantDays, q.FullDays(), len(q.Days))
+			}
+			if q.Total != tt.want {
+				t.Errorf("Expected total %.2f, got %.2f (%+v)", tt.want, q.Total, q.Items)
+			}
+		})
+	}
+
+	if _, err := NewDefaultPricingEngine().Quote(ctx, &models.Car{Location: models.Location{TimeZone: "Mars/Olympus"}}, time.Now(), time.Now().Add(time.Hour)); err == nil {
+		t.Error("Expected an unknown time zone to fail the quote")
+	}
+}
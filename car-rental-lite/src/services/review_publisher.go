@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ReviewPublisher periodically publishes reviews whose window has closed.
+type ReviewPublisher struct {
+	reviews  *ReviewService
+	interval time.Duration
+}
+
+func NewReviewPublisher(reviews *ReviewService, interval time.Duration) *ReviewPublisher {
+	return &ReviewPublisher{reviews: reviews, interval: interval}
+}
+
+// Start runs the publisher in a goroutine until ctx is done.
+func (p *ReviewPublisher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := p.reviews.PublishDue(ctx); err != nil {
+					fmt.Printf("[review-publisher] publishing reviews: %v\n", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
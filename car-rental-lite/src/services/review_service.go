@@ -0,0 +1,186 @@
+package services
+
+import (
+	"car-rental-lite/src/idgen"
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultReviewWindow is how long after the return both sides may review a
+// booking.
+const DefaultReviewWindow = 14 * 24 * time.Hour
+
+const maxCommentLength = 2000
+
+var (
+	// ErrReviewNotOpen is returned, wrapped in a *models.ConflictError, when
+	// reviewing a booking that hasn't been completed.
+	ErrReviewNotOpen = errors.New("only completed bookings can be reviewed")
+	// ErrReviewWindowClosed is returned, wrapped in a *models.ConflictError,
+	// when reviewing after the review window.
+	ErrReviewWindowClosed = errors.New("review window has closed")
+)
+
+// ReviewService lets the renter and the host rate each other once per
+// completed booking. Reviews are double-blind: neither side sees the other's
+// review, and ratings don't move, until both have submitted or the window
+// closes.
+type ReviewService struct {
+	reviewRepo  repositories.ReviewRepository
+	bookingRepo repositories.BookingRepository
+	carRepo     repositories.CarRepository
+	ids         idgen.IDGenerator
+	now         func() time.Time
+	window      time.Duration
+}
+
+func NewReviewService(rRepo repositories.ReviewRepository, bRepo repositories.BookingRepository, cRepo repositories.CarRepository) *ReviewService {
+	return &ReviewService{
+		reviewRepo:  rRepo,
+		bookingRepo: bRepo,
+		carRepo:     cRepo,
+		ids:         idgen.NewULIDGenerator(),
+		now:         time.Now,
+		window:      DefaultReviewWindow,
+	}
+}
+
+func (s *ReviewService) SetIDGenerator(ids idgen.IDGenerator) {
+	s.ids = ids
+}
+
+// SetClock replaces time.Now, e.g. with a fake clock in tests.
+func (s *ReviewService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// SetWindow replaces DefaultReviewWindow.
+func (s *ReviewService) SetWindow(window time.Duration) {
+	s.window = window
+}
+
+// SubmitReview records reviewerID's rating of the other side of a completed
+// booking. The reviewer must be the booking's renter or the car's host. The
+// review is published straight away if the other side has already reviewed.
+func (s *ReviewService) SubmitReview(ctx context.Context, bookingID, reviewerID string, rating int, comment string) (*models.Review, error) {
+	if rating < 1 || rating > 5 {
+		return nil, &models.ValidationError{Field: "rating", Message: "rating must be between 1 and 5"}
+	}
+	comment = strings.TrimSpace(comment)
+	if len(comment) > maxCommentLength {
+		return nil, &models.ValidationError{Field: "comment", Message: fmt.Sprintf("comment must be at most %d characters", maxCommentLength)}
+	}
+
+	booking, err := s.bookingRepo.GetBooking(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status != models.BookingStatusCompleted {
+		return nil, &models.ConflictError{Err: ErrReviewNotOpen, BookingID: bookingID}
+	}
+	now := s.now()
+	revealAt := booking.ReturnedAt.Add(s.window)
+	if !now.Before(revealAt) {
+		return nil, &models.ConflictError{Err: ErrReviewWindowClosed, BookingID: bookingID}
+	}
+	car, err := s.carRepo.GetCar(ctx, booking.CarID)
+	if err != nil {
+		return nil, err
+	}
+
+	review := &models.Review{
+		ID:        s.ids.NewID(),
+		BookingID: bookingID,
+		CarID:     car.ID,
+		HostID:    car.HostID,
+		UserID:    booking.UserID,
+		Rating:    rating,
+		Comment:   comment,
+		CreatedAt: now,
+		RevealAt:  revealAt,
+	}
+	switch reviewerID {
+	case booking.UserID:
+		review.Role = models.ReviewerRenter
+	case car.HostID:
+		review.Role = models.ReviewerHost
+	default:
+		return nil, &models.ValidationError{Field: "reviewer_id", Message: "reviewer must be the renter or the car's host"}
+	}
+	if err := s.reviewRepo.CreateReview(ctx, review); err != nil {
+		return nil, err
+	}
+
+	reviews, err := s.reviewRepo.GetReviewsForBooking(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if len(reviews) == 2 {
+		if err := s.reviewRepo.PublishReviews(ctx, []string{reviews[0].ID, reviews[1].ID}, now); err != nil {
+			return nil, err
+		}
+		review.PublishedAt = now
+	}
+	return review, nil
+}
+
+// GetBookingReviews returns the booking's reviews that viewerID may see: the
+// published ones and viewerID's own.
+func (s *ReviewService) GetBookingReviews(ctx context.Context, bookingID, viewerID string) ([]*models.Review, error) {
+	reviews, err := s.reviewRepo.GetReviewsForBooking(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	var visible []*models.Review
+	for _, rv := range reviews {
+		own := (rv.Role == models.ReviewerRenter && viewerID == rv.UserID) || (rv.Role == models.ReviewerHost && viewerID == rv.HostID)
+		if rv.Published() || own {
+			visible = append(visible, rv)
+		}
+	}
+	return visible, nil
+}
+
+// GetCarReviews returns the published reviews renters left for the car.
+func (s *ReviewService) GetCarReviews(ctx context.Context, carID string) ([]*models.Review, error) {
+	reviews, err := s.reviewRepo.GetReviewsForCar(ctx, carID)
+	return published(reviews), err
+}
+
+// GetUserReviews returns the published reviews hosts left for the renter.
+func (s *ReviewService) GetUserReviews(ctx context.Context, userID string) ([]*models.Review, error) {
+	reviews, err := s.reviewRepo.GetReviewsForUser(ctx, userID)
+	return published(reviews), err
+}
+
+func published(reviews []*models.Review) []*models.Review {
+	var res []*models.Review
+	for _, rv := range reviews {
+		if rv.Published() {
+			res = append(res, rv)
+		}
+	}
+	return res
+}
+
+// PublishDue publishes every review whose window has closed without the
+// other side reviewing. It returns the number published.
+func (s *ReviewService) PublishDue(ctx context.Context) (int, error) {
+	due, err := s.reviewRepo.ListDueReviews(ctx, s.now())
+	if err != nil || len(due) == 0 {
+		return 0, err
+	}
+	ids := make([]string, len(due))
+	for i, rv := range due {
+		ids[i] = rv.ID
+	}
+	if err := s.reviewRepo.PublishReviews(ctx, ids, s.now()); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// completeBooking books, confirms, picks up and returns the fixture car for
+// user1 over [start, end), returning on time.
+func completeBooking(t *testing.T, f *bookingFixture, start, end time.Time) *models.Booking {
+	t.Helper()
+	ctx := context.Background()
+	now := start
+	inspections := NewInspectionService(f.repo, f.repo, f.repo)
+	inspections.SetClock(func() time.Time { return now })
+	trips := NewTripService(f.repo, inspections)
+
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.bookings.ConfirmBooking(ctx, b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trips.StartTrip(ctx, &models.InspectionReport{BookingID: b.ID, InspectorID: "host1", Type: models.InspectionTypePickup, FuelLevel: 100}); err != nil {
+		t.Fatal(err)
+	}
+	now = end
+	if b, err = trips.EndTrip(ctx, &models.InspectionReport{BookingID: b.ID, InspectorID: "host1", Type: models.InspectionTypeDropoff, FuelLevel: 100}); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestReviewsAreDoubleBlind(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	now := jan(5)
+	reviews := NewReviewService(f.repo, f.repo, f.repo)
+	reviews.SetClock(func() time.Time { return now })
+
+	open, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(10), jan(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reviews.SubmitReview(ctx, open.ID, "user1", 5, ""); !errors.Is(err, ErrReviewNotOpen) {
+		t.Errorf("Expected a pending booking to be refused, got %v", err)
+	}
+
+	b := completeBooking(t, f, jan(2), jan(4))
+	var invalid *models.ValidationError
+	if _, err := reviews.SubmitReview(ctx, b.ID, "user1", 6, ""); !errors.As(err, &invalid) || invalid.Field != "rating" {
+		t.Errorf("Expected a rating of 6 to be invalid, got %v", err)
+	}
+	if _, err := reviews.SubmitReview(ctx, b.ID, "stranger", 3, ""); !errors.As(err, &invalid) || invalid.Field != "reviewer_id" {
+		t.Errorf("Expected a stranger to be refused, got %v", err)
+	}
+
+	renter, err := reviews.SubmitReview(ctx, b.ID, "user1", 4, "  Clean car  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renter.Role != models.ReviewerRenter || renter.Published() || renter.Comment != "Clean car" {
+		t.Errorf("Expected an unpublished renter review, got %+v", renter)
+	}
+	if _, err := reviews.SubmitReview(ctx, b.ID, "user1", 1, ""); !errors.Is(err, models.ErrConflict) {
+		t.Errorf("Expected a second renter review to conflict, got %v", err)
+	}
+	if seen, _ := reviews.GetBookingReviews(ctx, b.ID, "host1"); len(seen) != 0 {
+		t.Errorf("Expected the host not to see the renter's review yet, got %d", len(seen))
+	}
+	if seen, _ := reviews.GetBookingReviews(ctx, b.ID, "user1"); len(seen) != 1 {
+		t.Errorf("Expected the renter to see their own review, got %d", len(seen))
+	}
+	if car, _ := f.repo.GetCar(ctx, f.car.ID); car.Rating.Count != 0 {
+		t.Errorf("Expected no rating before publication, got %+v", car.Rating)
+	}
+
+	host, err := reviews.SubmitReview(ctx, b.ID, "host1", 5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !host.Published() {
+		t.Error("Expected the second review to publish both")
+	}
+	if seen, _ := reviews.GetBookingReviews(ctx, b.ID, "host1"); len(seen) != 2 {
+		t.Errorf("Expected the host to see both reviews, got %d", len(seen))
+	}
+	car, _ := f.repo.GetCar(ctx, f.car.ID)
+	user, _ := f.repo.GetUser(ctx, "user1")
+	if car.Rating != (models.Rating{Average: 4, Count: 1}) || user.Rating != (models.Rating{Average: 5, Count: 1}) {
+		t.Errorf("Expected car 4 and renter 5, got %+v and %+v", car.Rating, user.Rating)
+	}
+}
+
+func TestReviewWindowPublishesAndCloses(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	now := jan(5)
+	reviews := NewReviewService(f.repo, f.repo, f.repo)
+	reviews.SetClock(func() time.Time { return now })
+	reviews.SetWindow(7 * 24 * time.Hour)
+
+	b := completeBooking(t, f, jan(2), jan(4))
+	if _, err := reviews.SubmitReview(ctx, b.ID, "user1", 2, ""); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := reviews.PublishDue(ctx); err != nil || n != 0 {
+		t.Errorf("Expected nothing due inside the window, got %d, %v", n, err)
+	}
+
+	now = jan(11)
+	if n, err := reviews.PublishDue(ctx); err != nil || n != 1 {
+		t.Fatalf("Expected 1 review published when the window closed, got %d, %v", n, err)
+	}
+	if got, _ := reviews.GetCarReviews(ctx, f.car.ID); len(got) != 1 || got[0].Rating != 2 {
+		t.Errorf("Expected the renter's 2 on the car, got %+v", got)
+	}
+	if _, err := reviews.SubmitReview(ctx, b.ID, "host1", 5, ""); !errors.Is(err, ErrReviewWindowClosed) {
+		t.Errorf("Expected the host to be too late, got %v", err)
+	}
+}
+
+func TestSearchSortsByRating(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	reviews := NewReviewService(f.repo, f.repo, f.repo)
+	reviews.SetClock(func() time.Time { return jan(5) })
+
+	for _, id := range []string{"unrated", "best"} {
+		if err := f.inv.RegisterCar(ctx, &models.Car{ID: id, HostID: "host1", PricePerDay: 100, IsActive: true}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.inv.AddAvailability(ctx, id, jan(1), jan(31)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Both windows have closed, so PublishDue folds them in.
+	for booking, rv := range map[string]*models.Review{
+		"b1": {CarID: f.car.ID, Rating: 3},
+		"b2": {CarID: "best", Rating: 5},
+	} {
+		rv.BookingID, rv.HostID, rv.Role, rv.RevealAt = booking, "host1", models.ReviewerRenter, jan(4)
+		if err := f.repo.CreateReview(ctx, rv); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := reviews.PublishDue(ctx); err != nil || n != 2 {
+		t.Fatalf("Expected 2 reviews published, got %d, %v", n, err)
+	}
+
+	page, err := f.inv.Search(ctx, models.SearchQuery{StartTime: jan(10), EndTime: jan(12), SortBy: models.SearchSortRating})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range page.Results {
+		got = append(got, r.Car.ID)
+	}
+	if len(got) != 3 || got[0] != "best" || got[1] != f.car.ID || got[2] != "unrated" {
+		t.Errorf("Expected best, the fixture car, then unrated, got %v", got)
+	}
+}
+
+func TestSearchSortsByHostRating(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	reviews := NewReviewService(f.repo, f.repo, f.repo)
+	reviews.SetClock(func() time.Time { return jan(5) })
+
+	for _, id := range []string{"host2", "host3"} {
+		if err := f.repo.CreateHost(ctx, &models.Host{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id, host := range map[string]string{"star": "host1", "steady": "host2", "newcomer": "host3"} {
+		if err := f.inv.RegisterCar(ctx, &models.Car{ID: id, HostID: host, PricePerDay: 100, IsActive: true}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.inv.AddAvailability(ctx, id, jan(1), jan(31)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// host1 averages 3.5 over its two cars, host2 has 4 and host3 is unrated,
+	// although "star" is the best-rated car.
+	for booking, rv := range map[string]*models.Review{
+		"b1": {CarID: f.car.ID, HostID: "host1", Rating: 2},
+		"b2": {CarID: "star", HostID: "host1", Rating: 5},
+		"b3": {CarID: "steady", HostID: "host2", Rating: 4},
+	} {
+		rv.BookingID, rv.Role, rv.RevealAt = booking, models.ReviewerRenter, jan(4)
+		if err := f.repo.CreateReview(ctx, rv); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := reviews.PublishDue(ctx); err != nil || n != 3 {
+		t.Fatalf("Expected 3 reviews published, got %d, %v", n, err)
+	}
+
+	page, err := f.inv.Search(ctx, models.SearchQuery{StartTime: jan(10), EndTime: jan(12), SortBy: models.SearchSortHostRating})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range page.Results {
+		got = append(got, r.Car.ID)
+	}
+	if len(got) != 4 || got[0] != "steady" || got[3] != "newcomer" {
+		t.Fatalf("Expected host2's car first and host3's last, got %v", got)
+	}
+	if host1 := map[string]bool{got[1]: true, got[2]: true}; !host1["star"] || !host1[f.car.ID] {
+		t.Errorf("Expected host1's cars in the middle, got %v", got)
+	}
+}
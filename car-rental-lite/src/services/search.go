@@ -0,0 +1,162 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// searchCursor is the keyset position of the last result on a page. Paging by
+// key rather than offset keeps pages stable when cars are added or booked
+// between requests.
+type searchCursor struct {
+	SortBy models.SearchSort `json:"s"`
+	Key    float64           `json:"k"`
+	CarID  string            `json:"id"`
+}
+
+func encodeCursor(c searchCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (searchCursor, error) {
+	var c searchCursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, &models.ValidationError{Field: "cursor", Message: "invalid search cursor"}
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, &models.ValidationError{Field: "cursor", Message: "invalid search cursor"}
+	}
+	return c, nil
+}
+
+func validateSearchQuery(q *models.SearchQuery) error {
+	if !q.StartTime.Before(q.EndTime) {
+		return &models.ValidationError{Field: "end_time", Message: "start time must be before end time"}
+	}
+	if q.MaxPrice > 0 && q.MinPrice > q.MaxPrice {
+		return &models.ValidationError{Field: "max_price", Message: "min price must not exceed max price"}
+	}
+	switch q.SortBy {
+	case "":
+		q.SortBy = models.SearchSortDistance
+	case models.SearchSortDistance, models.SearchSortPrice, models.SearchSortNewest, models.SearchSortRating, models.SearchSortHostRating:
+	default:
+		return &models.ValidationError{Field: "sort_by", Message: "unknown sort order"}
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultSearchLimit
+	}
+	if q.Limit > maxSearchLimit {
+		q.Limit = maxSearchLimit
+	}
+	return nil
+}
+
+func matchesQuery(car *models.Car, q *models.SearchQuery) bool {
+	if q.MinPrice > 0 && car.PricePerDay < q.MinPrice {
+		return false
+	}
+	if q.MaxPrice > 0 && car.PricePerDay > q.MaxPrice {
+		return false
+	}
+	if len(q.CarTypes) > 0 {
+		found := false
+		for _, t := range q.CarTypes {
+			if car.Type == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.Make != "" && !strings.EqualFold(car.Make, q.Make) {
+		return false
+	}
+	if q.Model != "" && !strings.EqualFold(car.Model, q.Model) {
+		return false
+	}
+	if q.MinYear > 0 && car.Year < q.MinYear {
+		return false
+	}
+	if q.HostID != "" && car.HostID != q.HostID {
+		return false
+	}
+	return car.HasAmenities(q.Amenities)
+}
+
+// sortKey maps a result to an ascending key for the given order. hostRatings
+// is only needed for SearchSortHostRating.
+func sortKey(r *models.CarSearchResult, by models.SearchSort, hostRatings map[string]models.Rating) float64 {
+	switch by {
+	case models.SearchSortPrice:
+		return r.Car.PricePerDay
+	case models.SearchSortNewest:
+		return -float64(r.Car.Year)
+	case models.SearchSortRating:
+		return ratingKey(r.Car.Rating)
+	case models.SearchSortHostRating:
+		return ratingKey(hostRatings[r.Car.HostID])
+	default:
+		return r.DistanceKm
+	}
+}
+
+// ratingKey puts the best ratings first and unrated ones after every rated
+// one.
+func ratingKey(rating models.Rating) float64 {
+	if rating.Count == 0 {
+		return 1
+	}
+	return -rating.Average
+}
+
+// paginate sorts results by q.SortBy (car ID breaks ties) and returns the
+// page following q.Cursor.
+func paginate(results []*models.CarSearchResult, q *models.SearchQuery, hostRatings map[string]models.Rating) (*models.SearchPage, error) {
+	less := func(ka float64, ida string, kb float64, idb string) bool {
+		if ka != kb {
+			return ka < kb
+		}
+		return ida < idb
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return less(sortKey(results[i], q.SortBy, hostRatings), results[i].Car.ID, sortKey(results[j], q.SortBy, hostRatings), results[j].Car.ID)
+	})
+
+	from := 0
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if c.SortBy != q.SortBy {
+			return nil, &models.ValidationError{Field: "cursor", Message: "search cursor does not match sort order"}
+		}
+		from = sort.Search(len(results), func(i int) bool {
+			return less(c.Key, c.CarID, sortKey(results[i], q.SortBy, hostRatings), results[i].Car.ID)
+		})
+	}
+
+	to := from + q.Limit
+	if to > len(results) {
+		to = len(results)
+	}
+	page := &models.SearchPage{Results: results[from:to]}
+	if to < len(results) {
+		last := results[to-1]
+		page.NextCursor = encodeCursor(searchCursor{SortBy: q.SortBy, Key: sortKey(last, q.SortBy, hostRatings), CarID: last.Car.ID})
+	}
+	return page, nil
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// TripPolicy sets the charges applied when a car comes back.
+type TripPolicy struct {
+	// LateGracePeriod is how long after EndTime a return is still on time.
+	LateGracePeriod time.Duration
+	// LateFeeMultiplier scales the hourly rate for each started late hour.
+	LateFeeMultiplier float64
+	// MileageAllowancePerDay is the distance included per rental day.
+	MileageAllowancePerDay int
+	// OverageFeePerUnit is charged per unit driven past the allowance.
+	OverageFeePerUnit float64
+	// FuelFeePerPercent is charged per percentage point of fuel missing
+	// compared to pickup, plus a flat RefuelServiceFee.
+	FuelFeePerPercent float64
+	RefuelServiceFee  float64
+}
+
+func DefaultTripPolicy() TripPolicy {
+	return TripPolicy{
+		LateGracePeriod:        30 * time.Minute,
+		LateFeeMultiplier:      1.5,
+		MileageAllowancePerDay: 200,
+		OverageFeePerUnit:      0.5,
+		FuelFeePerPercent:      0.8,
+		RefuelServiceFee:       15,
+	}
+}
+
+// TripService runs a booking through pickup and return, using the
+// inspection reports to move it CONFIRMED -> ACTIVE -> COMPLETED.
+type TripService struct {
+	bookingRepo repositories.BookingRepository
+	inspections *InspectionService
+	policy      TripPolicy
+	events      EventPublisher
+}
+
+func NewTripService(bRepo repositories.BookingRepository, inspections *InspectionService) *TripService {
+	return &TripService{
+		bookingRepo: bRepo,
+		inspections: inspections,
+		policy:      DefaultTripPolicy(),
+		events:      nopPublisher{},
+	}
+}
+
+func (s *TripService) SetPolicy(policy TripPolicy) {
+	s.policy = policy
+}
+
+// SetEventPublisher sets where the service's domain events go. By default
+// they're discarded.
+func (s *TripService) SetEventPublisher(events EventPublisher) {
+	s.events = events
+}
+
+// StartTrip records the pickup inspection and activates the booking in one
+// step, so a refused transition leaves no report behind.
+func (s *TripService) StartTrip(ctx context.Context, pickup *models.InspectionReport) (*models.Booking, error) {
+	if pickup.Type != models.InspectionTypePickup {
+		return nil, &models.ValidationError{Field: "type", Message: "trip must start with a pickup inspection"}
+	}
+	booking, err := s.bookingRepo.GetBooking(ctx, pickup.BookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status != models.BookingStatusConfirmed {
+		return nil, &models.ConflictError{Err: fmt.Errorf("%w: only confirmed bookings can start a trip", repositories.ErrInvalidTransition), BookingID: booking.ID}
+	}
+
+	if _, err := s.inspections.RecordInspection(ctx, pickup, nil); err != nil {
+		return nil, err
+	}
+	return s.bookingRepo.GetBooking(ctx, booking.ID)
+}
+
+// EndTrip records the dropoff inspection, charges any late return, mileage
+// overage and fuel refill, and completes the booking.
+func (s *TripService) EndTrip(ctx context.Context, dropoff *models.InspectionReport) (*models.Booking, error) {
+	if dropoff.Type != models.InspectionTypeDropoff {
+		return nil, &models.ValidationError{Field: "type", Message: "trip must end with a dropoff inspection"}
+	}
+	booking, err := s.bookingRepo.GetBooking(ctx, dropoff.BookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status != models.BookingStatusActive {
+		return nil, &models.ConflictError{Err: fmt.Errorf("%w: only active bookings can end a trip", repositories.ErrInvalidTransition), BookingID: booking.ID}
+	}
+
+	report, err := s.inspections.RecordInspection(ctx, dropoff, s.Adjustments)
+	if err != nil {
+		return nil, err
+	}
+	completed, err := s.bookingRepo.GetBooking(ctx, booking.ID)
+	if err != nil {
+		return nil, err
+	}
+	publish(ctx, s.events, models.BookingCompleted{
+		BookingID:  completed.ID,
+		UserID:     completed.UserID,
+		CarID:      completed.CarID,
+		TotalPrice: completed.TotalPrice,
+		OccurredAt: report.CreatedAt,
+	})
+	return completed, nil
+}
+
+// Adjustments computes the post-trip charges for a dropoff report.
+func (s *TripService) Adjustments(car *models.Car, booking *models.Booking, dropoff *models.InspectionReport) []models.PriceLineItem {
+	var items []models.PriceLineItem
+	add := func(component models.PriceComponent, description string, amount float64) {
+		amount = roundCents(amount)
+		if amount > 0 {
+			items = append(items, models.PriceLineItem{Component: component, Description: description, Amount: amount})
+		}
+	}
+
+	// Late return: every started hour past EndTime, once past the grace period
+	if late := dropoff.CreatedAt.Sub(booking.EndTime); late > s.policy.LateGracePeriod {
+		hours := math.Ceil(late.Hours())
+		rate := hourlyRate(car) * s.policy.LateFeeMultiplier
+		add(models.PriceComponentLateReturn, fmt.Sprintf("Late return: %.0f hour(s) at %.2f", hours, rate), hours*rate)
+	}
+
+	// Mileage beyond the allowance for the booked days
+	allowance := s.policy.MileageAllowancePerDay * rentalDayCount(car, booking.StartTime, booking.EndTime)
+	if s.policy.MileageAllowancePerDay > 0 && dropoff.Mileage > allowance {
+		over := dropoff.Mileage - allowance
+		add(models.PriceComponentMileageOverage, fmt.Sprintf("Mileage overage: %d over %d allowed at %.2f", over, allowance, s.policy.OverageFeePerUnit), float64(over)*s.policy.OverageFeePerUnit)
+	}
+
+	// Fuel returned below the pickup level
+	if dropoff.FuelDelta < 0 {
+		missing := -dropoff.FuelDelta
+		add(models.PriceComponentFuelRefill, fmt.Sprintf("Fuel refill: %g%% below pickup level", missing), missing*s.policy.FuelFeePerPercent+s.policy.RefuelServiceFee)
+	}
+	return items
+}
+
+// hourlyRate falls back to a 24th of the daily rate for cars without one.
+func hourlyRate(car *models.Car) float64 {
+	if car.PricePerHour > 0 {
+		return car.PricePerHour
+	}
+	return car.PricePerDay / 24
+}
+
+// rentalDayCount counts rental days the way the pricing engine splits them,
+// in the car's local calendar, with a trailing partial day counted as a
+// whole one. A car with an unknown time zone is counted in UTC.
+func rentalDayCount(car *models.Car, start, end time.Time) int {
+	loc, err := car.Location.TimeLocation()
+	if err != nil {
+		loc = time.UTC
+	}
+	return len(rentalDays(start, end, loc))
+}
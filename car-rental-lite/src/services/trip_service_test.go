@@ -0,0 +1,93 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTripLifecycleCharges(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	now := jan(2)
+	inspections := NewInspectionService(f.repo, f.repo, f.repo)
+	inspections.SetClock(func() time.Time { return now })
+	trips := NewTripService(f.repo, inspections)
+
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(2), jan(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pickup := &models.InspectionReport{BookingID: b.ID, InspectorID: "host1", Type: models.InspectionTypePickup, FuelLevel: 90, Odometer: 10000}
+	if _, err := trips.StartTrip(ctx, pickup); !errors.Is(err, repositories.ErrInvalidTransition) {
+		t.Errorf("Expected an unconfirmed booking to be refused, got %v", err)
+	}
+	if _, err := f.bookings.ConfirmBooking(ctx, b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = trips.StartTrip(ctx, pickup); err != nil {
+		t.Fatal(err)
+	}
+	if b.Status != models.BookingStatusActive || !b.PickedUpAt.Equal(jan(2)) {
+		t.Fatalf("Expected ACTIVE picked up on Jan 2, got %s at %v", b.Status, b.PickedUpAt)
+	}
+
+	// 3h10m late (4 started hours), 500 driven against 2 x 200 allowed, 30% fuel missing.
+	now = jan(4).Add(3*time.Hour + 10*time.Minute)
+	b, err = trips.EndTrip(ctx, &models.InspectionReport{BookingID: b.ID, InspectorID: "host1", Type: models.InspectionTypeDropoff, FuelLevel: 60, Odometer: 10500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Status != models.BookingStatusCompleted || !b.ReturnedAt.Equal(now) {
+		t.Fatalf("Expected COMPLETED returned at %v, got %s at %v", now, b.Status, b.ReturnedAt)
+	}
+	want := map[models.PriceComponent]float64{
+		models.PriceComponentLateReturn:     25, // 4 x 100/24 x 1.5
+		models.PriceComponentMileageOverage: 50, // 100 x 0.5
+		models.PriceComponentFuelRefill:     39, // 30 x 0.8 + 15
+	}
+	if len(b.Adjustments) != len(want) {
+		t.Fatalf("Expected %d adjustments, got %+v", len(want), b.Adjustments)
+	}
+	for _, item := range b.Adjustments {
+		if item.Amount != want[item.Component] {
+			t.Errorf("Expected %s to be %.2f, got %.2f", item.Component, want[item.Component], item.Amount)
+		}
+	}
+	if b.TotalPrice != 314 {
+		t.Errorf("Expected total 314, got %.2f", b.TotalPrice)
+	}
+}
+
+func TestTripOnTimeReturnHasNoCharges(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	now := jan(2)
+	inspections := NewInspectionService(f.repo, f.repo, f.repo)
+	inspections.SetClock(func() time.Time { return now })
+	trips := NewTripService(f.repo, inspections)
+
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(2), jan(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.bookings.ConfirmBooking(ctx, b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trips.StartTrip(ctx, &models.InspectionReport{BookingID: b.ID, InspectorID: "user1", Type: models.InspectionTypePickup, FuelLevel: 80, Odometer: 500}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Inside the grace period, under the allowance, tank topped up.
+	now = jan(4).Add(20 * time.Minute)
+	b, err = trips.EndTrip(ctx, &models.InspectionReport{BookingID: b.ID, InspectorID: "user1", Type: models.InspectionTypeDropoff, FuelLevel: 100, Odometer: 800})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Adjustments) != 0 || b.TotalPrice != 200 {
+		t.Errorf("Expected no adjustments and total 200, got %+v and %.2f", b.Adjustments, b.TotalPrice)
+	}
+}
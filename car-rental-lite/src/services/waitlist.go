@@ -0,0 +1,216 @@
+package services
+
+import (
+	"car-rental-lite/src/idgen"
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// farFuture stands in for the open end of a recurrence rule with no Until.
+var farFuture = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// WaitlistService keeps renters' saved searches and tells them when a car
+// frees up for one. Matching is driven by the car that changed rather than
+// by the searches: a cancellation or new availability only re-checks the
+// saved searches indexed near that car, and only the car itself is checked
+// for availability, so the fleet is never rescanned.
+type WaitlistService struct {
+	waitlistRepo  repositories.WaitlistRepository
+	userRepo      repositories.UserRepository
+	carRepo       repositories.CarRepository
+	inventoryRepo repositories.InventoryRepository
+	ids           idgen.IDGenerator
+	now           func() time.Time
+	events        EventPublisher
+}
+
+func NewWaitlistService(wRepo repositories.WaitlistRepository, uRepo repositories.UserRepository, cRepo repositories.CarRepository, iRepo repositories.InventoryRepository) *WaitlistService {
+	return &WaitlistService{
+		waitlistRepo:  wRepo,
+		userRepo:      uRepo,
+		carRepo:       cRepo,
+		inventoryRepo: iRepo,
+		ids:           idgen.NewULIDGenerator(),
+		now:           time.Now,
+		events:        nopPublisher{},
+	}
+}
+
+func (s *WaitlistService) SetIDGenerator(ids idgen.IDGenerator) {
+	s.ids = ids
+}
+
+// SetClock replaces time.Now, e.g. with a fake clock in tests.
+func (s *WaitlistService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// SetEventPublisher sets where WaitlistMatched events go. By default they're
+// discarded.
+func (s *WaitlistService) SetEventPublisher(events EventPublisher) {
+	s.events = events
+}
+
+// SaveSearch puts the renter on the waitlist for q. The search's sort and
+// paging fields are dropped.
+func (s *WaitlistService) SaveSearch(ctx context.Context, userID string, q models.SearchQuery) (*models.SavedSearch, error) {
+	if _, err := s.userRepo.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := validateSearchQuery(&q); err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if !q.StartTime.After(now) {
+		return nil, &models.ValidationError{Field: "start_time", Message: "start time must be in the future"}
+	}
+	q.SortBy, q.Limit, q.Cursor = "", 0, ""
+
+	search := &models.SavedSearch{
+		ID:        s.ids.NewID(),
+		UserID:    userID,
+		Query:     q,
+		Status:    models.SavedSearchActive,
+		CreatedAt: now,
+	}
+	if err := s.waitlistRepo.CreateSavedSearch(ctx, search); err != nil {
+		return nil, err
+	}
+	return search, nil
+}
+
+// CancelSavedSearch takes the renter off the waitlist. Only ACTIVE searches
+// can be cancelled.
+func (s *WaitlistService) CancelSavedSearch(ctx context.Context, id, userID string) (*models.SavedSearch, error) {
+	search, err := s.waitlistRepo.GetSavedSearch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if search.UserID != userID {
+		return nil, &models.ValidationError{Field: "user_id", Message: "only the renter can cancel this saved search"}
+	}
+	if err := s.waitlistRepo.CloseSavedSearch(ctx, id, models.SavedSearchCancelled, "", s.now()); err != nil {
+		return nil, err
+	}
+	return s.waitlistRepo.GetSavedSearch(ctx, id)
+}
+
+// GetSavedSearches returns the renter's searches, newest first. ACTIVE
+// searches whose window has started are expired on the way.
+func (s *WaitlistService) GetSavedSearches(ctx context.Context, userID string) ([]*models.SavedSearch, error) {
+	searches, err := s.waitlistRepo.GetSavedSearchesForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	for _, search := range searches {
+		if search.Status != models.SavedSearchActive || search.Query.StartTime.After(now) {
+			continue
+		}
+		if err := s.closeSearch(ctx, search, models.SavedSearchExpired, "", now); err != nil {
+			return nil, err
+		}
+	}
+	return searches, nil
+}
+
+// HandleEvent is an EventHandler that re-checks the waitlist when a booking
+// is cancelled or a host adds availability. Subscribe it to an EventBus for
+// EventBookingCancelled and EventAvailabilityChanged.
+func (s *WaitlistService) HandleEvent(ctx context.Context, event models.Event) error {
+	var carID string
+	var start, end time.Time
+	switch ev := event.(type) {
+	case models.BookingCancelled:
+		carID, start, end = ev.CarID, ev.StartTime, ev.EndTime
+	case models.AvailabilityChanged:
+		switch ev.Change {
+		case models.AvailabilityAdded, models.AvailabilityUpdated, models.AvailabilityRuleAdded:
+		default:
+			return nil
+		}
+		carID, start, end = ev.CarID, ev.StartTime, ev.EndTime
+		if end.IsZero() {
+			end = farFuture
+		}
+	default:
+		return nil
+	}
+	_, err := s.CheckCar(ctx, carID, start, end)
+	return err
+}
+
+// CheckCar matches the saved searches whose window overlaps [start, end)
+// against the car, oldest search first, and returns how many matched. Each
+// match closes the search and publishes a WaitlistMatched event. The car
+// isn't held for the renter; the first to book it gets it.
+func (s *WaitlistService) CheckCar(ctx context.Context, carID string, start, end time.Time) (int, error) {
+	car, err := s.carRepo.GetCar(ctx, carID)
+	if err != nil {
+		return 0, err
+	}
+	if !car.IsActive {
+		return 0, nil
+	}
+	searches, err := s.waitlistRepo.CandidateSavedSearches(ctx, car, start, end)
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.now()
+	matched := 0
+	for _, search := range searches {
+		q := &search.Query
+		if !q.StartTime.After(now) {
+			if err := s.closeSearch(ctx, search, models.SavedSearchExpired, "", now); err != nil {
+				return matched, err
+			}
+			continue
+		}
+		if !matchesQuery(car, q) {
+			continue
+		}
+		available, err := s.inventoryRepo.FilterAvailable(ctx, []string{car.ID}, q.StartTime, q.EndTime)
+		if err != nil {
+			return matched, err
+		}
+		if !available[car.ID] {
+			continue
+		}
+		if err := s.closeSearch(ctx, search, models.SavedSearchMatched, car.ID, now); err != nil {
+			return matched, err
+		}
+		if search.Status != models.SavedSearchMatched {
+			continue
+		}
+		matched++
+		publish(ctx, s.events, models.WaitlistMatched{
+			SavedSearchID: search.ID,
+			UserID:        search.UserID,
+			CarID:         car.ID,
+			StartTime:     q.StartTime,
+			EndTime:       q.EndTime,
+			OccurredAt:    now,
+		})
+	}
+	return matched, nil
+}
+
+// closeSearch moves search to status and updates the copy in place. A
+// search that was closed concurrently is left with its status unchanged
+// and isn't an error.
+func (s *WaitlistService) closeSearch(ctx context.Context, search *models.SavedSearch, status models.SavedSearchStatus, carID string, at time.Time) error {
+	err := s.waitlistRepo.CloseSavedSearch(ctx, search.ID, status, carID, at)
+	if errors.Is(err, repositories.ErrInvalidTransition) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("closing saved search %s: %w", search.ID, err)
+	}
+	search.Status, search.ClosedAt, search.MatchedCarID = status, at, carID
+	return nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"car-rental-lite/src/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitlistMatchesWhenCarFreesUp(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	bus := NewEventBus()
+	var matches []models.WaitlistMatched
+	bus.Subscribe(func(ctx context.Context, ev models.Event) error {
+		matches = append(matches, ev.(models.WaitlistMatched))
+		return nil
+	}, models.EventWaitlistMatched)
+	waitlist := NewWaitlistService(f.repo, f.repo, f.repo, f.repo)
+	waitlist.SetClock(func() time.Time { return f.now })
+	waitlist.SetEventPublisher(bus)
+	bus.Subscribe(waitlist.HandleEvent, models.EventBookingCancelled, models.EventAvailabilityChanged)
+	f.bookings.SetEventPublisher(bus)
+	f.inv.SetEventPublisher(bus)
+
+	b, err := f.bookings.CreateBooking(ctx, "user1", f.car.ID, jan(2), jan(6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	january, err := waitlist.SaveSearch(ctx, "user2", models.SearchQuery{StartTime: jan(3), EndTime: jan(5), SortBy: models.SearchSortPrice, Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tooCheap, err := waitlist.SaveSearch(ctx, "user2", models.SearchQuery{StartTime: jan(3), EndTime: jan(5), MaxPrice: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	february, err := waitlist.SaveSearch(ctx, "user1", models.SearchQuery{StartTime: jan(31).AddDate(0, 0, 2), EndTime: jan(31).AddDate(0, 0, 4)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if january.Query.SortBy != "" || january.Query.Limit != 0 {
+		t.Errorf("Expected paging fields dropped, got %+v", january.Query)
+	}
+
+	if _, err := f.bookings.CancelBooking(ctx, b.ID, models.CancelledByRenter, "user1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.inv.AddAvailability(ctx, f.car.ID, jan(31), jan(31).AddDate(0, 0, 10)); err != nil {
+		t.Fatal(err)
+	}
+	// Once to run the matcher, once to deliver what it published.
+	for i := 0; i < 2; i++ {
+		if _, err := bus.Dispatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(matches) != 2 || matches[0].SavedSearchID != january.ID || matches[1].SavedSearchID != february.ID {
+		t.Fatalf("Expected the January then February searches matched, got %+v", matches)
+	}
+	if matches[0].UserID != "user2" || matches[0].CarID != f.car.ID || !matches[0].StartTime.Equal(jan(3)) {
+		t.Errorf("Expected user2 told about the car for Jan 3, got %+v", matches[0])
+	}
+	got, err := f.repo.GetSavedSearch(ctx, january.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.SavedSearchMatched || got.MatchedCarID != f.car.ID {
+		t.Errorf("Expected the search closed as matched, got %+v", got)
+	}
+	if got, _ := f.repo.GetSavedSearch(ctx, tooCheap.ID); got.Status != models.SavedSearchActive {
+		t.Errorf("Expected the search filtered on price to stay active, got %s", got.Status)
+	}
+
+	// A search matches at most once.
+	if n, err := waitlist.CheckCar(ctx, f.car.ID, jan(1), jan(31)); err != nil || n != 0 {
+		t.Errorf("Expected no further matches, got %d, %v", n, err)
+	}
+}
+
+func TestWaitlistSavedSearchLifecycle(t *testing.T) {
+	ctx := context.Background()
+	f := newBookingFixture(t)
+	waitlist := NewWaitlistService(f.repo, f.repo, f.repo, f.repo)
+	waitlist.SetClock(func() time.Time { return f.now })
+
+	if _, err := waitlist.SaveSearch(ctx, "user1", models.SearchQuery{StartTime: f.now.Add(-time.Hour), EndTime: jan(2)}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected a window in the past to be refused, got %v", err)
+	}
+	if _, err := waitlist.SaveSearch(ctx, "nobody", models.SearchQuery{StartTime: jan(2), EndTime: jan(3)}); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected an unknown user to be refused, got %v", err)
+	}
+	soon, err := waitlist.SaveSearch(ctx, "user1", models.SearchQuery{StartTime: jan(2), EndTime: jan(3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	later, err := waitlist.SaveSearch(ctx, "user1", models.SearchQuery{StartTime: jan(20), EndTime: jan(22)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := waitlist.CancelSavedSearch(ctx, later.ID, "user2"); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected another renter's cancel to be refused, got %v", err)
+	}
+	cancelled, err := waitlist.CancelSavedSearch(ctx, later.ID, "user1")
+	if err != nil || cancelled.Status != models.SavedSearchCancelled {
+		t.Fatalf("Expected the search cancelled, got %+v, %v", cancelled, err)
+	}
+	if _, err := waitlist.CancelSavedSearch(ctx, later.ID, "user1"); !errors.Is(err, models.ErrConflict) {
+		t.Errorf("Expected a second cancel to conflict, got %v", err)
+	}
+
+	f.now = jan(2).Add(time.Hour)
+	searches, err := waitlist.GetSavedSearches(ctx, "user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(searches) != 2 || searches[0].ID != later.ID || searches[1].ID != soon.ID {
+		t.Fatalf("Expected both searches newest first, got %+v", searches)
+	}
+	if searches[1].Status != models.SavedSearchExpired {
+		t.Errorf("Expected the started search expired, got %s", searches[1].Status)
+	}
+}
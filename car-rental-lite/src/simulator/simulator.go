@@ -0,0 +1,420 @@
+// Package simulator races many renters for a generated fleet through
+// BookingService and checks that no car was ever booked twice. It measures
+// throughput, conflict rate and booking latency, and is meant as a load test
+// and as a regression gate for repository implementations.
+package simulator
+
+import (
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"car-rental-lite/src/services"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrOverbooked is returned by Report.Err when the run broke the
+// no-overbooking invariant.
+var ErrOverbooked = errors.New("car overbooked")
+
+// Repository is what the simulator needs from a repository implementation.
+type Repository interface {
+	repositories.UserRepository
+	repositories.CarRepository
+	repositories.InventoryRepository
+	repositories.BookingRepository
+}
+
+// Config sizes a run. Zero fields take the defaults noted.
+type Config struct {
+	Hosts       int // default 10
+	CarsPerHost int // default 5
+	Renters     int // concurrent renters, default 50
+	Attempts    int // booking attempts per renter, default 20
+	// Contention is the share of attempts aimed at the hot cars over the
+	// hot days, between 0 and 1. The rest pick any car and any window.
+	Contention float64
+	HotCars    int // default 1
+	HotDays    int // default 3
+	// Days is how many days every car is available for, starting the day
+	// after Start. Default 30.
+	Days        int
+	MaxTripDays int // trips last 1 to MaxTripDays days, default 3
+	// CancelRate is the share of successful bookings the renter cancels
+	// straight away, freeing the car for others; the rest are confirmed.
+	CancelRate float64
+	Seed       int64     // seeds the renters' choices; runs with the same seed make the same requests
+	Start      time.Time // the services' clock, fixed for the run; default 2026-01-01 UTC
+}
+
+func (c Config) withDefaults() Config {
+	defaults := []struct {
+		field *int
+		value int
+	}{
+		{&c.Hosts, 10}, {&c.CarsPerHost, 5}, {&c.Renters, 50}, {&c.Attempts, 20},
+		{&c.HotCars, 1}, {&c.HotDays, 3}, {&c.Days, 30}, {&c.MaxTripDays, 3},
+	}
+	for _, d := range defaults {
+		if *d.field <= 0 {
+			*d.field = d.value
+		}
+	}
+	if c.Start.IsZero() {
+		c.Start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return c
+}
+
+func (c Config) validate() error {
+	switch {
+	case c.Contention < 0 || c.Contention > 1:
+		return &models.ValidationError{Field: "contention", Message: "must be between 0 and 1"}
+	case c.CancelRate < 0 || c.CancelRate > 1:
+		return &models.ValidationError{Field: "cancel_rate", Message: "must be between 0 and 1"}
+	case c.HotCars > c.Hosts*c.CarsPerHost:
+		return &models.ValidationError{Field: "hot_cars", Message: "must not exceed the number of cars"}
+	case c.HotDays > c.Days || c.MaxTripDays > c.Days:
+		return &models.ValidationError{Field: "days", Message: "must cover the hot days and the longest trip"}
+	}
+	return nil
+}
+
+// Latency summarises how long CreateBooking took.
+type Latency struct {
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+// Overbooking is two bookings holding the same car at the same time.
+type Overbooking struct {
+	CarID  string `json:"car_id"`
+	First  string `json:"first"`
+	Second string `json:"second"`
+}
+
+// Report is the outcome of a run. Every attempt ends up in exactly one of
+// Booked, Conflicts, Rejected and Errors.
+type Report struct {
+	Attempts  int `json:"attempts"`
+	Booked    int `json:"booked"`
+	Conflicts int `json:"conflicts"` // the car was taken
+	Rejected  int `json:"rejected"`  // the renter was ineligible or the request invalid
+	Errors    int `json:"errors"`    // anything else
+	Cancelled int `json:"cancelled"` // bookings the renters cancelled again
+
+	Elapsed      time.Duration `json:"elapsed"`
+	Throughput   float64       `json:"throughput"`    // booked per second
+	ConflictRate float64       `json:"conflict_rate"` // Conflicts / Attempts
+	Latency      Latency       `json:"latency"`
+
+	// Overbookings lists overlapping bookings, whether among those the
+	// renters were promised or among those the repository holds. Missing
+	// counts promised bookings the repository doesn't hold.
+	Overbookings []Overbooking `json:"overbookings"`
+	Missing      int           `json:"missing"`
+	// FirstErrors keeps a few of the unexpected errors for diagnosis.
+	FirstErrors []string `json:"first_errors,omitempty"`
+}
+
+// Err returns an error wrapping ErrOverbooked if the run broke the
+// no-overbooking invariant or lost a booking, and nil otherwise.
+func (r *Report) Err() error {
+	if len(r.Overbookings) == 0 && r.Missing == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d overlapping pairs, %d promised bookings missing", ErrOverbooked, len(r.Overbookings), r.Missing)
+}
+
+const maxReportedErrors = 5
+
+// attempt is one renter's request and how it went.
+type attempt struct {
+	booking   *models.Booking // nil unless it succeeded
+	cancelled bool
+	latency   time.Duration
+	err       error
+}
+
+// Run seeds repo with the generated fleet and renters, lets the renters
+// book concurrently, and checks the result. It returns an error only if the
+// run couldn't be carried out; a broken invariant is reported by
+// Report.Err. repo should be empty, since the simulator picks its own IDs.
+func Run(ctx context.Context, repo Repository, cfg Config) (*Report, error) {
+	cfg = cfg.withDefaults()
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	carIDs, err := seed(ctx, repo, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("seeding: %w", err)
+	}
+
+	bookings := services.NewBookingService(repo, repo, repo, repo)
+	bookings.SetClock(func() time.Time { return cfg.Start })
+
+	results := make([][]attempt, cfg.Renters)
+	var wg sync.WaitGroup
+	begin := make(chan struct{})
+	for i := 0; i < cfg.Renters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r := &renter{
+				id:       renterID(i),
+				cfg:      cfg,
+				carIDs:   carIDs,
+				rng:      rand.New(rand.NewSource(cfg.Seed + int64(i))),
+				bookings: bookings,
+			}
+			<-begin
+			results[i] = r.run(ctx)
+		}(i)
+	}
+	started := time.Now()
+	close(begin)
+	wg.Wait()
+	elapsed := time.Since(started)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report := summarise(results, elapsed)
+	if err := verify(ctx, repo, cfg, carIDs, results, report); err != nil {
+		return nil, fmt.Errorf("verifying: %w", err)
+	}
+	return report, nil
+}
+
+func hostID(i int) string   { return fmt.Sprintf("host%d", i) }
+func carID(i int) string    { return fmt.Sprintf("car%d", i) }
+func renterID(i int) string { return fmt.Sprintf("renter%d", i) }
+
+// seed creates the hosts, their cars available for cfg.Days, and licensed
+// renters. It returns the car IDs; the first cfg.HotCars are the hot ones.
+func seed(ctx context.Context, repo Repository, cfg Config) ([]string, error) {
+	inventory := services.NewInventoryService(repo, repo, repo, repo)
+	from := cfg.Start.AddDate(0, 0, 1)
+	to := from.AddDate(0, 0, cfg.Days)
+	var carIDs []string
+	for h := 0; h < cfg.Hosts; h++ {
+		host := &models.Host{ID: hostID(h), Name: "Host " + hostID(h), CancellationPolicy: models.CancellationPolicyFlexible}
+		if err := repo.CreateHost(ctx, host); err != nil {
+			return nil, err
+		}
+		for c := 0; c < cfg.CarsPerHost; c++ {
+			car := &models.Car{
+				ID:          carID(len(carIDs)),
+				HostID:      host.ID,
+				Make:        "Toyota",
+				Model:       "Corolla",
+				Year:        2022,
+				Type:        models.CarTypeSedan,
+				PricePerDay: 50 + float64(len(carIDs)%10)*10,
+				IsActive:    true,
+			}
+			if err := inventory.RegisterCar(ctx, car); err != nil {
+				return nil, err
+			}
+			if _, err := inventory.AddAvailability(ctx, car.ID, from, to); err != nil {
+				return nil, err
+			}
+			carIDs = append(carIDs, car.ID)
+		}
+	}
+	for i := 0; i < cfg.Renters; i++ {
+		user := &models.User{
+			ID:               renterID(i),
+			Name:             "Renter " + renterID(i),
+			DriverLicense:    "DL-" + renterID(i),
+			LicenseExpiresAt: cfg.Start.AddDate(10, 0, 0),
+			DateOfBirth:      cfg.Start.AddDate(-30, 0, 0),
+		}
+		if err := repo.CreateUser(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+	return carIDs, nil
+}
+
+type renter struct {
+	id       string
+	cfg      Config
+	carIDs   []string
+	rng      *rand.Rand
+	bookings *services.BookingService
+}
+
+func (r *renter) run(ctx context.Context) []attempt {
+	attempts := make([]attempt, 0, r.cfg.Attempts)
+	for n := 0; n < r.cfg.Attempts && ctx.Err() == nil; n++ {
+		carID, start, end := r.pick()
+		began := time.Now()
+		b, err := r.bookings.CreateBooking(ctx, r.id, carID, start, end)
+		a := attempt{latency: time.Since(began), err: err}
+		if err == nil {
+			a.booking = b
+			if r.rng.Float64() < r.cfg.CancelRate {
+				_, err = r.bookings.CancelBooking(ctx, b.ID, models.CancelledByRenter, r.id)
+				a.cancelled = err == nil
+			} else {
+				_, err = r.bookings.ConfirmBooking(ctx, b.ID)
+			}
+			// The booking stands either way; a failed follow-up is still
+			// an unexpected error.
+			if err != nil {
+				a.err = fmt.Errorf("after booking %s: %w", b.ID, err)
+			}
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts
+}
+
+// pick chooses a car and a window of whole days, inside the hot set with
+// probability Contention.
+func (r *renter) pick() (carID string, start, end time.Time) {
+	cars, days := len(r.carIDs), r.cfg.Days
+	if r.rng.Float64() < r.cfg.Contention {
+		cars, days = r.cfg.HotCars, r.cfg.HotDays
+	}
+	trip := 1 + r.rng.Intn(r.cfg.MaxTripDays)
+	if trip > days {
+		trip = days
+	}
+	first := r.cfg.Start.AddDate(0, 0, 1)
+	start = first.AddDate(0, 0, r.rng.Intn(days-trip+1))
+	return r.carIDs[r.rng.Intn(cars)], start, start.AddDate(0, 0, trip)
+}
+
+func summarise(results [][]attempt, elapsed time.Duration) *Report {
+	report := &Report{Elapsed: elapsed}
+	var latencies []time.Duration
+	for _, attempts := range results {
+		for _, a := range attempts {
+			report.Attempts++
+			latencies = append(latencies, a.latency)
+			if a.booking != nil {
+				report.Booked++
+				if a.cancelled {
+					report.Cancelled++
+				}
+			}
+			switch {
+			case a.err == nil:
+			case a.booking == nil && errors.Is(a.err, repositories.ErrOverlap):
+				report.Conflicts++
+			case a.booking == nil && (errors.Is(a.err, models.ErrIneligible) || errors.Is(a.err, models.ErrValidation)):
+				report.Rejected++
+			default:
+				// A follow-up failure was counted as Booked too; move it.
+				if a.booking != nil {
+					report.Booked--
+				}
+				report.Errors++
+				if len(report.FirstErrors) < maxReportedErrors {
+					report.FirstErrors = append(report.FirstErrors, a.err.Error())
+				}
+			}
+		}
+	}
+	if report.Attempts > 0 {
+		report.ConflictRate = float64(report.Conflicts) / float64(report.Attempts)
+	}
+	if elapsed > 0 {
+		report.Throughput = float64(report.Booked) / elapsed.Seconds()
+	}
+	report.Latency = percentiles(latencies)
+	return report
+}
+
+func percentiles(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	at := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+	return Latency{P50: at(0.50), P90: at(0.90), P99: at(0.99), Max: latencies[len(latencies)-1]}
+}
+
+// verify checks the invariant twice: over the bookings the renters were
+// promised and not cancelled, and over what the repository holds for every
+// car. Each promised booking must also be held by the repository.
+func verify(ctx context.Context, repo Repository, cfg Config, carIDs []string, results [][]attempt, report *Report) error {
+	var promised []*models.Booking
+	for _, attempts := range results {
+		for _, a := range attempts {
+			if a.booking != nil && !a.cancelled {
+				promised = append(promised, a.booking)
+			}
+		}
+	}
+	seen := make(map[Overbooking]bool)
+	add := func(found []Overbooking) {
+		for _, o := range found {
+			if !seen[o] {
+				seen[o] = true
+				report.Overbookings = append(report.Overbookings, o)
+			}
+		}
+	}
+	add(findOverlaps(promised))
+
+	held := make(map[string]bool)
+	from, to := cfg.Start, cfg.Start.AddDate(0, 0, cfg.Days+1)
+	for _, carID := range carIDs {
+		stored, err := repo.GetBookingsForCar(ctx, carID, from, to)
+		if err != nil {
+			return err
+		}
+		var holding []*models.Booking
+		for _, b := range stored {
+			if b.Status.HoldsCar() {
+				holding = append(holding, b)
+				held[b.ID] = true
+			}
+		}
+		add(findOverlaps(holding))
+	}
+	for _, b := range promised {
+		if !held[b.ID] {
+			report.Missing++
+		}
+	}
+	return nil
+}
+
+// findOverlaps returns the pairs of bookings for the same car whose windows
+// overlap. Each booking is reported against the earlier-starting booking
+// that reaches furthest into it.
+func findOverlaps(bookings []*models.Booking) []Overbooking {
+	sorted := append([]*models.Booking(nil), bookings...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].CarID != sorted[j].CarID {
+			return sorted[i].CarID < sorted[j].CarID
+		}
+		if !sorted[i].StartTime.Equal(sorted[j].StartTime) {
+			return sorted[i].StartTime.Before(sorted[j].StartTime)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	var res []Overbooking
+	var reach *models.Booking // the booking ending last so far on this car
+	for _, b := range sorted {
+		if reach != nil && reach.CarID == b.CarID && reach.EndTime.After(b.StartTime) {
+			res = append(res, Overbooking{CarID: b.CarID, First: reach.ID, Second: b.ID})
+		}
+		if reach == nil || reach.CarID != b.CarID || b.EndTime.After(reach.EndTime) {
+			reach = b
+		}
+	}
+	return res
+}
@@ -0,0 +1,100 @@
+package simulator
+
+import (
+	"car-rental-lite/src/database"
+	"car-rental-lite/src/models"
+	"car-rental-lite/src/repositories"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunKeepsBookingsApart(t *testing.T) {
+	repos := map[string]func(t *testing.T) Repository{
+		"memory": func(t *testing.T) Repository { return repositories.NewInMemoryRepo() },
+		"sql": func(t *testing.T) Repository {
+			db, err := database.InitDB(filepath.Join(t.TempDir(), "rental.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sqlDB, err := db.DB()
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { sqlDB.Close() })
+			return repositories.NewSQLRepo(db)
+		},
+	}
+	for name, newRepo := range repos {
+		t.Run(name, func(t *testing.T) {
+			cfg := Config{Hosts: 2, CarsPerHost: 3, Renters: 12, Attempts: 8, Contention: 0.8, HotCars: 2, CancelRate: 0.2, Seed: 7}
+			report, err := Run(context.Background(), newRepo(t), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := report.Err(); err != nil {
+				t.Fatalf("Expected no overbooking, got %v (%+v)", err, report.Overbookings)
+			}
+			if report.Attempts != 96 || report.Booked+report.Conflicts+report.Rejected+report.Errors != report.Attempts {
+				t.Errorf("Expected 96 attempts each counted once, got %+v", report)
+			}
+			if report.Errors != 0 {
+				t.Errorf("Expected no unexpected errors, got %v", report.FirstErrors)
+			}
+			if report.Conflicts == 0 || report.Booked == 0 {
+				t.Errorf("Expected both bookings and conflicts under contention, got %+v", report)
+			}
+			if report.Latency.P50 > report.Latency.P99 || report.Latency.P99 > report.Latency.Max {
+				t.Errorf("Expected ordered latency percentiles, got %+v", report.Latency)
+			}
+		})
+	}
+}
+
+// unlockedRepo reserves without checking for overlaps, as a broken
+// implementation might.
+type unlockedRepo struct {
+	*repositories.InMemoryRepo
+}
+
+func (r unlockedRepo) ReserveBooking(ctx context.Context, booking *models.Booking, check repositories.BookingsCheck) error {
+	return r.CreateBooking(ctx, booking)
+}
+
+func TestRunCatchesOverbooking(t *testing.T) {
+	repo := unlockedRepo{repositories.NewInMemoryRepo()}
+	report, err := Run(context.Background(), repo, Config{Hosts: 1, CarsPerHost: 1, Renters: 4, Attempts: 3, Contention: 1, HotDays: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Err(); !errors.Is(err, ErrOverbooked) || len(report.Overbookings) == 0 {
+		t.Fatalf("Expected overbooking reported, got %v", err)
+	}
+	if report.Conflicts != 0 {
+		t.Errorf("Expected the broken repository to report no conflicts, got %d", report.Conflicts)
+	}
+}
+
+func TestFindOverlaps(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	bookings := []*models.Booking{
+		{ID: "long", CarID: "car1", StartTime: day(1), EndTime: day(10)},
+		{ID: "inside", CarID: "car1", StartTime: day(3), EndTime: day(4)},
+		{ID: "after-inside", CarID: "car1", StartTime: day(5), EndTime: day(6)},
+		{ID: "adjacent", CarID: "car1", StartTime: day(10), EndTime: day(12)},
+		{ID: "other-car", CarID: "car2", StartTime: day(3), EndTime: day(4)},
+	}
+	got := findOverlaps(bookings)
+	want := []Overbooking{{"car1", "long", "inside"}, {"car1", "long", "after-inside"}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestRunValidatesConfig(t *testing.T) {
+	if _, err := Run(context.Background(), repositories.NewInMemoryRepo(), Config{Contention: 1.5}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected a validation error for contention above 1, got %v", err)
+	}
+}
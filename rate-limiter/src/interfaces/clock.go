@@ -0,0 +1,22 @@
+package interfaces
+
+import "time"
+
+// Clock tells the rules the time, so tests can drive them with a fake one.
+type Clock interface {
+	Now() time.Time
+}
+
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// now reads c, falling back to the system clock when a rule has none.
+func now(c Clock) time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
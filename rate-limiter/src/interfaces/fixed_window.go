@@ -0,0 +1,39 @@
+package interfaces
+
+import (
+	"rate-limiter/src/models"
+	"sync"
+)
+
+// FixedWindowRule allows Requests per window, with windows aligned to
+// multiples of Timeframe. Up to twice the limit can pass around a window
+// boundary.
+type FixedWindowRule struct {
+	mu          sync.RWMutex
+	LimitPolicy models.LimitPolicy
+	Clock       Clock
+}
+
+func (r *FixedWindowRule) GetKey(ctx models.RequestContext) string {
+	return ctx.UserID
+}
+
+func (r *FixedWindowRule) Evaluate(ctx models.RequestContext, state *LimiterState, policy models.LimitPolicy) (bool, *LimiterState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if policy.Timeframe <= 0 {
+		return false, state
+	}
+	window := &state.FixedWindow
+	if start := now(r.Clock).Truncate(policy.Timeframe); !start.Equal(window.WindowStart) {
+		window.WindowStart = start
+		window.Count = 0
+	}
+
+	if window.Count >= policy.Requests {
+		return false, state
+	}
+	window.Count++
+	return true, state
+}
@@ -0,0 +1,57 @@
+package interfaces
+
+import (
+	"rate-limiter/src/models"
+	"sync"
+	"time"
+)
+
+// LeakyBucketRule admits a request if the bucket has room. The bucket holds
+// MaxBurst requests (Requests when MaxBurst is unset) and drains at
+// Requests per Timeframe, so sustained traffic is smoothed to that rate.
+type LeakyBucketRule struct {
+	mu          sync.RWMutex
+	LimitPolicy models.LimitPolicy
+	Clock       Clock
+}
+
+func (r *LeakyBucketRule) GetKey(ctx models.RequestContext) string {
+	return ctx.UserID
+}
+
+func (r *LeakyBucketRule) Evaluate(ctx models.RequestContext, state *LimiterState, policy models.LimitPolicy) (bool, *LimiterState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if policy.Requests <= 0 || policy.Timeframe <= 0 {
+		return false, state
+	}
+	capacity := policy.MaxBurst
+	if capacity <= 0 {
+		capacity = policy.Requests
+	}
+
+	// Drain whole drops only, carrying the remainder forward so partial
+	// intervals aren't lost between requests.
+	t := now(r.Clock)
+	bucket := &state.LeakyBucket
+	interval := policy.Timeframe / time.Duration(policy.Requests)
+	if bucket.Water > 0 {
+		leaked := int(t.Sub(bucket.LastLeakTime) / interval)
+		if leaked >= bucket.Water {
+			bucket.Water = 0
+		} else {
+			bucket.Water -= leaked
+			bucket.LastLeakTime = bucket.LastLeakTime.Add(time.Duration(leaked) * interval)
+		}
+	}
+	if bucket.Water == 0 {
+		bucket.LastLeakTime = t
+	}
+
+	if bucket.Water >= capacity {
+		return false, state
+	}
+	bucket.Water++
+	return true, state
+}
@@ -3,7 +3,6 @@ package interfaces
 import (
 	"rate-limiter/src/models"
 	"sync"
-	"time"
 )
 
 type LimiterRule interface {
@@ -14,6 +13,7 @@ type LimiterRule interface {
 type TokenBucketRule struct {
 	mu          sync.RWMutex
 	LimitPolicy models.LimitPolicy
+	Clock       Clock
 }
 
 func (r *TokenBucketRule) GetKey(ctx models.RequestContext) string {
@@ -24,9 +24,10 @@ func (r *TokenBucketRule) Evaluate(ctx models.RequestContext, state *LimiterStat
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if time.Since(state.TokenBucket.LastRefillTime) > policy.Timeframe {
+	t := now(r.Clock)
+	if t.Sub(state.TokenBucket.LastRefillTime) > policy.Timeframe {
 		state.TokenBucket.Tokens = policy.Requests
-		state.TokenBucket.LastRefillTime = time.Now()
+		state.TokenBucket.LastRefillTime = t
 	}
 
 	if state.TokenBucket.Tokens < 1 {
@@ -0,0 +1,127 @@
+package interfaces
+
+import (
+	"rate-limiter/src/models"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.t
+}
+
+// TestRulesAllowDenySequence sends requests at fixed offsets and checks
+// each rule's decisions, written as a string of A (allowed) and D (denied).
+func TestRulesAllowDenySequence(t *testing.T) {
+	s := time.Second
+	twoPer10s := models.LimitPolicy{Requests: 2, Timeframe: 10 * s}
+	tests := []struct {
+		name    string
+		newRule func(Clock) LimiterRule
+		policy  models.LimitPolicy
+		at      []time.Duration
+		want    string
+	}{
+		{
+			name:    "token bucket refills after the timeframe",
+			newRule: func(c Clock) LimiterRule { return &TokenBucketRule{Clock: c} },
+			policy:  twoPer10s,
+			at:      []time.Duration{0, 1 * s, 2 * s, 10 * s, 11 * s},
+			want:    "AADDA",
+		},
+		{
+			name:    "leaky bucket drains one request per interval",
+			newRule: func(c Clock) LimiterRule { return &LeakyBucketRule{Clock: c} },
+			policy:  twoPer10s,
+			at:      []time.Duration{0, 1 * s, 2 * s, 5 * s, 6 * s, 20 * s},
+			want:    "AADADA",
+		},
+		{
+			name:    "leaky bucket holds MaxBurst",
+			newRule: func(c Clock) LimiterRule { return &LeakyBucketRule{Clock: c} },
+			policy:  models.LimitPolicy{Requests: 1, Timeframe: s, MaxBurst: 3},
+			at:      []time.Duration{0, 0, 0, 0, 1 * s, 1500 * time.Millisecond},
+			want:    "AAADAD",
+		},
+		{
+			name:    "fixed window resets on the boundary",
+			newRule: func(c Clock) LimiterRule { return &FixedWindowRule{Clock: c} },
+			policy:  twoPer10s,
+			at:      []time.Duration{0, 1 * s, 9 * s, 10 * s, 11 * s, 12 * s},
+			want:    "AADAAD",
+		},
+		{
+			name:    "fixed window lets twice the limit through around a boundary",
+			newRule: func(c Clock) LimiterRule { return &FixedWindowRule{Clock: c} },
+			policy:  twoPer10s,
+			at:      []time.Duration{9 * s, 9 * s, 10 * s, 10 * s, 10 * s},
+			want:    "AAAAD",
+		},
+		{
+			name:    "sliding window counter weights the previous window",
+			newRule: func(c Clock) LimiterRule { return &SlidingWindowCounterRule{Clock: c} },
+			policy:  twoPer10s,
+			at:      []time.Duration{0, 5 * s, 9 * s, 10 * s, 15 * s, 16 * s, 17 * s, 30 * s},
+			want:    "AADDAADA",
+		},
+		{
+			name:    "sliding window log counts denied requests",
+			newRule: func(c Clock) LimiterRule { return &SlidingWindowLogRule{Clock: c} },
+			policy:  twoPer10s,
+			at:      []time.Duration{0, 1 * s, 5 * s, 10 * s, 11 * s, 25 * s},
+			want:    "AADDDA",
+		},
+		{
+			name:    "rolling window counts allowed requests only",
+			newRule: func(c Clock) LimiterRule { return &RollingWindowRule{Clock: c} },
+			policy:  twoPer10s,
+			at:      []time.Duration{0, 1 * s, 5 * s, 10 * s, 11 * s, 12 * s},
+			want:    "AADAAD",
+		},
+	}
+
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{}
+			rule := tt.newRule(clock)
+			ctx := models.RequestContext{UserID: "user"}
+			state := &LimiterState{}
+			got := ""
+			for _, at := range tt.at {
+				clock.t = t0.Add(at)
+				var allowed bool
+				allowed, state = rule.Evaluate(ctx, state, tt.policy)
+				if allowed {
+					got += "A"
+				} else {
+					got += "D"
+				}
+			}
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRulesDenyWithoutLimit(t *testing.T) {
+	rules := []LimiterRule{
+		&TokenBucketRule{},
+		&LeakyBucketRule{},
+		&FixedWindowRule{},
+		&SlidingWindowLogRule{},
+		&SlidingWindowCounterRule{},
+		&RollingWindowRule{},
+	}
+	policy := models.LimitPolicy{Requests: 0, Timeframe: time.Second}
+	for _, rule := range rules {
+		if allowed, _ := rule.Evaluate(models.RequestContext{}, &LimiterState{}, policy); allowed {
+			t.Errorf("%T allowed a request with a limit of 0", rule)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package interfaces
+
+import (
+	"rate-limiter/src/models"
+	"sync"
+)
+
+// RollingWindowRule allows a request if fewer than Requests requests were
+// allowed in the Timeframe before it. Unlike SlidingWindowLogRule, denied
+// requests aren't recorded, so capacity returns as soon as the oldest
+// allowed request ages out.
+type RollingWindowRule struct {
+	mu          sync.RWMutex
+	LimitPolicy models.LimitPolicy
+	Clock       Clock
+}
+
+func (r *RollingWindowRule) GetKey(ctx models.RequestContext) string {
+	return ctx.UserID
+}
+
+func (r *RollingWindowRule) Evaluate(ctx models.RequestContext, state *LimiterState, policy models.LimitPolicy) (bool, *LimiterState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := now(r.Clock)
+	window := &state.RollingWindow
+	window.Timestamps = dropUntil(window.Timestamps, t.Add(-policy.Timeframe))
+	if len(window.Timestamps) >= policy.Requests {
+		return false, state
+	}
+	window.Timestamps = append(window.Timestamps, t)
+	return true, state
+}
@@ -0,0 +1,84 @@
+package interfaces
+
+import (
+	"rate-limiter/src/models"
+	"sync"
+	"time"
+)
+
+// SlidingWindowLogRule allows a request if fewer than Requests requests,
+// allowed or not, arrived in the Timeframe before it. Logging denied
+// requests too means a client that keeps sending over the limit stays
+// blocked until it backs off for a whole Timeframe.
+type SlidingWindowLogRule struct {
+	mu          sync.RWMutex
+	LimitPolicy models.LimitPolicy
+	Clock       Clock
+}
+
+func (r *SlidingWindowLogRule) GetKey(ctx models.RequestContext) string {
+	return ctx.UserID
+}
+
+func (r *SlidingWindowLogRule) Evaluate(ctx models.RequestContext, state *LimiterState, policy models.LimitPolicy) (bool, *LimiterState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := now(r.Clock)
+	log := &state.SlidingWindowLog
+	log.Timestamps = dropUntil(log.Timestamps, t.Add(-policy.Timeframe))
+	allowed := len(log.Timestamps) < policy.Requests
+	log.Timestamps = append(log.Timestamps, t)
+	return allowed, state
+}
+
+// SlidingWindowCounterRule approximates a sliding window from two fixed
+// windows: the previous window's count, weighted by how much of it the
+// sliding window still covers, plus the current window's count.
+type SlidingWindowCounterRule struct {
+	mu          sync.RWMutex
+	LimitPolicy models.LimitPolicy
+	Clock       Clock
+}
+
+func (r *SlidingWindowCounterRule) GetKey(ctx models.RequestContext) string {
+	return ctx.UserID
+}
+
+func (r *SlidingWindowCounterRule) Evaluate(ctx models.RequestContext, state *LimiterState, policy models.LimitPolicy) (bool, *LimiterState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if policy.Timeframe <= 0 {
+		return false, state
+	}
+	t := now(r.Clock)
+	counter := &state.SlidingWindowCounter
+	if start := t.Truncate(policy.Timeframe); !start.Equal(counter.WindowStart) {
+		if start.Equal(counter.WindowStart.Add(policy.Timeframe)) {
+			counter.PreviousCount = counter.CurrentCount
+		} else {
+			counter.PreviousCount = 0
+		}
+		counter.CurrentCount = 0
+		counter.WindowStart = start
+	}
+
+	elapsed := float64(t.Sub(counter.WindowStart)) / float64(policy.Timeframe)
+	estimate := float64(counter.PreviousCount)*(1-elapsed) + float64(counter.CurrentCount)
+	if estimate >= float64(policy.Requests) {
+		return false, state
+	}
+	counter.CurrentCount++
+	return true, state
+}
+
+// dropUntil removes the timestamps at or before cutoff from the front of a
+// chronological log.
+func dropUntil(timestamps []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(timestamps) && !timestamps[i].After(cutoff) {
+		i++
+	}
+	return timestamps[i:]
+}
@@ -2,9 +2,14 @@ package interfaces
 
 import "time"
 
+// LimiterState holds one entity's state; each rule uses only its own field.
 type LimiterState struct {
-	TokenBucket TokenBucketState
-	LeakyBucket LeakyBucketState
+	TokenBucket          TokenBucketState
+	LeakyBucket          LeakyBucketState
+	FixedWindow          FixedWindowState
+	SlidingWindowLog     SlidingWindowLogState
+	SlidingWindowCounter SlidingWindowCounterState
+	RollingWindow        RollingWindowState
 }
 
 type TokenBucketState struct {
@@ -16,3 +21,22 @@ type LeakyBucketState struct {
 	Water        int
 	LastLeakTime time.Time
 }
+
+type FixedWindowState struct {
+	WindowStart time.Time
+	Count       int
+}
+
+type SlidingWindowLogState struct {
+	Timestamps []time.Time // every request in the window, allowed or not
+}
+
+type SlidingWindowCounterState struct {
+	WindowStart   time.Time
+	CurrentCount  int
+	PreviousCount int
+}
+
+type RollingWindowState struct {
+	Timestamps []time.Time // allowed requests in the window
+}